@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -19,18 +20,21 @@ import (
 // LibClient provides direct library integration with agentapi
 // instead of spawning the agentapi binary and communicating via HTTP
 type LibClient struct {
-	process *termexec.Process
-	server  *httpapi.Server
-	emitter *httpapi.EventEmitter
-	port    int
-	verbose bool
-	logger  *slog.Logger
-	ctx     context.Context
+	process    *termexec.Process
+	server     *httpapi.Server
+	listener   net.Listener // Set when serving on a listener bound by the caller
+	httpServer *http.Server // Serves listener once started
+	emitter    *httpapi.EventEmitter
+	port       int
+	verbose    bool
+	logger     *slog.Logger
+	ctx        context.Context
 }
 
 // LibClientConfig configures the library client
 type LibClientConfig struct {
 	Port          int
+	Listener      net.Listener // Already bound to Port; served instead of listening again
 	Verbose       bool
 	AgentCmd      string   // e.g., "claude"
 	AgentArgs     []string // additional args for the agent
@@ -89,13 +93,14 @@ func NewLibClient(ctx context.Context, cfg LibClientConfig) (*LibClient, error)
 	}
 
 	client := &LibClient{
-		process: process,
-		server:  server,
-		emitter: emitter,
-		port:    cfg.Port,
-		verbose: cfg.Verbose,
-		logger:  logger,
-		ctx:     ctx,
+		process:  process,
+		server:   server,
+		emitter:  emitter,
+		port:     cfg.Port,
+		listener: cfg.Listener,
+		verbose:  cfg.Verbose,
+		logger:   logger,
+		ctx:      ctx,
 	}
 
 	// Start the snapshot loop - this is critical for status detection!
@@ -107,6 +112,13 @@ func NewLibClient(ctx context.Context, cfg LibClientConfig) (*LibClient, error)
 
 // Start begins serving the HTTP API (non-blocking)
 func (c *LibClient) Start() error {
+	// Serve the caller's listener, so the port can't be taken in between
+	if c.listener != nil {
+		c.httpServer = &http.Server{Handler: c.server.Handler()}
+		go func() { _ = c.httpServer.Serve(c.listener) }()
+		return nil
+	}
+
 	// Start server in goroutine since Start() blocks
 	errCh := make(chan error, 1)
 	go func() {
@@ -230,7 +242,7 @@ func (c *LibClient) WaitForCompletion(ctx context.Context, timeout time.Duration
 			lastScreen = screen
 
 			if c.verbose {
-				fmt.Printf("[LIB] Screen updated (elapsed: %s)\n", time.Since(start).Round(time.Second))
+				c.logger.Debug("screen updated", "elapsed", time.Since(start).Round(time.Second))
 			}
 		} else {
 			stableCount++
@@ -238,7 +250,7 @@ func (c *LibClient) WaitForCompletion(ctx context.Context, timeout time.Duration
 			// Agent is complete when it was running and now stable
 			if wasRunning && stableCount >= requiredStableChecks {
 				if c.verbose {
-					fmt.Printf("[LIB] Agent completed (elapsed: %s)\n", time.Since(start).Round(time.Second))
+					c.logger.Debug("agent completed", "elapsed", time.Since(start).Round(time.Second))
 				}
 				return nil
 			}
@@ -258,6 +270,15 @@ func (c *LibClient) Close(ctx context.Context) error {
 		}
 	}
 
+	switch {
+	case c.httpServer != nil:
+		if err := c.httpServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("server stop: %w", err))
+		}
+	case c.listener != nil:
+		_ = c.listener.Close()
+	}
+
 	if c.process != nil {
 		if err := c.process.Close(c.logger, 10*time.Second); err != nil {
 			errs = append(errs, fmt.Errorf("process close: %w", err))
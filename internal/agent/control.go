@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tuannvm/pagent/internal/events"
+)
+
+// errStopped is reported for agents cancelled through CancelAgent
+var errStopped = errors.New("agent stopped on request")
+
+// Pause stops the manager from starting new agents until Resume is called.
+// Agents that are already running are not interrupted.
+func (m *Manager) Pause() {
+	m.mu.Lock()
+	if m.resumeCh != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.resumeCh = make(chan struct{})
+	m.mu.Unlock()
+
+	m.publish(events.New(events.RunPaused))
+}
+
+// Resume lets a paused manager start agents again
+func (m *Manager) Resume() {
+	m.mu.Lock()
+	if m.resumeCh == nil {
+		m.mu.Unlock()
+		return
+	}
+	close(m.resumeCh)
+	m.resumeCh = nil
+	m.mu.Unlock()
+
+	m.publish(events.New(events.RunResumed))
+}
+
+// Paused reports whether the manager is paused
+func (m *Manager) Paused() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.resumeCh != nil
+}
+
+// waitIfPaused blocks while the manager is paused
+func (m *Manager) waitIfPaused(ctx context.Context) error {
+	m.mu.Lock()
+	ch := m.resumeCh
+	m.mu.Unlock()
+	if ch == nil {
+		return nil
+	}
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RestartAgent stops the named agent's current attempt and starts it again
+// with a fresh agentapi process. The agent must be running.
+func (m *Manager) RestartAgent(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cancel, ok := m.attempts[name]
+	if !ok {
+		return fmt.Errorf("agent %s is not running", name)
+	}
+	m.restarts[name] = true
+	cancel()
+	return nil
+}
+
+// CancelAgent stops the named agent; its result is reported as failed
+func (m *Manager) CancelAgent(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cancel, ok := m.attempts[name]
+	if !ok {
+		return fmt.Errorf("agent %s is not running", name)
+	}
+	m.cancelled[name] = true
+	cancel()
+	return nil
+}
+
+// beginAttempt registers a cancellable attempt for name
+func (m *Manager) beginAttempt(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	attemptCtx, cancel := context.WithCancel(ctx)
+
+	m.mu.Lock()
+	m.attempts[name] = cancel
+	m.mu.Unlock()
+
+	return attemptCtx, cancel
+}
+
+// endAttempt unregisters name's attempt and reports whether a restart or
+// cancellation was requested while it ran
+func (m *Manager) endAttempt(name string) (restart, cancelled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, name)
+	restart, cancelled = m.restarts[name], m.cancelled[name]
+	delete(m.restarts, name)
+	delete(m.cancelled, name)
+	return restart, cancelled
+}
@@ -3,19 +3,31 @@ package agent
 import (
 	"context"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/tuannvm/pagent/internal/api"
+	"github.com/tuannvm/pagent/internal/events"
 )
 
-// spawnAgent starts an agent using the agentapi library
-func (m *Manager) spawnAgent(ctx context.Context, name string, port int) (*RunningAgent, error) {
+// spawnAgent starts an agent using the agentapi library, serving its API
+// on ln, which is bound to port. ln is closed if the agent fails to start.
+func (m *Manager) spawnAgent(ctx context.Context, name string, ln net.Listener, port int) (*RunningAgent, error) {
+	agentArgs, err := m.agentArgs(name)
+	if err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+
 	libClient, err := NewLibClient(ctx, LibClientConfig{
-		Port:     port,
-		Verbose:  m.verbose,
-		AgentCmd: "claude",
+		Port:      port,
+		Listener:  ln,
+		Verbose:   m.verbose,
+		AgentCmd:  "claude",
+		AgentArgs: agentArgs,
 	})
 	if err != nil {
+		_ = ln.Close()
 		return nil, fmt.Errorf("failed to create lib client: %w", err)
 	}
 
@@ -62,8 +74,9 @@ func (m *Manager) waitForCompletion(ctx context.Context, agent *RunningAgent, ti
 			if consecutiveErrors >= maxConsecutiveErrors {
 				return fmt.Errorf("agent API unreachable after %d consecutive failures - process likely crashed", consecutiveErrors)
 			}
-			if m.verbose && consecutiveErrors%10 == 0 {
-				fmt.Printf("[DEBUG] Agent %s API error (attempt %d/%d): %v\n",
+			// A failed poll is not a retry of the agent, only of the status check
+			if consecutiveErrors == 1 || consecutiveErrors%10 == 0 {
+				m.debugf(agent.Name, "Agent %s status check failed (%d/%d): %v",
 					agent.Name, consecutiveErrors, maxConsecutiveErrors, err)
 			}
 			time.Sleep(pollInterval)
@@ -73,10 +86,15 @@ func (m *Manager) waitForCompletion(ctx context.Context, agent *RunningAgent, ti
 
 		// Track status transitions
 		if status.Status != lastStatus {
-			if m.verbose {
-				fmt.Printf("[DEBUG] Agent %s status: %s (elapsed: %s)\n",
-					agent.Name, status.Status, time.Since(start).Round(time.Second))
-			}
+			m.debugf(agent.Name, "Agent %s status: %s (elapsed: %s)",
+				agent.Name, status.Status, time.Since(start).Round(time.Second))
+
+			changed := events.New(events.AgentStatusChanged)
+			changed.Agent = agent.Name
+			changed.Status = status.Status
+			changed.DurationMS = time.Since(start).Milliseconds()
+			m.publish(changed)
+
 			lastStatus = status.Status
 		}
 
@@ -86,16 +104,14 @@ func (m *Manager) waitForCompletion(ctx context.Context, agent *RunningAgent, ti
 
 		// Agent is done when it transitions from running to stable
 		if wasRunning && status.Status == "stable" {
-			if m.verbose {
-				fmt.Printf("[DEBUG] Agent %s completed in %s\n",
-					agent.Name, time.Since(start).Round(time.Second))
-			}
+			m.debugf(agent.Name, "Agent %s completed in %s",
+				agent.Name, time.Since(start).Round(time.Second))
 			return nil
 		}
 
 		// Progress indicator every 30 seconds
 		if m.verbose && time.Since(lastProgressLog) > 30*time.Second {
-			fmt.Printf("[DEBUG] Agent %s still %s... (elapsed: %s)\n",
+			m.debugf(agent.Name, "Agent %s still %s... (elapsed: %s)",
 				agent.Name, status.Status, time.Since(start).Round(time.Second))
 			lastProgressLog = time.Now()
 		}
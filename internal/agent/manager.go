@@ -2,8 +2,8 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"sync"
@@ -11,18 +11,17 @@ import (
 
 	"github.com/tuannvm/pagent/internal/api"
 	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/events"
 	"github.com/tuannvm/pagent/internal/prompt"
 	"github.com/tuannvm/pagent/internal/state"
+	"github.com/tuannvm/pagent/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
 	basePort      = 3284
 	healthTimeout = 120 * time.Second // 2 min for Claude Code to fully initialize
-)
-
-// State file paths
-var (
-	StateFile = filepath.Join(os.TempDir(), "pagent-state.json")
+	maxPortProbes = 100               // Ports tried before giving up on finding a free one
 )
 
 // Result represents the result of running an agent
@@ -31,6 +30,7 @@ type Result struct {
 	OutputPath string
 	Error      error
 	Duration   time.Duration
+	Skipped    bool // True if resume mode found the output up-to-date
 }
 
 // RunningAgent tracks a running agent
@@ -53,7 +53,17 @@ type Manager struct {
 	portAlloc    int
 	mu           sync.Mutex
 	promptLoader *prompt.Loader
-	stateManager *state.Manager // Tracks resume state for incremental execution
+	stateManager *state.Manager               // Tracks resume state for incremental execution
+	runID        string                       // Identifies the run in published events
+	bus          *events.Bus                  // Lifecycle event bus (nil = no subscribers)
+	logf         func(string, ...interface{}) // Verbose output without a bus (nil = stderr)
+	resolution   *prompt.StackResolution      // PRD-vs-config stack conflicts (nil = none)
+
+	// Run control (see control.go)
+	resumeCh  chan struct{}                 // Non-nil while paused; closed on resume
+	attempts  map[string]context.CancelFunc // Cancels the running attempt per agent
+	restarts  map[string]bool               // Restart requested for the running attempt
+	cancelled map[string]bool               // Stop requested for the running attempt
 }
 
 // NewManager creates a new agent manager
@@ -67,6 +77,9 @@ func NewManager(cfg *config.Config, prdPath string, verbose bool) *Manager {
 		portAlloc:    basePort,
 		promptLoader: prompt.NewLoader("prompts"), // Load from ./prompts if exists
 		stateManager: state.NewManager(cfg.OutputDir),
+		attempts:     make(map[string]context.CancelFunc),
+		restarts:     make(map[string]bool),
+		cancelled:    make(map[string]bool),
 	}
 	m.initializeState()
 	return m
@@ -84,6 +97,9 @@ func NewManagerWithInputs(cfg *config.Config, primaryFile string, inputFiles []s
 		portAlloc:    basePort,
 		promptLoader: prompt.NewLoader("prompts"),
 		stateManager: state.NewManager(cfg.OutputDir),
+		attempts:     make(map[string]context.CancelFunc),
+		restarts:     make(map[string]bool),
+		cancelled:    make(map[string]bool),
 	}
 	m.initializeState()
 	return m
@@ -92,23 +108,128 @@ func NewManagerWithInputs(cfg *config.Config, primaryFile string, inputFiles []s
 // initializeState loads existing resume state and updates input/config hashes.
 func (m *Manager) initializeState() {
 	// Load existing state (if any)
-	if err := m.stateManager.Load(); err != nil && m.verbose {
-		fmt.Printf("[DEBUG] Failed to load resume state: %v\n", err)
+	if err := m.stateManager.Load(); err != nil {
+		m.debugf("", "Failed to load resume state: %v", err)
 	}
 
 	// Update input hash
-	if err := m.stateManager.UpdateInputHash(m.inputFiles); err != nil && m.verbose {
-		fmt.Printf("[DEBUG] Failed to update input hash: %v\n", err)
+	if err := m.stateManager.UpdateInputHash(m.inputFiles); err != nil {
+		m.debugf("", "Failed to update input hash: %v", err)
 	}
 
 	// Update config hash
-	if err := m.stateManager.UpdateConfigHash(m.config.Persona, m.config.Stack, m.config.Preferences); err != nil && m.verbose {
-		fmt.Printf("[DEBUG] Failed to update config hash: %v\n", err)
+	if err := m.stateManager.UpdateConfigHash(m.config.Persona, m.config.Stack, m.config.Preferences); err != nil {
+		m.debugf("", "Failed to update config hash: %v", err)
 	}
 }
 
+// SetStackResolution passes the PRD-vs-config stack conflicts of the run,
+// resolved or not, to every agent's prompt
+func (m *Manager) SetStackResolution(r *prompt.StackResolution) {
+	m.resolution = r
+}
+
+// SetEventBus makes the manager publish lifecycle events to bus.
+// runID is attached to every event published by this manager.
+func (m *Manager) SetEventBus(runID string, bus *events.Bus) {
+	m.runID = runID
+	m.bus = bus
+}
+
+// SetLogger sends verbose messages to logf when there is no event bus,
+// instead of printing them to stderr
+func (m *Manager) SetLogger(logf func(format string, args ...interface{})) {
+	m.logf = logf
+}
+
+// publish stamps e with the run ID and sends it to the event bus (if any)
+func (m *Manager) publish(e events.Event) {
+	e.RunID = m.runID
+	m.bus.Publish(e)
+}
+
+// debugf publishes a verbose diagnostic message for an agent.
+// Without an event bus the message goes to the logger set with SetLogger,
+// or stderr, so it never mixes with machine-readable output on stdout.
+func (m *Manager) debugf(agentName, format string, args ...interface{}) {
+	if !m.verbose {
+		return
+	}
+	switch {
+	case m.bus == nil && m.logf != nil:
+		m.logf(format, args...)
+		return
+	case m.bus == nil:
+		fmt.Fprintf(os.Stderr, "[DEBUG] "+format+"\n", args...)
+		return
+	}
+	e := events.New(events.Log)
+	e.Agent = agentName
+	e.Message = fmt.Sprintf(format, args...)
+	m.publish(e)
+}
+
 // RunAgent spawns and runs a single agent
 func (m *Manager) RunAgent(ctx context.Context, name string) Result {
+	ctx, span := telemetry.StartSpan(ctx, "agent.run",
+		attribute.String("pagent.agent", name),
+		attribute.String("pagent.run_id", m.runID),
+	)
+
+	var result Result
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := m.beginAttempt(ctx, name)
+		result = m.runAgent(attemptCtx, name)
+		cancel()
+
+		restart, cancelled := m.endAttempt(name)
+		if cancelled {
+			result.Error = errStopped
+		}
+		if !restart || ctx.Err() != nil {
+			break
+		}
+
+		// The next attempt is a retry of the agent
+		retry := events.New(events.AgentRetry)
+		retry.Agent = name
+		retry.Attempt = attempt + 1
+		if result.Error != nil {
+			retry.Error = result.Error.Error()
+		}
+		m.publish(retry)
+
+		restarted := events.New(events.AgentRestarted)
+		restarted.Agent = name
+		restarted.Attempt = attempt + 1
+		m.publish(restarted)
+	}
+
+	span.SetAttributes(
+		attribute.Bool("pagent.skipped", result.Skipped),
+		attribute.String("pagent.output_path", result.OutputPath),
+	)
+	telemetry.End(span, result.Error)
+
+	if result.Skipped {
+		return result
+	}
+
+	e := events.New(events.AgentCompleted)
+	if result.Error != nil {
+		e = events.New(events.AgentFailed)
+		e.Error = result.Error.Error()
+	}
+	e.Agent = name
+	e.OutputPath = result.OutputPath
+	e.DurationMS = result.Duration.Milliseconds()
+	m.publish(e)
+
+	return result
+}
+
+// runAgent performs the agent lifecycle; RunAgent wraps it to emit the final event
+func (m *Manager) runAgent(ctx context.Context, name string) Result {
 	start := time.Now()
 
 	agentCfg, ok := m.config.Agents[name]
@@ -119,85 +240,46 @@ func (m *Manager) RunAgent(ctx context.Context, name string) Result {
 		}
 	}
 
-	// Determine output path based on agent type and mode
-	// Spec outputs (architect, qa, security) go to SpecsOutputDir
-	// Code outputs (implementer, verifier) go to CodeOutputDir in modify mode,
-	// but in create mode, the agent output already includes the code/ prefix
-	var outputPath string
-	if isSpecAgent(name) {
-		outputPath = filepath.Join(m.config.GetEffectiveSpecsOutputDir(), agentCfg.Output)
-	} else if isCodeAgent(name) && m.config.IsModifyMode() {
-		// In modify mode, code goes to target codebase
-		outputPath = filepath.Join(m.config.GetEffectiveCodeOutputDir(), agentCfg.Output)
-	} else {
-		// In create mode or for non-code agents, use OutputDir directly
-		// (agent output like "code/.complete" already has the code/ prefix)
-		outputPath = filepath.Join(m.config.OutputDir, agentCfg.Output)
-	}
-	absOutputPath, _ := filepath.Abs(outputPath)
+	absOutputPath, _ := filepath.Abs(m.outputPath(name, agentCfg))
 
 	// Resume mode: use content hashing to determine if regeneration is needed
 	if m.config.ResumeMode {
 		deps := m.config.GetDependencies(name)
+		_, resumeSpan := telemetry.StartSpan(ctx, "agent.resume_check")
 		shouldRegen, reason := m.stateManager.ShouldRegenerate(name, absOutputPath, deps)
+		resumeSpan.SetAttributes(
+			attribute.Bool("pagent.regenerate", shouldRegen),
+			attribute.String("pagent.reason", reason),
+		)
+		resumeSpan.End()
 		if !shouldRegen {
-			if m.verbose {
-				fmt.Printf("[DEBUG] Skipping agent %s - %s: %s\n", name, reason, absOutputPath)
-			}
+			m.debugf(name, "Skipping agent %s - %s: %s", name, reason, absOutputPath)
+			e := events.New(events.AgentSkipped)
+			e.Agent = name
+			e.OutputPath = absOutputPath
+			e.Reason = reason
+			m.publish(e)
 			return Result{
 				Agent:      name,
 				OutputPath: absOutputPath,
 				Duration:   time.Since(start),
+				Skipped:    true,
 			}
 		}
-		if m.verbose {
-			fmt.Printf("[DEBUG] Regenerating %s - %s\n", name, reason)
-		}
+		m.debugf(name, "Regenerating %s - %s", name, reason)
 	}
 
-	// Allocate port
-	port := m.allocatePort()
-
-	if m.verbose {
-		fmt.Printf("[DEBUG] Starting agent %s on port %d\n", name, port)
+	// Hold new agents while the run is paused
+	if err := m.waitIfPaused(ctx); err != nil {
+		return Result{
+			Agent:    name,
+			Error:    err,
+			Duration: time.Since(start),
+		}
 	}
 
 	// Build the prompt using template loader
-	absOutputDir, _ := filepath.Abs(m.config.OutputDir)
-
-	// In force mode, don't pass existing files (treat as fresh generation)
-	var existingFiles []string
-	if !m.config.ForceMode {
-		existingFiles = m.listExistingFiles(absOutputDir)
-	}
-
-	// Determine effective output directories based on mode
-	specsOutputDir := m.config.GetEffectiveSpecsOutputDir()
-	codeOutputDir := m.config.GetEffectiveCodeOutputDir()
-	absSpecsOutputDir, _ := filepath.Abs(specsOutputDir)
-	absCodeOutputDir, _ := filepath.Abs(codeOutputDir)
-
-	promptVars := prompt.Variables{
-		PRDPath:       m.prdPath,
-		InputFiles:    m.inputFiles,
-		InputDir:      m.inputDir,
-		HasMultiInput: len(m.inputFiles) > 1,
-		OutputDir:     absOutputDir,
-		OutputPath:    absOutputPath,
-		AgentName:     name,
-		ExistingFiles: existingFiles,
-		HasExisting:   len(existingFiles) > 0 && !m.config.ForceMode,
-		Persona:       m.config.Persona,
-		// Stack and Preferences are now the same type in config and prompt packages
-		// (both alias types.TechStack and types.ArchitecturePreferences)
-		Stack:       m.config.Stack,
-		Preferences: m.config.Preferences,
-		// Mode-specific variables
-		Mode:           m.config.Mode,
-		TargetCodebase: m.config.TargetCodebase,
-		SpecsOutputDir: absSpecsOutputDir,
-		CodeOutputDir:  absCodeOutputDir,
-	}
+	promptVars := m.promptVariables(name, absOutputPath)
 
 	renderedPrompt, err := m.promptLoader.LoadAndRender(name, agentCfg.Prompt, agentCfg.PromptFile, promptVars)
 	if err != nil {
@@ -208,8 +290,22 @@ func (m *Manager) RunAgent(ctx context.Context, name string) Result {
 		}
 	}
 
+	// Bind a port for the agent's API
+	ln, port, err := m.listenPort()
+	if err != nil {
+		return Result{
+			Agent:    name,
+			Error:    err,
+			Duration: time.Since(start),
+		}
+	}
+
+	m.debugf(name, "Starting agent %s on port %d", name, port)
+
 	// Start AgentAPI process
-	agent, err := m.spawnAgent(ctx, name, port)
+	_, spawnSpan := telemetry.StartSpan(ctx, "agent.spawn", attribute.Int("pagent.port", port))
+	agent, err := m.spawnAgent(ctx, name, ln, port)
+	telemetry.End(spawnSpan, err)
 	if err != nil {
 		return Result{
 			Agent:    name,
@@ -222,6 +318,11 @@ func (m *Manager) RunAgent(ctx context.Context, name string) Result {
 	m.agents[name] = agent
 	m.mu.Unlock()
 
+	started := events.New(events.AgentStarted)
+	started.Agent = name
+	started.Port = port
+	m.publish(started)
+
 	// Save state for monitoring commands
 	_ = m.saveState()
 
@@ -231,7 +332,10 @@ func (m *Manager) RunAgent(ctx context.Context, name string) Result {
 	}()
 
 	// Wait for agent API to be healthy
-	if err := agent.Client.WaitForHealthy(healthTimeout); err != nil {
+	_, healthSpan := telemetry.StartSpan(ctx, "agent.wait_healthy")
+	err = agent.Client.WaitForHealthy(ctx, healthTimeout)
+	telemetry.End(healthSpan, err)
+	if err != nil {
 		return Result{
 			Agent:    name,
 			Error:    fmt.Errorf("agent failed to start: %w", err),
@@ -239,13 +343,19 @@ func (m *Manager) RunAgent(ctx context.Context, name string) Result {
 		}
 	}
 
-	if m.verbose {
-		fmt.Printf("[DEBUG] Agent %s API is healthy, waiting for stable state\n", name)
-	}
+	m.debugf(name, "Agent %s API is healthy, waiting for stable state", name)
+
+	healthy := events.New(events.AgentHealthy)
+	healthy.Agent = name
+	healthy.Port = port
+	m.publish(healthy)
 
 	// Wait for agent to be ready for input (stable state)
 	// Claude Code starts in "running" state while loading
-	if err := agent.Client.WaitForStable(healthTimeout); err != nil {
+	_, stableSpan := telemetry.StartSpan(ctx, "agent.wait_stable")
+	err = agent.Client.WaitForStable(ctx, healthTimeout)
+	telemetry.End(stableSpan, err)
+	if err != nil {
 		return Result{
 			Agent:    name,
 			Error:    fmt.Errorf("agent failed to become stable: %w", err),
@@ -253,12 +363,13 @@ func (m *Manager) RunAgent(ctx context.Context, name string) Result {
 		}
 	}
 
-	if m.verbose {
-		fmt.Printf("[DEBUG] Agent %s is stable, sending task\n", name)
-	}
+	m.debugf(name, "Agent %s is stable, sending task", name)
 
 	// Send the task prompt
-	if err := agent.Client.SendMessage(renderedPrompt, "user"); err != nil {
+	_, sendSpan := telemetry.StartSpan(ctx, "agent.send_prompt", attribute.Int("pagent.prompt_bytes", len(renderedPrompt)))
+	err = agent.Client.SendMessage(ctx, renderedPrompt, "user")
+	telemetry.End(sendSpan, err)
+	if err != nil {
 		return Result{
 			Agent:    name,
 			Error:    fmt.Errorf("failed to send task: %w", err),
@@ -266,9 +377,16 @@ func (m *Manager) RunAgent(ctx context.Context, name string) Result {
 		}
 	}
 
+	promptSent := events.New(events.AgentPromptSent)
+	promptSent.Agent = name
+	m.publish(promptSent)
+
 	// Wait for agent to complete (become stable after being running)
 	timeout := time.Duration(m.config.Timeout) * time.Second
-	if err := m.waitForCompletion(ctx, agent, timeout); err != nil {
+	waitCtx, waitSpan := telemetry.StartSpan(ctx, "agent.wait_completion")
+	err = m.waitForCompletion(waitCtx, agent, timeout)
+	telemetry.End(waitSpan, err)
+	if err != nil {
 		return Result{
 			Agent:    name,
 			Error:    err,
@@ -287,11 +405,11 @@ func (m *Manager) RunAgent(ctx context.Context, name string) Result {
 
 	// Record successful output for resume state tracking
 	deps := m.config.GetDependencies(name)
-	if err := m.stateManager.RecordAgentOutput(name, absOutputPath, deps); err != nil && m.verbose {
-		fmt.Printf("[DEBUG] Failed to record agent output state: %v\n", err)
+	if err := m.stateManager.RecordAgentOutput(name, absOutputPath, deps); err != nil {
+		m.debugf(name, "Failed to record agent output state: %v", err)
 	}
-	if err := m.stateManager.Save(); err != nil && m.verbose {
-		fmt.Printf("[DEBUG] Failed to save resume state: %v\n", err)
+	if err := m.stateManager.Save(); err != nil {
+		m.debugf(name, "Failed to save resume state: %v", err)
 	}
 
 	return Result{
@@ -301,92 +419,81 @@ func (m *Manager) RunAgent(ctx context.Context, name string) Result {
 	}
 }
 
-// allocatePort returns the next available port
-func (m *Manager) allocatePort() int {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	port := m.portAlloc
-	m.portAlloc++
-	return port
+// outputPath returns the path agent name writes its output to, under the
+// output root of its kind (see config.AgentOutputRoot)
+func (m *Manager) outputPath(name string, agentCfg config.AgentConfig) string {
+	return filepath.Join(m.config.AgentOutputRoot(name), agentCfg.Output)
 }
 
-// saveState persists agent state to disk for monitoring commands
-func (m *Manager) saveState() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	state := make(map[string]int)
-	for name, agent := range m.agents {
-		state[name] = agent.Port
-	}
-
-	data, err := json.Marshal(state)
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(StateFile, data, 0644)
-}
-
-// ClearState removes the state file
-func ClearState() {
-	_ = os.Remove(StateFile)
-}
-
-// RemoveAgentFromState removes a specific agent from the state file
-func RemoveAgentFromState(agentName string) error {
-	state, err := LoadState()
-	if err != nil {
-		return err
-	}
-
-	delete(state, agentName)
-
-	if len(state) == 0 {
-		ClearState()
-		return nil
-	}
+// promptVariables builds the template variables for agent name
+func (m *Manager) promptVariables(name, absOutputPath string) prompt.Variables {
+	absOutputDir, _ := filepath.Abs(m.config.OutputDir)
 
-	data, err := json.Marshal(state)
-	if err != nil {
-		return err
+	// In force mode, don't pass existing files (treat as fresh generation)
+	var existingFiles []string
+	if !m.config.ForceMode {
+		existingFiles = m.listExistingFiles(absOutputDir)
 	}
 
-	return os.WriteFile(StateFile, data, 0644)
-}
-
-// LoadState loads agent state from disk
-func LoadState() (map[string]int, error) {
-	data, err := os.ReadFile(StateFile)
-	if err != nil {
-		return nil, err
-	}
+	// Determine effective output directories based on mode
+	specsOutputDir := m.config.GetEffectiveSpecsOutputDir()
+	codeOutputDir := m.config.GetEffectiveCodeOutputDir()
+	absSpecsOutputDir, _ := filepath.Abs(specsOutputDir)
+	absCodeOutputDir, _ := filepath.Abs(codeOutputDir)
 
-	var state map[string]int
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, err
+	return prompt.Variables{
+		PRDPath:       m.prdPath,
+		InputFiles:    m.inputFiles,
+		InputDir:      m.inputDir,
+		HasMultiInput: len(m.inputFiles) > 1,
+		OutputDir:     absOutputDir,
+		OutputPath:    absOutputPath,
+		AgentName:     name,
+		ExistingFiles: existingFiles,
+		HasExisting:   len(existingFiles) > 0 && !m.config.ForceMode,
+		Persona:       m.config.Persona,
+		PersonaTraits: m.config.PersonaTraits(m.config.Persona),
+		// Stack and Preferences are now the same type in config and prompt packages
+		// (both alias types.TechStack and types.ArchitecturePreferences)
+		Stack:       m.config.Stack,
+		Preferences: m.config.Preferences,
+		// Mode-specific variables
+		Mode:           m.config.Mode,
+		TargetCodebase: m.config.TargetCodebase,
+		SpecsOutputDir: absSpecsOutputDir,
+		CodeOutputDir:  absCodeOutputDir,
+		Resolution:     m.resolution,
 	}
-
-	return state, nil
 }
 
-// isSpecAgent returns true if the agent produces specification documents
-func isSpecAgent(name string) bool {
-	specAgents := map[string]bool{
-		"architect": true,
-		"qa":        true,
-		"security":  true,
+// RenderPrompt renders the prompt agent name would be started with,
+// without starting it
+func (m *Manager) RenderPrompt(name string) (string, error) {
+	agentCfg, ok := m.config.Agents[name]
+	if !ok {
+		return "", fmt.Errorf("unknown agent: %s", name)
 	}
-	return specAgents[name]
+	absOutputPath, _ := filepath.Abs(m.outputPath(name, agentCfg))
+	return m.promptLoader.LoadAndRender(name, agentCfg.Prompt, agentCfg.PromptFile, m.promptVariables(name, absOutputPath))
 }
 
-// isCodeAgent returns true if the agent produces or modifies code
-func isCodeAgent(name string) bool {
-	codeAgents := map[string]bool{
-		"implementer": true,
-		"verifier":    true,
+// listenPort binds the next available port on localhost, skipping ports
+// already bound by other processes (e.g. agents of another run in the
+// daemon). The listener is handed to the agent's API server, so the port
+// stays taken from the moment it is chosen.
+func (m *Manager) listenPort() (net.Listener, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	first := m.portAlloc
+	for i := 0; i < maxPortProbes; i++ {
+		port := m.portAlloc
+		m.portAlloc++
+		ln, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+		if err == nil {
+			return ln, port, nil
+		}
 	}
-	return codeAgents[name]
+	return nil, 0, fmt.Errorf("no free port for the agent API in %d-%d", first, m.portAlloc-1)
 }
 
 // listExistingFiles returns a list of files in the output directory
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenPortHoldsPorts(t *testing.T) {
+	taken, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = taken.Close() }()
+	base := taken.Addr().(*net.TCPAddr).Port
+
+	// Two runs in one daemon start from the same port
+	first := newTestManager(t, "output_dir: ./outputs\n")
+	second := newTestManager(t, "output_dir: ./outputs\n")
+	first.portAlloc, second.portAlloc = base, base
+
+	ln1, port1, err := first.listenPort()
+	if err != nil {
+		t.Fatalf("listenPort() error = %v", err)
+	}
+	defer func() { _ = ln1.Close() }()
+	ln2, port2, err := second.listenPort()
+	if err != nil {
+		t.Fatalf("listenPort() error = %v", err)
+	}
+	defer func() { _ = ln2.Close() }()
+
+	if port1 == base || port2 == base {
+		t.Errorf("ports %d, %d include %d, which is bound elsewhere", port1, port2, base)
+	}
+	if port1 == port2 {
+		t.Errorf("both runs got port %d", port1)
+	}
+	if got := ln1.Addr().(*net.TCPAddr).Port; got != port1 {
+		t.Errorf("listener bound to %d, reported %d", got, port1)
+	}
+}
+
+func TestSaveStateKeepsOtherRuns(t *testing.T) {
+	stateFile := StateFile
+	StateFile = filepath.Join(t.TempDir(), "pagent-state.json")
+	t.Cleanup(func() { StateFile = stateFile })
+
+	// Two runs of one daemon, as with queue.concurrency > 1
+	first := newTestManager(t, "output_dir: ./outputs\n")
+	second := newTestManager(t, "output_dir: ./outputs\n")
+	first.SetEventBus("run-1", nil)
+	second.SetEventBus("run-2", nil)
+	first.agents["architect"] = &RunningAgent{Name: "architect", Port: 3284}
+	second.agents["architect"] = &RunningAgent{Name: "architect", Port: 3285}
+
+	if err := first.saveState(); err != nil {
+		t.Fatalf("saveState() error = %v", err)
+	}
+	if err := second.saveState(); err != nil {
+		t.Fatalf("saveState() error = %v", err)
+	}
+	state, err := LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if state["run-1"]["architect"] != 3284 || state["run-2"]["architect"] != 3285 {
+		t.Errorf("state = %v, want both runs' agents", state)
+	}
+	if info, err := os.Stat(StateFile); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("state file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// The first run finishing leaves the second's agents listed
+	delete(first.agents, "architect")
+	if err := first.saveState(); err != nil {
+		t.Fatalf("saveState() error = %v", err)
+	}
+	if err := RemoveAgentFromState("run-9", "architect"); err != nil {
+		t.Fatalf("RemoveAgentFromState() error = %v", err)
+	}
+	state, err = LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	agents := state.Agents()
+	if len(agents) != 1 || agents[0].RunID != "run-2" || agents[0].Port != 3285 {
+		t.Errorf("agents = %+v, want only run-2's architect", agents)
+	}
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tuannvm/pagent/internal/config"
+)
+
+// mcpConfigDir holds the generated per-agent MCP config files, relative to
+// the output directory
+const mcpConfigDir = ".pagent/mcp"
+
+// agentArgs returns the agent CLI arguments giving an agent its configured
+// MCP servers and nothing else. The servers are written to a per-agent MCP
+// config file; agents without servers keep the CLI defaults, unless they set
+// an empty mcp_servers to run without any.
+func (m *Manager) agentArgs(name string) ([]string, error) {
+	servers := m.config.AgentMCPServers(name)
+	if len(servers) == 0 && m.config.Agents[name].MCPServers == nil {
+		return nil, nil
+	}
+
+	path, err := filepath.Abs(filepath.Join(m.config.OutputDir, mcpConfigDir, name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve MCP config path: %w", err)
+	}
+	data, err := json.MarshalIndent(map[string]any{"mcpServers": servers}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal MCP config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create MCP config directory: %w", err)
+	}
+	// Server env and headers may hold credentials
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write MCP config: %w", err)
+	}
+
+	m.debugf(name, "MCP servers for %s written to %s", name, path)
+	args := []string{"--mcp-config", path, "--strict-mcp-config"}
+	if tools := allowedMCPTools(servers); len(tools) > 0 {
+		args = append(args, "--allowedTools", strings.Join(tools, ","))
+	}
+	return args, nil
+}
+
+// allowedMCPTools lists the MCP tools an agent may call without prompting:
+// every tool of a server, or only the tools listed for it
+func allowedMCPTools(servers map[string]config.MCPServerConfig) []string {
+	var tools []string
+	for server, cfg := range servers {
+		if len(cfg.Tools) == 0 {
+			tools = append(tools, "mcp__"+server)
+			continue
+		}
+		for _, tool := range cfg.Tools {
+			tools = append(tools, "mcp__"+server+"__"+tool)
+		}
+	}
+	sort.Strings(tools)
+	return tools
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tuannvm/pagent/internal/config"
+)
+
+// newTestManager returns a manager for a config file with the given content
+func newTestManager(t *testing.T, content string) *Manager {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Chdir(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return NewManager(cfg, filepath.Join(dir, "prd.md"), false)
+}
+
+// readMCPConfig returns the servers in a generated MCP config file
+func readMCPConfig(t *testing.T, path string) map[string]config.MCPServerConfig {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("MCP config not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("MCP config mode = %v, want 0600", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file struct {
+		MCPServers map[string]config.MCPServerConfig `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("invalid MCP config: %v\n%s", err, data)
+	}
+	if file.MCPServers == nil {
+		t.Fatalf("MCP config has no mcpServers object:\n%s", data)
+	}
+	return file.MCPServers
+}
+
+func TestAgentArgsWithoutServers(t *testing.T) {
+	m := newTestManager(t, "output_dir: ./outputs\n")
+
+	args, err := m.agentArgs("architect")
+	if err != nil {
+		t.Fatalf("agentArgs() error = %v", err)
+	}
+	if args != nil {
+		t.Errorf("agentArgs() = %v, want CLI defaults", args)
+	}
+}
+
+func TestAgentArgsGlobalServers(t *testing.T) {
+	m := newTestManager(t, `output_dir: ./outputs
+mcp_servers:
+  github:
+    command: github-mcp
+agents:
+  qa:
+    output: test-plan.md
+    mcp_servers:
+      github:
+        tools: [search_issues]
+`)
+
+	args, err := m.agentArgs("architect")
+	if err != nil {
+		t.Fatalf("agentArgs() error = %v", err)
+	}
+	path, _ := filepath.Abs(filepath.Join("outputs", mcpConfigDir, "architect.json"))
+	want := []string{"--mcp-config", path, "--strict-mcp-config", "--allowedTools", "mcp__github"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("agentArgs(architect) = %v, want %v", args, want)
+	}
+	if servers := readMCPConfig(t, path); servers["github"].Command != "github-mcp" {
+		t.Errorf("architect servers = %+v, want the global github server", servers)
+	}
+
+	args, err = m.agentArgs("qa")
+	if err != nil {
+		t.Fatalf("agentArgs() error = %v", err)
+	}
+	if got := args[len(args)-1]; got != "mcp__github__search_issues" {
+		t.Errorf("qa allowed tools = %q, want only search_issues", got)
+	}
+}
+
+func TestAgentArgsEmptyServers(t *testing.T) {
+	m := newTestManager(t, `output_dir: ./outputs
+mcp_servers:
+  github:
+    command: github-mcp
+agents:
+  architect:
+    output: architecture.md
+    mcp_servers: {}
+`)
+
+	args, err := m.agentArgs("architect")
+	if err != nil {
+		t.Fatalf("agentArgs() error = %v", err)
+	}
+	path, _ := filepath.Abs(filepath.Join("outputs", mcpConfigDir, "architect.json"))
+	want := []string{"--mcp-config", path, "--strict-mcp-config"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("agentArgs() = %v, want %v", args, want)
+	}
+	if servers := readMCPConfig(t, path); len(servers) != 0 {
+		t.Errorf("servers = %+v, want none", servers)
+	}
+}
+
+func TestAllowedMCPTools(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers map[string]config.MCPServerConfig
+		want    []string
+	}{
+		{"none", nil, nil},
+		{"whole servers", map[string]config.MCPServerConfig{
+			"jira":   {Command: "jira-mcp"},
+			"github": {Command: "github-mcp"},
+		}, []string{"mcp__github", "mcp__jira"}},
+		{"listed tools", map[string]config.MCPServerConfig{
+			"github": {Command: "github-mcp", Tools: []string{"search_issues", "get_issue"}},
+			"docs":   {URL: "https://docs.example.com/mcp"},
+		}, []string{"mcp__docs", "mcp__github__get_issue", "mcp__github__search_issues"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowedMCPTools(tt.servers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allowedMCPTools() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
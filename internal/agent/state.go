@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+)
+
+// StateFile lists the running agents of every run of the current user, for
+// monitoring commands. Runs in several processes (or several runs in one
+// daemon) update their own entries under a lock.
+var StateFile = filepath.Join(os.TempDir(), fmt.Sprintf("pagent-state-%d.json", os.Getuid()))
+
+// State maps run IDs to the API ports of their running agents, by agent name
+type State map[string]map[string]int
+
+// StateAgent is a running agent listed in the state file
+type StateAgent struct {
+	RunID string
+	Name  string
+	Port  int
+}
+
+// Agents returns the agents of every run, sorted by name, then most recent
+// run first (run IDs start with their start time)
+func (s State) Agents() []StateAgent {
+	var agents []StateAgent
+	for runID, ports := range s {
+		for name, port := range ports {
+			agents = append(agents, StateAgent{RunID: runID, Name: name, Port: port})
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		if agents[i].Name != agents[j].Name {
+			return agents[i].Name < agents[j].Name
+		}
+		return agents[i].RunID > agents[j].RunID
+	})
+	return agents
+}
+
+// saveState records this manager's running agents under its run ID
+func (m *Manager) saveState() error {
+	m.mu.Lock()
+	agents := make(map[string]int, len(m.agents))
+	for name, agent := range m.agents {
+		agents[name] = agent.Port
+	}
+	m.mu.Unlock()
+
+	return updateState(func(s State) {
+		if len(agents) == 0 {
+			delete(s, m.runID)
+			return
+		}
+		s[m.runID] = agents
+	})
+}
+
+// RemoveAgentFromState removes an agent of a run from the state file
+func RemoveAgentFromState(runID, agentName string) error {
+	return updateState(func(s State) {
+		delete(s[runID], agentName)
+		if len(s[runID]) == 0 {
+			delete(s, runID)
+		}
+	})
+}
+
+// LoadState loads the running agents of every run from disk
+func LoadState() (State, error) {
+	data, err := os.ReadFile(StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", StateFile, err)
+	}
+	return state, nil
+}
+
+// updateState applies fn to the state file while holding its lock, so
+// concurrent runs never drop each other's entries. An unreadable file
+// (e.g. from an older version) starts over empty.
+func updateState(fn func(State)) error {
+	lock, err := os.OpenFile(StateFile+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to lock agent state: %w", err)
+	}
+	defer func() { _ = lock.Close() }()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock agent state: %w", err)
+	}
+	defer func() { _ = syscall.Flock(int(lock.Fd()), syscall.LOCK_UN) }()
+
+	state, err := LoadState()
+	if err != nil {
+		state = State{}
+	}
+	fn(state)
+
+	if len(state) == 0 {
+		if err := os.Remove(StateFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeStateFile(data)
+}
+
+// writeStateFile replaces the state file atomically, with mode 0600, so
+// readers never see a partial file
+func writeStateFile(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(StateFile), ".pagent-state-*")
+	if err != nil {
+		return fmt.Errorf("failed to write agent state: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), StateFile); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -51,7 +52,15 @@ func NewClient(port int) *Client {
 
 // GetStatus returns the current agent status
 func (c *Client) GetStatus() (*Status, error) {
-	resp, err := c.httpClient.Get(c.baseURL + "/status")
+	return c.getStatus(context.Background())
+}
+
+func (c *Client) getStatus(ctx context.Context) (*Status, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get status: %w", err)
 	}
@@ -70,8 +79,8 @@ func (c *Client) GetStatus() (*Status, error) {
 	return &status, nil
 }
 
-// SendMessage sends a message to the agent
-func (c *Client) SendMessage(content string, msgType string) error {
+// SendMessage sends a message to the agent, giving up when ctx is done
+func (c *Client) SendMessage(ctx context.Context, content string, msgType string) error {
 	msg := Message{
 		Content: content,
 		Type:    msgType,
@@ -82,11 +91,12 @@ func (c *Client) SendMessage(content string, msgType string) error {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(
-		c.baseURL+"/message",
-		"application/json",
-		bytes.NewReader(body),
-	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/message", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
@@ -121,43 +131,60 @@ func (c *Client) GetMessages() ([]ConversationMessage, error) {
 	return response.Messages, nil
 }
 
-// WaitForStable waits until the agent is in stable state
-func (c *Client) WaitForStable(timeout time.Duration) error {
+// WaitForStable waits until the agent is in stable state. It returns
+// ctx's error as soon as ctx is done.
+func (c *Client) WaitForStable(ctx context.Context, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
-		status, err := c.GetStatus()
-		if err != nil {
+		status, err := c.getStatus(ctx)
+		delay := 1 * time.Second
+		switch {
+		case err != nil:
 			// Agent might not be ready yet, continue waiting
-			time.Sleep(500 * time.Millisecond)
-			continue
-		}
-
-		if status.Status == "stable" {
+			delay = 500 * time.Millisecond
+		case status.Status == "stable":
 			return nil
 		}
 
-		time.Sleep(1 * time.Second)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("timeout waiting for stable state")
 }
 
-// WaitForHealthy waits until the agent responds to health checks
-func (c *Client) WaitForHealthy(timeout time.Duration) error {
+// WaitForHealthy waits until the agent responds to health checks. It
+// returns ctx's error as soon as ctx is done.
+func (c *Client) WaitForHealthy(ctx context.Context, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
-		_, err := c.GetStatus()
+		_, err := c.getStatus(ctx)
 		if err == nil {
 			return nil
 		}
-		time.Sleep(500 * time.Millisecond)
+		if err := sleep(ctx, 500*time.Millisecond); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("timeout waiting for agent to be healthy")
 }
 
+// sleep pauses for d, or returns ctx's error if ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 // IsRunning returns true if the agent is currently processing
 func (c *Client) IsRunning() (bool, error) {
 	status, err := c.GetStatus()
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for an agent API served by handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+	return NewClient(port)
+}
+
+func TestWaitReturnsWhenCancelled(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wait    func(*Client, context.Context) error
+	}{
+		{
+			name: "healthy",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			wait: func(c *Client, ctx context.Context) error { return c.WaitForHealthy(ctx, time.Minute) },
+		},
+		{
+			name: "stable",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{"status":"running"}`))
+			},
+			wait: func(c *Client, ctx context.Context) error { return c.WaitForStable(ctx, time.Minute) },
+		},
+		{
+			// The agent never answers; once the body is read, the
+			// server notices the client hanging up
+			name: "send",
+			handler: func(_ http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				<-r.Context().Done()
+			},
+			wait: func(c *Client, ctx context.Context) error { return c.SendMessage(ctx, "hi", "user") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, tt.handler)
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, cancel)
+
+			start := time.Now()
+			err := tt.wait(client, ctx)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want context.Canceled", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("returned after %v, want promptly after cancellation", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+// Package audit keeps an append-only JSON lines log of MCP tool calls and
+// runs: who did what, with which arguments and paths, and how it ended.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/config"
+)
+
+// Sources of audit records
+const (
+	SourceMCP    = "mcp"    // MCP tool call, or a run it submitted
+	SourceCLI    = "cli"    // Run started or queued from the command line
+	SourceDaemon = "daemon" // Run submitted to the daemon
+)
+
+// Outcomes of audited actions
+const (
+	OutcomeOK     = "ok"
+	OutcomeError  = "error"
+	OutcomeDenied = "denied"
+)
+
+// Record is one audited action
+type Record struct {
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Source     string         `json:"source"`
+	Actor      string         `json:"actor"`  // OAuth subject, or local user
+	Action     string         `json:"action"` // MCP tool or command
+	RunID      string         `json:"run_id,omitempty"`
+	Args       map[string]any `json:"args,omitempty"` // Secrets redacted
+	Paths      []string       `json:"paths,omitempty"`
+	Outcome    string         `json:"outcome"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Log appends records to a file, rotating it when it grows too large. A nil
+// Log discards records.
+type Log struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+}
+
+// mu serializes appends and rotation within the process
+var mu sync.Mutex
+
+// New returns the audit log configured by cfg, or nil when it is disabled
+func New(cfg config.AuditConfig) *Log {
+	if cfg.Disabled {
+		return nil
+	}
+	return &Log{
+		path:     cfg.EffectivePath(),
+		maxSize:  cfg.EffectiveMaxSize(),
+		maxFiles: cfg.EffectiveMaxFiles(),
+	}
+}
+
+// Append writes a record, redacting secrets from its arguments
+func (l *Log) Append(rec Record) error {
+	if l == nil {
+		return nil
+	}
+	rec.Args = Redact(rec.Args)
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	data = append(data, '\n')
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	if info, err := os.Stat(l.path); err == nil && info.Size()+int64(len(data)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// rotate moves the current file aside with a timestamp suffix and removes
+// the oldest rotated files beyond maxFiles
+func (l *Log) rotate() error {
+	rotated := l.path + "." + time.Now().UTC().Format("20060102-150405.000000000")
+	// Another process may have rotated the file first
+	if err := os.Rename(l.path, rotated); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	files, err := rotatedFiles(l.path)
+	if err != nil {
+		return err
+	}
+	for len(files) > l.maxFiles {
+		_ = os.Remove(files[0])
+		files = files[1:]
+	}
+	return nil
+}
+
+// rotatedFiles returns the rotated files of the log at path, oldest first
+func rotatedFiles(path string) ([]string, error) {
+	files, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Filter selects audit records; zero fields match everything
+type Filter struct {
+	Actor   string
+	Action  string
+	Outcome string
+	RunID   string
+	Since   time.Time
+	Until   time.Time
+}
+
+// Matches reports whether rec satisfies every set field of f
+func (f Filter) Matches(rec Record) bool {
+	switch {
+	case f.Actor != "" && rec.Actor != f.Actor,
+		f.Action != "" && rec.Action != f.Action,
+		f.Outcome != "" && rec.Outcome != f.Outcome,
+		f.RunID != "" && rec.RunID != f.RunID,
+		!f.Since.IsZero() && rec.StartedAt.Before(f.Since),
+		!f.Until.IsZero() && rec.StartedAt.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// Query reads the records matching f from the log at path and its rotated
+// files, oldest first. Malformed lines are skipped.
+func Query(path string, f Filter) ([]Record, error) {
+	files, err := rotatedFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, path)
+
+	records := make([]Record, 0)
+	for _, file := range files {
+		recs, err := readFile(file, f)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, recs...)
+	}
+	return records, nil
+}
+
+func readFile(path string, f Filter) ([]Record, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		if f.Matches(rec) {
+			records = append(records, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// redacted replaces the values of secret arguments
+const redacted = "[REDACTED]"
+
+// secretKeys are substrings of argument names whose values are redacted
+var secretKeys = []string{"secret", "token", "password", "passwd", "apikey", "api_key", "authorization", "credential", "private_key"}
+
+// Redact returns a copy of args with the values of secret-looking keys
+// replaced, including in nested objects
+func Redact(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if isSecretKey(k) {
+			out[k] = redacted
+			continue
+		}
+		out[k] = redactValue(v)
+	}
+	return out
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return Redact(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = redactValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalUser names the user running the process, the actor of CLI runs
+func LocalUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
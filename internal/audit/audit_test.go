@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/config"
+)
+
+func TestAppendAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := New(config.AuditConfig{Path: path})
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{StartedAt: start, Source: SourceMCP, Actor: "alice", Action: "run_pipeline", RunID: "r1", Outcome: OutcomeOK},
+		{StartedAt: start.Add(time.Hour), Source: SourceMCP, Actor: "bob", Action: "stop_agents", Outcome: OutcomeDenied},
+		{StartedAt: start.Add(2 * time.Hour), Source: SourceCLI, Actor: "alice", Action: "run", RunID: "r2", Outcome: OutcomeError, Error: "boom"},
+	}
+	for _, rec := range records {
+		if err := log.Append(rec); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string // run IDs or actions
+	}{
+		{"all", Filter{}, []string{"run_pipeline", "stop_agents", "run"}},
+		{"actor", Filter{Actor: "alice"}, []string{"run_pipeline", "run"}},
+		{"outcome", Filter{Outcome: OutcomeDenied}, []string{"stop_agents"}},
+		{"run", Filter{RunID: "r2"}, []string{"run"}},
+		{"since", Filter{Since: start.Add(30 * time.Minute)}, []string{"stop_agents", "run"}},
+		{"until", Filter{Until: start.Add(30 * time.Minute)}, []string{"run_pipeline"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Query(path, tt.filter)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Query() returned %d records, want %d", len(got), len(tt.want))
+			}
+			for i, rec := range got {
+				if rec.Action != tt.want[i] {
+					t.Errorf("record %d action = %q, want %q", i, rec.Action, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := New(config.AuditConfig{Path: path, MaxFiles: 2})
+	log.maxSize = 200 // bytes, so every other record rotates
+
+	for i := 0; i < 10; i++ {
+		if err := log.Append(Record{Actor: "alice", Action: "get_run", Outcome: OutcomeOK}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	files, err := rotatedFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("kept %d rotated files, want 2", len(files))
+	}
+
+	// Older records are dropped with their files, newer ones stay readable
+	got, err := Query(path, Filter{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(got) == 0 || len(got) >= 10 {
+		t.Errorf("Query() returned %d records, want some but not all", len(got))
+	}
+}
+
+func TestDisabledLogDiscards(t *testing.T) {
+	log := New(config.AuditConfig{Disabled: true})
+	if log != nil {
+		t.Fatal("New() should return nil when disabled")
+	}
+	if err := log.Append(Record{Action: "run"}); err != nil {
+		t.Errorf("Append() on nil log error = %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	args := map[string]any{
+		"prd_path":  "/specs/prd.md",
+		"api_token": "abc",
+		"stack": map[string]any{
+			"database":      "postgres",
+			"db-password":   "hunter2",
+			"webhook_creds": []any{map[string]any{"client_secret": "s"}},
+		},
+	}
+	got := Redact(args)
+
+	if got["prd_path"] != "/specs/prd.md" {
+		t.Errorf("prd_path = %v, want unchanged", got["prd_path"])
+	}
+	if got["api_token"] != redacted {
+		t.Errorf("api_token = %v, want redacted", got["api_token"])
+	}
+	stack := got["stack"].(map[string]any)
+	if stack["database"] != "postgres" || stack["db-password"] != redacted {
+		t.Errorf("stack = %v, want only the password redacted", stack)
+	}
+	creds := stack["webhook_creds"].([]any)[0].(map[string]any)
+	if creds["client_secret"] != redacted {
+		t.Errorf("nested client_secret = %v, want redacted", creds["client_secret"])
+	}
+	if args["api_token"] != "abc" {
+		t.Error("Redact() must not modify its input")
+	}
+}
@@ -45,34 +45,67 @@ Examples:
 `)
 }
 
+// agentEntry is the JSON representation of an agent definition
+type agentEntry struct {
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind"`
+	Output      string   `json:"output"`
+	DependsOn   []string `json:"depends_on"`
+	Description string   `json:"description"`
+}
+
 func agentsListMain(args []string) error {
 	fs := flag.NewFlagSet("agents list", flag.ContinueOnError)
-	var configPath string
+	var configPath, outputFormat string
 	fs.StringVar(&configPath, "c", "", "config file path")
 	fs.StringVar(&configPath, "config", "", "config file path")
+	addOutputFlag(fs, &outputFormat)
 	parseGlobalFlags(fs)
 
 	fs.Usage = func() {
 		fmt.Print(`Usage: pagent agents list [flags]
 
-List all available agent types with their output files.
+List all available agents with their kind, output files and description.
 
 Flags:
   -c, -config string    Config file path
+  -output string        Output format: text, json (default: text)
 `)
 	}
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
+	}
 
 	cfg, err := config.Load(configPath)
 	if err != nil {
 		cfg = config.Default()
 	}
 
+	if outputFormat == outputJSON {
+		entries := make([]agentEntry, 0, len(cfg.Agents))
+		for _, name := range cfg.GetAgentNames() {
+			agentCfg := cfg.Agents[name]
+			deps := agentCfg.DependsOn
+			if deps == nil {
+				deps = []string{}
+			}
+			entries = append(entries, agentEntry{
+				Name:        name,
+				Kind:        cfg.AgentKind(name),
+				Output:      agentCfg.Output,
+				DependsOn:   deps,
+				Description: agentCfg.Description,
+			})
+		}
+		return printJSON(map[string]interface{}{"agents": entries})
+	}
+
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "AGENT\tOUTPUT\tDEPENDS ON")
+	_, _ = fmt.Fprintln(w, "AGENT\tKIND\tOUTPUT\tDEPENDS ON\tDESCRIPTION")
 
 	for _, name := range cfg.GetAgentNames() {
 		agentCfg := cfg.Agents[name]
@@ -80,7 +113,7 @@ Flags:
 		if len(agentCfg.DependsOn) > 0 {
 			deps = fmt.Sprintf("%v", agentCfg.DependsOn)
 		}
-		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", name, agentCfg.Output, deps)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, cfg.AgentKind(name), agentCfg.Output, deps, agentCfg.Description)
 	}
 
 	_ = w.Flush()
@@ -133,7 +166,12 @@ Examples:
 	}
 
 	fmt.Printf("Agent: %s\n", agentName)
+	fmt.Printf("Kind: %s\n", cfg.AgentKind(agentName))
+	if agentCfg.Description != "" {
+		fmt.Printf("Description: %s\n", agentCfg.Description)
+	}
 	fmt.Printf("Output: %s\n", agentCfg.Output)
+	fmt.Printf("Output root: %s\n", cfg.AgentOutputRoot(agentName))
 	if len(agentCfg.DependsOn) > 0 {
 		fmt.Printf("Depends on: %v\n", agentCfg.DependsOn)
 	}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/audit"
+	"github.com/tuannvm/pagent/internal/config"
+)
+
+func auditMain(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	var (
+		configPath   string
+		file         string
+		outputFormat string
+		since        string
+		until        string
+		limit        int
+		filter       audit.Filter
+	)
+	fs.StringVar(&configPath, "c", "", "config file path")
+	fs.StringVar(&configPath, "config", "", "config file path")
+	fs.StringVar(&file, "file", "", "audit log file (default: audit.path from config)")
+	fs.StringVar(&filter.Actor, "actor", "", "only records of this actor (OAuth subject or local user)")
+	fs.StringVar(&filter.Action, "action", "", "only records of this tool or command")
+	fs.StringVar(&filter.Outcome, "outcome", "", "only records with this outcome: ok, error, denied")
+	fs.StringVar(&filter.RunID, "run", "", "only records of this run ID")
+	fs.StringVar(&since, "since", "", "only records started after a time or age (e.g. 24h, 2025-01-01)")
+	fs.StringVar(&until, "until", "", "only records started before a time or age")
+	fs.IntVar(&limit, "n", 50, "number of most recent records to show (0=all)")
+	addOutputFlag(fs, &outputFormat)
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent audit [flags]
+
+Query the audit log of MCP tool calls and runs.
+
+Flags:
+  -c, -config string    Config file path
+  -file string          Audit log file (default: audit.path from config)
+  -actor string         Only records of this actor (OAuth subject or local user)
+  -action string        Only records of this tool or command (e.g. run_pipeline, run)
+  -outcome string       Only records with this outcome: ok, error, denied
+  -run string           Only records of this run ID
+  -since string         Only records started after a time or age (e.g. 24h, 2025-01-01)
+  -until string         Only records started before a time or age
+  -n int                Number of most recent records to show, 0=all (default: 50)
+  -output string        Output format: text, json (default: text)
+
+Examples:
+  pagent audit
+  pagent audit -outcome denied -since 24h
+  pagent audit -actor alice@example.com -output json
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
+	}
+	switch filter.Outcome {
+	case "", audit.OutcomeOK, audit.OutcomeError, audit.OutcomeDenied:
+	default:
+		return fmt.Errorf("invalid outcome %q (use: ok, error, denied)", filter.Outcome)
+	}
+
+	var err error
+	if filter.Since, err = parseTimeFlag("since", since); err != nil {
+		return err
+	}
+	if filter.Until, err = parseTimeFlag("until", until); err != nil {
+		return err
+	}
+
+	if file == "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			cfg = config.Default()
+		}
+		file = cfg.Audit.EffectivePath()
+	}
+
+	records, err := audit.Query(file, filter)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+
+	if outputFormat == outputJSON {
+		return printJSON(map[string]interface{}{"records": records})
+	}
+
+	if len(records) == 0 {
+		logInfo("No audit records in %s", file)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STARTED\tSOURCE\tACTOR\tACTION\tRUN\tPATHS\tOUTCOME")
+	for _, rec := range records {
+		runID := rec.RunID
+		if runID == "" {
+			runID = "-"
+		}
+		paths := strings.Join(rec.Paths, ",")
+		if paths == "" {
+			paths = "-"
+		}
+		outcome := rec.Outcome
+		if rec.Error != "" {
+			outcome += ": " + firstLine(rec.Error)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			rec.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			rec.Source,
+			rec.Actor,
+			rec.Action,
+			runID,
+			paths,
+			outcome,
+		)
+	}
+
+	_ = w.Flush()
+	return nil
+}
+
+// parseTimeFlag parses an absolute time (RFC 3339 or YYYY-MM-DD) or an age
+// such as 24h, counted back from now
+func parseTimeFlag(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid -%s %q (use a duration like 24h, or YYYY-MM-DD)", name, value)
+}
+
+// firstLine returns the first line of a possibly multi-line error
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -5,6 +5,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+
+	"github.com/tuannvm/pagent/internal/telemetry"
 )
 
 var (
@@ -16,6 +18,7 @@ var (
 // SetVersion sets the version string
 func SetVersion(v string) {
 	version = v
+	telemetry.Version = v
 }
 
 // Execute runs the CLI
@@ -47,8 +50,24 @@ func Execute() error {
 		return stopMain(os.Args[2:])
 	case "agents":
 		return agentsMain(os.Args[2:])
+	case "presets":
+		return presetsMain(os.Args[2:])
+	case "config":
+		return configMain(os.Args[2:])
+	case "history":
+		return historyMain(os.Args[2:])
 	case "mcp":
 		return mcpMain(os.Args[2:])
+	case "daemon":
+		return daemonMain(os.Args[2:])
+	case "runs":
+		return runsMain(os.Args[2:])
+	case "submit":
+		return submitMain(os.Args[2:])
+	case "jobs":
+		return jobsMain(os.Args[2:])
+	case "audit":
+		return auditMain(os.Args[2:])
 	case "version", "-v", "--version":
 		fmt.Printf("pagent version %s\n", version)
 		return nil
@@ -77,7 +96,15 @@ Commands:
   message <agent>   Send a message to an agent
   stop [agent]      Stop running agents
   agents            Manage agent definitions
+  presets           List preset profiles
+  config            Show the effective configuration
+  history           Show previous runs
   mcp               Run as MCP server
+  daemon            Run the long-lived orchestrator
+  runs              List and control daemon runs
+  submit <input>    Queue a run for the daemon or MCP server
+  jobs              List and cancel queued jobs
+  audit             Query the audit log of MCP calls and runs
   version           Print version information
   help              Show this help
 
@@ -88,6 +115,7 @@ Examples:
   pagent run ./prd.md -a architect,qa -s
   pagent init
   pagent status
+  pagent status -output json
   pagent mcp --transport http --port 8080
 
 Run 'pagent <command> -h' for command-specific help.
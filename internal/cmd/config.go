@@ -0,0 +1,367 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func configMain(args []string) error {
+	if len(args) == 0 {
+		printConfigUsage()
+		return nil
+	}
+
+	subcmd := args[0]
+	switch subcmd {
+	case "show":
+		return configShowMain(args[1:])
+	case "validate":
+		return configValidateMain(args[1:])
+	case "schema":
+		return configSchemaMain(args[1:])
+	case "env":
+		return configEnvMain(args[1:])
+	case "-h", "-help", "help":
+		printConfigUsage()
+		return nil
+	default:
+		printConfigUsage()
+		return fmt.Errorf("unknown config subcommand: %s", subcmd)
+	}
+}
+
+func printConfigUsage() {
+	fmt.Print(`Usage: pagent config <command>
+
+Inspect and check the effective configuration. Settings are layered,
+later layers overriding earlier ones field by field:
+
+  built-in defaults < preset < ~/.pagent/config.yaml
+    < .pagent/config.yaml (or -config) < PAGENT_* environment < flags
+
+Commands:
+  show        Show the effective configuration
+  validate    Check the configuration for errors
+  schema      Print the JSON Schema of the config file
+  env         List the PAGENT_* variables that override settings
+
+Examples:
+  pagent config show
+  pagent config show -origin
+  pagent config validate
+  pagent config schema -o .pagent/config.schema.json
+`)
+}
+
+func configShowMain(args []string) error {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	var (
+		configPath   string
+		preset       string
+		showOrigin   bool
+		showSecrets  bool
+		outputFormat string
+	)
+	fs.StringVar(&configPath, "c", "", "config file path")
+	fs.StringVar(&configPath, "config", "", "config file path")
+	fs.StringVar(&preset, "preset", "", "preset profile to layer the config on")
+	fs.BoolVar(&showOrigin, "origin", false, "show where each value came from")
+	fs.BoolVar(&showSecrets, "show-secrets", false, "show webhook secrets and headers and MCP server env and headers")
+	addOutputFlag(fs, &outputFormat)
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent config show [flags]
+
+Show the effective configuration after merging all layers. In modify mode
+this includes stack settings detected in target_codebase. Webhook secrets
+and headers and the env and headers of MCP servers are redacted unless
+-show-secrets is given.
+
+Flags:
+  -c, -config string    Config file path (default: .pagent/config.yaml)
+  -preset string        Preset profile to layer the config on
+  -origin               Show where each value came from: default,
+                        preset:<name>, a config file, env:<VAR>, flag
+                        or detected:<file>
+  -show-secrets         Show secret values instead of <redacted>
+  -output string        Output format: text, json (default: text)
+
+Examples:
+  pagent config show
+  pagent config show -origin
+  pagent config show -preset go-api -origin -output json
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadWithPreset(configPath, preset)
+	if err != nil {
+		for _, p := range configProblems(err) {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", p)
+		}
+		return err
+	}
+	if _, err := cfg.ApplyTargetStack(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return err
+	}
+	if !showSecrets {
+		cfg = cfg.Redacted()
+	}
+
+	if outputFormat == outputJSON {
+		settings, err := cfg.Settings()
+		if err != nil {
+			return err
+		}
+		return printJSON(map[string]interface{}{"settings": settings})
+	}
+
+	if !showOrigin {
+		data, err := yaml.Marshal(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+
+	settings, err := cfg.Settings()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tVALUE\tORIGIN")
+	for _, s := range settings {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, s.Origin)
+	}
+
+	_ = w.Flush()
+	return nil
+}
+
+// configProblems returns each problem of a config load error
+func configProblems(err error) []string {
+	var verr *config.ValidationError
+	if errors.As(err, &verr) {
+		return verr.Problems
+	}
+	return []string{err.Error()}
+}
+
+func configValidateMain(args []string) error {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	var (
+		configPath   string
+		preset       string
+		strict       bool
+		outputFormat string
+	)
+	fs.StringVar(&configPath, "c", "", "config file path")
+	fs.StringVar(&configPath, "config", "", "config file path")
+	fs.StringVar(&preset, "preset", "", "preset profile to layer the config on")
+	fs.BoolVar(&strict, "strict", false, "treat warnings as errors")
+	addOutputFlag(fs, &outputFormat)
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent config validate [flags]
+
+Check every config layer for unknown keys and invalid values, then check
+the merged config: persona, mode, preference values, prompt files,
+target_codebase, agent dependencies and integrations. The same checks run
+before every 'pagent run'.
+
+Stack values outside the documented ones are reported as warnings, since
+agents receive them as written. In modify mode, settings that contradict
+what target_codebase uses (e.g. database: postgres when it depends on a
+MongoDB driver) are reported as warnings too.
+
+Flags:
+  -c, -config string    Config file path (default: .pagent/config.yaml)
+  -preset string        Preset profile to layer the config on
+  -strict               Treat warnings as errors
+  -output string        Output format: text, json (default: text)
+
+Examples:
+  pagent config validate
+  pagent config validate -c ./my-config.yaml -strict
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
+	}
+
+	problems, warnings := []string{}, []string{}
+	cfg, err := config.LoadWithPreset(configPath, preset)
+	if err != nil {
+		problems = configProblems(err)
+	} else {
+		disagreements, err := cfg.ApplyTargetStack()
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		for _, d := range disagreements {
+			warnings = append(warnings, d.String())
+		}
+	}
+	if strict {
+		problems = append(problems, warnings...)
+		warnings = []string{}
+	}
+
+	if outputFormat == outputJSON {
+		if err := printJSON(map[string]interface{}{
+			"valid":    len(problems) == 0,
+			"errors":   problems,
+			"warnings": warnings,
+		}); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range problems {
+			fmt.Printf("✗ %s\n", p)
+		}
+		for _, w := range warnings {
+			fmt.Printf("Warning: %s\n", w)
+		}
+		if len(problems) == 0 {
+			fmt.Println("✓ Config is valid")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("config has %d problem(s)", len(problems))
+	}
+	return nil
+}
+
+func configSchemaMain(args []string) error {
+	fs := flag.NewFlagSet("config schema", flag.ContinueOnError)
+	var outputFile string
+	fs.StringVar(&outputFile, "o", "", "write the schema to a file")
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent config schema [flags]
+
+Print the JSON Schema of .pagent/config.yaml, with the allowed values,
+descriptions and defaults of every setting. Editors using the YAML
+language server pick it up from a modeline at the top of the config:
+
+  # yaml-language-server: $schema=./config.schema.json
+
+'pagent init' writes the schema and the modeline for you.
+
+Flags:
+  -o string             Write the schema to a file instead of stdout
+
+Examples:
+  pagent config schema
+  pagent config schema -o .pagent/config.schema.json
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := config.SchemaJSON()
+	if err != nil {
+		return fmt.Errorf("failed to generate schema: %w", err)
+	}
+	if outputFile == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	logInfo("Wrote %s", outputFile)
+	return nil
+}
+
+func configEnvMain(args []string) error {
+	fs := flag.NewFlagSet("config env", flag.ContinueOnError)
+	var (
+		configPath   string
+		preset       string
+		outputFormat string
+	)
+	fs.StringVar(&configPath, "c", "", "config file path")
+	fs.StringVar(&configPath, "config", "", "config file path")
+	fs.StringVar(&preset, "preset", "", "preset profile to layer the config on")
+	addOutputFlag(fs, &outputFormat)
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent config env [flags]
+
+List the environment variables that override config settings. A setting's
+variable is PAGENT_ followed by its key in upper case, with dots and dashes
+as underscores:
+
+  stack.database         PAGENT_STACK_DATABASE
+  agents.qa.depends_on   PAGENT_AGENTS_QA_DEPENDS_ON
+
+Lists are comma-separated. Empty variables are ignored, and values that do
+not convert to the setting's type are reported as config errors. Agent
+settings are listed for the agents of the loaded config.
+
+Flags:
+  -c, -config string    Config file path (default: .pagent/config.yaml)
+  -preset string        Preset profile to layer the config on
+  -output string        Output format: text, json (default: text)
+
+Examples:
+  pagent config env
+  PAGENT_STACK_DATABASE=mongodb pagent config show -origin
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadWithPreset(configPath, preset)
+	if err != nil {
+		for _, p := range configProblems(err) {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", p)
+		}
+		return err
+	}
+	vars := cfg.EnvVars()
+
+	if outputFormat == outputJSON {
+		return printJSON(map[string]interface{}{"variables": vars})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VARIABLE\tKEY\tTYPE")
+	for _, v := range vars {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", v.Name, v.Key, v.Type)
+	}
+
+	_ = w.Flush()
+	return nil
+}
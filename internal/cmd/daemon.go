@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/agent"
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/daemon"
+	"github.com/tuannvm/pagent/internal/events"
+	"github.com/tuannvm/pagent/internal/queue"
+	"github.com/tuannvm/pagent/internal/runner"
+)
+
+func daemonMain(args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	var (
+		socketPath string
+		configPath string
+		resolve    string
+	)
+	fs.StringVar(&socketPath, "socket", daemon.DefaultSocketPath(), "unix socket path")
+	fs.StringVar(&configPath, "config", "", "config file path (selects the job queue)")
+	fs.StringVar(&resolve, "resolve", "", "stack conflict strategy for runs that don't set one: prefer-prd, prefer-config, fail")
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Printf(`Usage: pagent daemon [flags]
+
+Run a long-lived orchestrator that owns agent runs.
+
+While the daemon is running, 'pagent run -daemon' submits runs to it and
+'status', 'logs', 'message' and 'stop' query it instead of the state file.
+Use 'pagent runs' to list, pause, resume or cancel runs and restart agents.
+
+The daemon also runs jobs queued with 'pagent submit', up to
+queue.concurrency at a time; running jobs appear in 'pagent runs'.
+
+Relative paths in configs are resolved against the daemon's working
+directory, so start it from the project root.
+
+Flags:
+  -socket string    Unix socket path (default: $%s or %s)
+  -config string    Config file path (selects the job queue)
+  -resolve string   Settle PRD-vs-config stack conflicts in runs that don't
+                    choose: prefer-prd, prefer-config, or fail to stop them
+                    (default: warn, agents follow the PRD)
+
+Examples:
+  pagent daemon &
+  pagent daemon -resolve fail &
+  pagent run ./prd.md -daemon
+  pagent runs
+`, daemon.SocketEnv, daemon.DefaultSocketPath())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := (config.RunOptions{Resolve: resolve}).Validate(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	q, cfg, err := openQueue(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to open job queue: %w", err)
+	}
+	srv := daemon.NewServer(version).WithResolve(resolve).WithAudit(cfg.Audit)
+
+	// Queued jobs run as daemon runs; on shutdown they are requeued
+	queueDone := make(chan struct{})
+	go func() {
+		defer close(queueDone)
+		err := q.Serve(ctx, cfg.Queue.EffectiveConcurrency(), srv.ExecuteJob)
+		switch {
+		case errors.Is(err, queue.ErrWorkerActive):
+			log.Printf("Job queue %s is served by another process; not running queued jobs", q.Dir())
+		case err != nil:
+			log.Printf("Job queue worker stopped: %v", err)
+		}
+	}()
+
+	log.Printf("pagent daemon listening on %s (job queue: %s)", socketPath, q.Dir())
+	err = srv.ListenAndServe(ctx, socketPath)
+	stop()
+	<-queueDone
+	if err != nil {
+		return err
+	}
+	log.Println("Daemon shutdown complete")
+	return nil
+}
+
+// daemonClient returns a client for a running daemon, or nil if none is reachable
+func daemonClient() *daemon.Client {
+	client := daemon.NewClient(daemon.DefaultSocketPath())
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		return nil
+	}
+	return client
+}
+
+// submitToDaemon runs opts in the daemon. With detach it prints the run ID
+// and returns; otherwise it follows the run's events until it finishes.
+// Ctrl-C stops following but leaves the run going.
+func submitToDaemon(opts config.RunOptions, detach bool, logger *runner.StdLogger) error {
+	if opts.EventFormat != "" && opts.EventFormat != config.EventFormatNDJSON {
+		return fmt.Errorf("unknown event format %q (supported: %s)", opts.EventFormat, config.EventFormatNDJSON)
+	}
+	client := daemon.NewClient(daemon.DefaultSocketPath())
+
+	opts, err := absRunOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	info, err := client.Submit(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+
+	if detach {
+		// Print only the run ID so scripts can capture it
+		fmt.Println(info.ID)
+		return nil
+	}
+	logger.Info("Submitted run %s to daemon (Ctrl-C stops following; the run continues)", info.ID)
+
+	handler := runner.LogSink(logger)
+	if opts.EventFormat == config.EventFormatNDJSON {
+		handler = events.NewNDJSONWriter(os.Stdout).Handle
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := client.Events(ctx, info.ID, handler); err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Info("\nStopped following run %s (see 'pagent runs show %s')", info.ID, info.ID)
+			return nil
+		}
+		return err
+	}
+
+	final, err := client.Run(context.Background(), info.ID)
+	if err != nil {
+		return err
+	}
+	if final.State != daemon.RunSucceeded {
+		return fmt.Errorf("run %s %s: %s", final.ID, final.State, final.Error)
+	}
+	return nil
+}
+
+// absRunOptions resolves paths against the current directory, since the
+// daemon runs with its own working directory. A project config in the
+// current directory is passed explicitly for the same reason.
+func absRunOptions(opts config.RunOptions) (config.RunOptions, error) {
+	var err error
+	if opts.OutputDir == "" {
+		// Resolve the configured output directory here rather than in the
+		// working directory of the process running the job
+		cfg, err := config.LoadWithPreset(opts.ConfigPath, opts.Preset)
+		if err != nil {
+			return opts, err
+		}
+		opts.OutputDir = cfg.OutputDir
+	}
+	for _, p := range []*string{&opts.InputPath, &opts.OutputDir, &opts.ConfigPath, &opts.TargetCodebase, &opts.SpecsOutputDir} {
+		if *p == "" {
+			continue
+		}
+		if *p, err = filepath.Abs(*p); err != nil {
+			return opts, err
+		}
+	}
+
+	if opts.ConfigPath == "" {
+		for _, loc := range []string{".pagent/config.yaml", ".pagent/config.yml"} {
+			if _, err := os.Stat(loc); err == nil {
+				if opts.ConfigPath, err = filepath.Abs(loc); err != nil {
+					return opts, err
+				}
+				break
+			}
+		}
+	}
+	return opts, nil
+}
+
+// runningAgent is an agent with a live agentapi process
+type runningAgent struct {
+	Name  string
+	Port  int
+	RunID string // Run the agent belongs to
+}
+
+// findRunningAgents returns running agents sorted by name, from the daemon
+// when one is reachable and from the state file otherwise
+func findRunningAgents() ([]runningAgent, error) {
+	var agents []runningAgent
+
+	if client := daemonClient(); client != nil {
+		infos, err := client.Agents(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range infos {
+			agents = append(agents, runningAgent{Name: a.Name, Port: a.Port, RunID: a.RunID})
+		}
+	} else {
+		state, err := agent.LoadState()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read state: %w", err)
+		}
+		for _, a := range state.Agents() {
+			agents = append(agents, runningAgent{Name: a.Name, Port: a.Port, RunID: a.RunID})
+		}
+	}
+
+	sort.SliceStable(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	return agents, nil
+}
+
+// findRunningAgent looks up a running agent by name. If several runs have
+// an agent with that name, the most recent run wins.
+func findRunningAgent(name string) (runningAgent, error) {
+	agents, err := findRunningAgents()
+	if err != nil {
+		return runningAgent{}, err
+	}
+	if len(agents) == 0 {
+		return runningAgent{}, fmt.Errorf("no agents running - start with 'pagent run'")
+	}
+	for _, a := range agents {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return runningAgent{}, fmt.Errorf("agent '%s' not found in running agents", name)
+}
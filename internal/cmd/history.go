@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/state"
+)
+
+func historyMain(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	var (
+		outputDir    string
+		configPath   string
+		outputFormat string
+		limit        int
+	)
+	fs.StringVar(&outputDir, "o", "", "output directory of the runs (default: from config)")
+	fs.StringVar(&outputDir, "output-dir", "", "output directory of the runs (default: from config)")
+	fs.StringVar(&configPath, "c", "", "config file path")
+	fs.StringVar(&configPath, "config", "", "config file path")
+	fs.IntVar(&limit, "n", 20, "number of most recent runs to show (0=all)")
+	addOutputFlag(fs, &outputFormat)
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent history [flags]
+
+Show previous runs recorded in the output directory.
+
+Flags:
+  -o, -output-dir string    Output directory of the runs (default: from config)
+  -c, -config string        Config file path
+  -n int                    Number of most recent runs to show, 0=all (default: 20)
+  -output string            Output format: text, json (default: text)
+
+Examples:
+  pagent history
+  pagent history -n 5 -output json
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
+	}
+
+	if outputDir == "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			cfg = config.Default()
+		}
+		outputDir = cfg.OutputDir
+	}
+
+	records, err := state.LoadHistory(outputDir)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+
+	if outputFormat == outputJSON {
+		return printJSON(map[string]interface{}{"runs": records})
+	}
+
+	if len(records) == 0 {
+		logInfo("No runs recorded in %s", outputDir)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RUN\tSTARTED\tDURATION\tAGENTS\tRESULT")
+	for _, rec := range records {
+		result := "ok"
+		if !rec.Success {
+			result = "failed"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			rec.ID,
+			rec.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			rec.FinishedAt.Sub(rec.StartedAt).Round(time.Second),
+			len(rec.Agents),
+			result,
+		)
+	}
+
+	_ = w.Flush()
+	return nil
+}
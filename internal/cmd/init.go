@@ -5,22 +5,69 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/detect"
+	"github.com/tuannvm/pagent/internal/tui"
+	"golang.org/x/term"
 	"gopkg.in/yaml.v3"
 )
 
 func initMain(args []string) error {
 	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	var (
+		yes         bool
+		accessible  bool
+		mode        string
+		target      string
+		persona     string
+		language    string
+		stack       string
+		preferences string
+	)
+	fs.BoolVar(&yes, "y", false, "accept the answers without prompting")
+	fs.BoolVar(&yes, "yes", false, "accept the answers without prompting")
+	fs.BoolVar(&accessible, "accessible", false, "enable accessible mode for screen readers")
+	fs.StringVar(&mode, "mode", "", "execution mode: create or modify")
+	fs.StringVar(&target, "target", "", "existing codebase to modify")
+	fs.StringVar(&persona, "persona", "", "implementation style: minimal, balanced, production or a persona from the config")
+	fs.StringVar(&language, "language", "", "primary programming language")
+	fs.StringVar(&stack, "stack", "", "stack settings as key=value,...")
+	fs.StringVar(&preferences, "preferences", "", "preferences as key=value,...")
 	parseGlobalFlags(fs)
 
 	fs.Usage = func() {
-		fmt.Print(`Usage: pagent init
+		fmt.Print(`Usage: pagent init [flags]
 
-Create a .pagent/config.yaml file in the current directory
-with default agent configurations.
+Create or update .pagent/config.yaml with an interactive wizard that asks
+for mode, persona, language, stack and preferences, and write
+.pagent/config.schema.json so editors can complete and check the settings.
 
-You can customize the prompts and settings after initialization.
+Answers are pre-filled from the existing config and from what is detected
+in the current directory (go.mod, package.json, Dockerfile, Terraform, CI
+workflows, ...). When a config exists, only the settings asked about are
+updated; the rest of the file is kept.
+
+With -yes, or when stdin is not a terminal, the pre-filled answers and the
+flags below are written without prompting.
+
+Flags:
+  -y, -yes              Accept the answers without prompting
+  -mode string          Execution mode: create, modify
+  -target string        Existing codebase to modify (modify mode)
+  -persona string       Implementation style: minimal, balanced, production or a persona from the config
+  -language string      Primary language: go, python, typescript, java, rust
+  -stack string         Stack settings as key=value,... (e.g. database=mongodb,cache=none)
+  -preferences string   Preferences as key=value,... (e.g. api_style=grpc,stateless=true)
+  -accessible           Enable accessible mode for screen readers
+
+Examples:
+  pagent init
+  pagent init -yes
+  pagent init -yes -persona production -stack database=mongodb
+  pagent init -yes -mode modify -target ./service
 `)
 	}
 
@@ -30,42 +77,181 @@ You can customize the prompts and settings after initialization.
 
 	configDir := ".pagent"
 	configFile := filepath.Join(configDir, "config.yaml")
+	if _, err := os.Stat(filepath.Join(configDir, "config.yml")); err == nil {
+		configFile = filepath.Join(configDir, "config.yml")
+	}
+	_, statErr := os.Stat(configFile)
+	exists := statErr == nil
 
-	// Check if already exists
-	if _, err := os.Stat(configFile); err == nil {
-		return fmt.Errorf("config file already exists: %s", configFile)
+	// Pre-fill from the effective config, then from the repo
+	loadPath := ""
+	if exists {
+		loadPath = configFile
+	}
+	cfg, err := config.Load(loadPath)
+	if err != nil {
+		for _, p := range configProblems(err) {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", p)
+		}
+		return err
+	}
+	var detected []string
+	result, err := detect.Detect(".")
+	if err == nil {
+		if _, err := cfg.ApplyDetected(result.Settings()); err != nil {
+			return fmt.Errorf("failed to apply detected settings: %w", err)
+		}
+		detected = describeDetected(result)
 	}
+	answers := config.InitOptionsFrom(cfg)
 
-	// Create directory
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+	// Source code in the directory suggests modifying it rather than starting over
+	if err == nil && result.Language != "" && !cfg.IsExplicit("mode") && answers.TargetCodebase == "" {
+		answers.Mode = config.ModeModify
+		answers.TargetCodebase = "."
+	}
+
+	// Flags override everything pre-filled
+	if mode != "" {
+		answers.Mode = mode
+	}
+	if target != "" {
+		answers.TargetCodebase = target
+	}
+	if persona != "" {
+		answers.Persona = persona
+	}
+	if language != "" {
+		answers.Preferences["language"] = language
+	}
+	stackValues, err := parseKeyValues(stack)
+	if err != nil {
+		return fmt.Errorf("invalid -stack: %w", err)
+	}
+	for k, v := range stackValues {
+		answers.Stack[k] = v
+	}
+	prefValues, err := parseKeyValues(preferences)
+	if err != nil {
+		return fmt.Errorf("invalid -preferences: %w", err)
+	}
+	for k, v := range prefValues {
+		if b, err := strconv.ParseBool(v); err == nil {
+			answers.Preferences[k] = b
+		} else {
+			answers.Preferences[k] = v
+		}
+	}
+
+	if !yes && term.IsTerminal(int(os.Stdin.Fd())) {
+		result, err := tui.RunInitWizard(tui.InitWizardOptions{
+			Initial:    answers,
+			Detected:   detected,
+			ConfigPath: configFile,
+			Exists:     exists,
+			Accessible: accessible,
+		})
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			logInfo("Cancelled")
+			return nil
+		}
+		answers = *result
 	}
 
-	// Get default config
-	cfg := config.Default()
+	if err := answers.Validate(); err != nil {
+		for _, p := range configProblems(err) {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", p)
+		}
+		return err
+	}
 
-	// Marshal to YAML
-	data, err := yaml.Marshal(cfg)
+	// Start from the existing file, or from the defaults for a new one
+	var data []byte
+	if exists {
+		if data, err = os.ReadFile(configFile); err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+	} else {
+		if data, err = yaml.Marshal(config.Default()); err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+	}
+	data, err = config.SetValues(data, answers.Values())
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return fmt.Errorf("failed to update config: %w", err)
 	}
 
-	// Add header comment
-	header := `# Pagent Configuration
+	// Add header comment; the modeline points editors at the schema
+	if !exists {
+		data = append([]byte(`# Pagent Configuration
 # Customize agent prompts and settings below
 # Documentation: https://github.com/tuannvm/pagent
 
-`
+`), data...)
+	}
+	if !strings.Contains(string(data), "yaml-language-server:") {
+		data = append([]byte("# yaml-language-server: $schema=./"+config.SchemaFile+"\n"), data...)
+	}
+
+	// Create directory
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Write the schema referenced by the modeline
+	schema, err := config.SchemaJSON()
+	if err != nil {
+		return fmt.Errorf("failed to generate schema: %w", err)
+	}
+	schemaFile := filepath.Join(configDir, config.SchemaFile)
+	if err := os.WriteFile(schemaFile, schema, 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
 
 	// Write file
-	if err := os.WriteFile(configFile, []byte(header+string(data)), 0644); err != nil {
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
-	logInfo("Created %s", configFile)
+	if exists {
+		logInfo("Updated %s", configFile)
+	} else {
+		logInfo("Created %s", configFile)
+	}
+	logInfo("Wrote %s (editor completion and validation)", schemaFile)
 	logInfo("")
 	logInfo("You can now customize agent prompts and run:")
 	logInfo("  pagent run ./prd.md")
 
 	return nil
 }
+
+// describeDetected describes each detected setting, e.g.
+// "stack.ci github-actions (.github/workflows/ci.yml)"
+func describeDetected(r *detect.Result) []string {
+	var descriptions []string
+	for _, s := range r.Settings() {
+		descriptions = append(descriptions, fmt.Sprintf("%s %s (%s)", s.Key, s.Value, s.Evidence))
+	}
+	return descriptions
+}
+
+// parseKeyValues parses "key=value,key=value"
+func parseKeyValues(s string) (map[string]string, error) {
+	values := map[string]string{}
+	if s == "" {
+		return values, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		values[key] = strings.TrimSpace(value)
+	}
+	return values, nil
+}
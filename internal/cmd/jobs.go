@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/queue"
+)
+
+func jobsMain(args []string) error {
+	fs := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	var (
+		configPath   string
+		outputFormat string
+	)
+	fs.StringVar(&configPath, "config", "", "config file path (selects the queue directory)")
+	addOutputFlag(fs, &outputFormat)
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent jobs [command] [flags] [args]
+
+List and cancel jobs in the persistent queue fed by 'pagent submit' and
+the MCP server.
+
+Commands:
+  list              List jobs (default)
+  show <job>        Show a job and its agent results
+  cancel <job>      Cancel a queued or running job
+
+Flags:
+  -config string    Config file path (selects the queue directory)
+  -output string    Output format: text, json (default: text)
+
+Examples:
+  pagent jobs
+  pagent jobs show 20250101-120000-1a2b3c
+  pagent jobs cancel 20250101-120000-1a2b3c
+`)
+	}
+
+	command := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
+	}
+
+	q, _, err := openQueue(configPath)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "list":
+		jobs, err := q.List()
+		if err != nil {
+			return err
+		}
+		return printJobs(jobs, outputFormat)
+	case "show", "cancel":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("jobs %s takes a job ID", command)
+		}
+		if command == "show" {
+			job, err := q.Get(fs.Arg(0))
+			if err != nil {
+				return err
+			}
+			return printJob(job, outputFormat)
+		}
+		job, err := q.Cancel(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if !job.Done() {
+			logInfo("Cancellation of job %s requested", job.ID)
+			return nil
+		}
+		return printJob(job, outputFormat)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown jobs command: %s", command)
+	}
+}
+
+// openQueue opens the job queue selected by the config at configPath
+// (or the default config locations) and returns that config
+func openQueue(configPath string) (*queue.Queue, *config.Config, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		if configPath != "" || !os.IsNotExist(err) {
+			return nil, nil, err
+		}
+		cfg = config.Default()
+	}
+	q, err := queue.Open(cfg.Queue.EffectiveDir())
+	return q, cfg, err
+}
+
+// printJobs renders a job list
+func printJobs(jobs []queue.Job, outputFormat string) error {
+	if outputFormat == outputJSON {
+		return printJSON(map[string]interface{}{"jobs": jobs})
+	}
+
+	if len(jobs) == 0 {
+		logInfo("No jobs in the queue")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "JOB\tSTATE\tPRIORITY\tSUBMITTED\tSUBMITTER\tINPUT")
+	for _, j := range jobs {
+		submitter := j.Submitter
+		if submitter == "" {
+			submitter = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n",
+			j.ID,
+			j.State,
+			j.Priority,
+			j.SubmittedAt.Local().Format("2006-01-02 15:04:05"),
+			submitter,
+			j.Options.InputPath,
+		)
+	}
+	return w.Flush()
+}
+
+// printJob renders a single job with its agent results
+func printJob(job queue.Job, outputFormat string) error {
+	if outputFormat == outputJSON {
+		return printJSON(job)
+	}
+
+	fmt.Printf("Job:       %s\n", job.ID)
+	fmt.Printf("State:     %s\n", job.State)
+	fmt.Printf("Priority:  %d\n", job.Priority)
+	fmt.Printf("Input:     %s\n", job.Options.InputPath)
+	fmt.Printf("Submitted: %s\n", job.SubmittedAt.Local().Format("2006-01-02 15:04:05"))
+	if job.Submitter != "" {
+		fmt.Printf("Submitter: %s\n", job.Submitter)
+	}
+	if job.StartedAt != nil && job.FinishedAt != nil {
+		fmt.Printf("Duration:  %s\n", job.FinishedAt.Sub(*job.StartedAt).Round(time.Second))
+	}
+	if job.Attempts > 1 {
+		fmt.Printf("Attempts:  %d\n", job.Attempts)
+	}
+	if job.Error != "" {
+		fmt.Printf("Error:     %s\n", job.Error)
+	}
+	if len(job.Agents) == 0 {
+		return nil
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "AGENT\tSTATUS\tDURATION\tDETAIL")
+	for _, a := range job.Agents {
+		detail := a.OutputPath
+		if a.Error != "" {
+			detail = a.Error
+		}
+		duration := (time.Duration(a.DurationMS) * time.Millisecond).Round(time.Second)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Name, a.Status, duration, detail)
+	}
+	return w.Flush()
+}
@@ -3,17 +3,17 @@ package cmd
 import (
 	"flag"
 	"fmt"
-	"os"
 
-	"github.com/tuannvm/pagent/internal/agent"
 	"github.com/tuannvm/pagent/internal/api"
 )
 
 func logsMain(args []string) error {
 	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
 	var followLogs bool
+	var outputFormat string
 	fs.BoolVar(&followLogs, "f", false, "follow log output (not implemented)")
 	fs.BoolVar(&followLogs, "follow", false, "follow log output (not implemented)")
+	addOutputFlag(fs, &outputFormat)
 	parseGlobalFlags(fs)
 
 	fs.Usage = func() {
@@ -25,7 +25,8 @@ Arguments:
   <agent>    Name of the agent
 
 Flags:
-  -f, -follow    Follow log output (not implemented)
+  -f, -follow       Follow log output (not implemented)
+  -output string    Output format: text, json (default: text)
 
 Examples:
   pagent logs design
@@ -36,6 +37,9 @@ Examples:
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
+	}
 
 	if fs.NArg() < 1 {
 		fs.Usage()
@@ -44,21 +48,13 @@ Examples:
 
 	agentName := fs.Arg(0)
 
-	// Read state file
-	state, err := agent.LoadState()
+	// Find the agent (daemon or state file)
+	running, err := findRunningAgent(agentName)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("no agents running - start with 'pagent run'")
-		}
-		return fmt.Errorf("failed to read state: %w", err)
-	}
-
-	port, ok := state[agentName]
-	if !ok {
-		return fmt.Errorf("agent '%s' not found in running agents", agentName)
+		return err
 	}
 
-	client := api.NewClient(port)
+	client := api.NewClient(running.Port)
 
 	// Get messages
 	messages, err := client.GetMessages()
@@ -66,6 +62,13 @@ Examples:
 		return fmt.Errorf("failed to get messages: %w", err)
 	}
 
+	if outputFormat == outputJSON {
+		if messages == nil {
+			messages = []api.ConversationMessage{}
+		}
+		return printJSON(map[string]interface{}{"agent": agentName, "messages": messages})
+	}
+
 	if len(messages) == 0 {
 		logInfo("No messages yet for agent %s", agentName)
 		return nil
@@ -1,12 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/tuannvm/pagent/internal/config"
 	pagentmcp "github.com/tuannvm/pagent/internal/mcp"
+	"github.com/tuannvm/pagent/internal/policy"
+	"github.com/tuannvm/pagent/internal/telemetry"
 )
 
 func mcpMain(args []string) error {
@@ -22,7 +26,10 @@ func mcpMain(args []string) error {
 		oauthServerURL string
 		sessionTimeout time.Duration
 		configPath     string
+		policyPath     string
+		resolve        string
 		mcpVerbose     bool
+		serveMetrics   bool
 	)
 
 	fs.StringVar(&transport, "transport", "stdio", "transport mode: stdio, http")
@@ -34,6 +41,9 @@ func mcpMain(args []string) error {
 	fs.StringVar(&oauthServerURL, "server-url", "", "OAuth server URL for callbacks (default: http://localhost:<port>)")
 	fs.DurationVar(&sessionTimeout, "session-timeout", 30*time.Minute, "HTTP session timeout")
 	fs.StringVar(&configPath, "config", "", "path to pagent config file")
+	fs.StringVar(&policyPath, "policy", "", "access policy file for OAuth callers (default: mcp.policy from config)")
+	fs.StringVar(&resolve, "resolve", "", "stack conflict strategy for runs that don't set resolve: prefer-prd, prefer-config, fail (default: warn)")
+	fs.BoolVar(&serveMetrics, "metrics", true, "serve Prometheus metrics at /metrics, without authentication (only with http transport)")
 	fs.BoolVar(&mcpVerbose, "v", false, "enable verbose logging")
 	fs.BoolVar(&mcpVerbose, "verbose", false, "enable verbose logging")
 
@@ -52,6 +62,8 @@ Examples:
   pagent mcp --transport http --oauth \
     --issuer https://company.okta.com \
     --audience api://pagent                     # HTTP with OAuth
+  pagent mcp --transport http --oauth ... \
+    --policy .pagent/policy.yaml                # HTTP with OAuth and RBAC
 
 Flags:
 `)
@@ -61,9 +73,27 @@ Flags:
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if err := (config.RunOptions{Resolve: resolve}).Validate(); err != nil {
+		return err
+	}
 
 	log.Println("Starting Pagent MCP Server...")
 
+	// Tracing and the policy are configured from the pagent config; a missing
+	// config leaves them off
+	pagentCfg, cfgErr := config.Load(configPath)
+	if cfgErr == nil {
+		shutdownTracing, err := telemetry.Setup(context.Background(), pagentCfg.Tracing)
+		if err != nil {
+			return fmt.Errorf("tracing setup failed: %w", err)
+		}
+		defer func() {
+			if err := shutdownTracing(context.Background()); err != nil {
+				log.Printf("Failed to flush traces: %v", err)
+			}
+		}()
+	}
+
 	// Create handlers with configuration
 	handlers := pagentmcp.NewHandlers()
 	if configPath != "" {
@@ -72,6 +102,27 @@ Flags:
 	if mcpVerbose {
 		handlers.WithVerbose(true)
 	}
+	handlers.WithResolve(resolve)
+	if policyPath == "" && cfgErr == nil {
+		policyPath = pagentCfg.MCP.EffectivePolicy()
+	}
+	if policyPath != "" {
+		p, err := policy.Load(policyPath)
+		if err != nil {
+			return err
+		}
+		handlers.WithPolicy(p)
+		if !enableOAuth {
+			log.Printf("Warning: access policy %s only applies to OAuth callers; use --oauth", policyPath)
+		}
+	}
+
+	// Runs requested through tools are executed from the persistent job queue
+	stopQueue, err := handlers.StartQueue(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to start job queue: %w", err)
+	}
+	defer stopQueue()
 
 	// Build server config
 	cfg := &pagentmcp.ServerConfig{
@@ -79,6 +130,7 @@ Flags:
 		Handlers:       handlers,
 		Port:           port,
 		SessionTimeout: sessionTimeout,
+		DisableMetrics: !serveMetrics,
 	}
 
 	if enableOAuth {
@@ -98,7 +150,6 @@ Flags:
 
 	// Run based on transport mode
 	log.Printf("Starting MCP server with %s transport...", transport)
-	var err error
 	switch transport {
 	case "stdio":
 		err = server.ServeStdio()
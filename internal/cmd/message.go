@@ -1,13 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
-	"github.com/tuannvm/pagent/internal/agent"
 	"github.com/tuannvm/pagent/internal/api"
 )
 
@@ -45,21 +44,13 @@ Examples:
 	agentName := fs.Arg(0)
 	message := strings.Join(fs.Args()[1:], " ")
 
-	// Read state file
-	state, err := agent.LoadState()
+	// Find the agent (daemon or state file)
+	running, err := findRunningAgent(agentName)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("no agents running - start with 'pagent run'")
-		}
-		return fmt.Errorf("failed to read state: %w", err)
-	}
-
-	port, ok := state[agentName]
-	if !ok {
-		return fmt.Errorf("agent '%s' not found in running agents", agentName)
+		return err
 	}
 
-	client := api.NewClient(port)
+	client := api.NewClient(running.Port)
 
 	// Check current status
 	status, err := client.GetStatus()
@@ -70,7 +61,7 @@ Examples:
 	if status.Status == "running" {
 		logInfo("Agent is currently running. Waiting for stable state...")
 
-		if err := client.WaitForStable(60 * time.Second); err != nil {
+		if err := client.WaitForStable(context.Background(), 60*time.Second); err != nil {
 			return fmt.Errorf("timeout waiting for agent: %w", err)
 		}
 	}
@@ -78,7 +69,7 @@ Examples:
 	// Send message
 	logInfo("Sending message to %s...", agentName)
 
-	if err := client.SendMessage(message, "user"); err != nil {
+	if err := client.SendMessage(context.Background(), message, "user"); err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
 
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Output format constants for commands that support --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// addOutputFlag registers the --output format flag on fs
+func addOutputFlag(fs *flag.FlagSet, format *string) {
+	fs.StringVar(format, "output", outputText, "output format: text, json")
+}
+
+// validateOutputFormat checks that format is a supported --output value
+func validateOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q (use: %s, %s)", format, outputText, outputJSON)
+	}
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/tuannvm/pagent/internal/config"
+)
+
+func presetsMain(args []string) error {
+	if len(args) == 0 {
+		printPresetsUsage()
+		return nil
+	}
+
+	subcmd := args[0]
+	switch subcmd {
+	case "list":
+		return presetsListMain(args[1:])
+	case "show":
+		return presetsShowMain(args[1:])
+	case "-h", "-help", "help":
+		printPresetsUsage()
+		return nil
+	default:
+		printPresetsUsage()
+		return fmt.Errorf("unknown presets subcommand: %s", subcmd)
+	}
+}
+
+func printPresetsUsage() {
+	fmt.Printf(`Usage: pagent presets <command>
+
+List preset profiles. A preset bundles persona, stack, preferences and
+optional agent overrides; select it with 'pagent run -preset <name>' or
+'preset: <name>' in the config, which is layered on top of it.
+
+User presets are read from %s/<name>.yaml
+and take precedence over built-in presets of the same name.
+
+Commands:
+  list    List available presets
+  show    Show the settings of a preset
+
+Examples:
+  pagent presets list
+  pagent presets show go-api
+`, config.PresetDir())
+}
+
+func presetsListMain(args []string) error {
+	fs := flag.NewFlagSet("presets list", flag.ContinueOnError)
+	var outputFormat string
+	addOutputFlag(fs, &outputFormat)
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent presets list [flags]
+
+List built-in and user presets.
+
+Flags:
+  -output string        Output format: text, json (default: text)
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
+	}
+
+	presets, err := config.ListPresets()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == outputJSON {
+		return printJSON(map[string]interface{}{"presets": presets})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PRESET\tSOURCE\tDESCRIPTION")
+	for _, p := range presets {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, p.Source, p.Description)
+	}
+
+	_ = w.Flush()
+	return nil
+}
+
+func presetsShowMain(args []string) error {
+	fs := flag.NewFlagSet("presets show", flag.ContinueOnError)
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent presets show <preset>
+
+Show the settings a preset applies.
+
+Arguments:
+  <preset>    Name of the preset
+
+Examples:
+  pagent presets show python-ml
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("missing required argument: preset name")
+	}
+
+	p, err := config.LoadPreset(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Preset: %s\n", p.Name)
+	if p.Path != "" {
+		fmt.Printf("Source: %s (%s)\n", p.Source, p.Path)
+	} else {
+		fmt.Printf("Source: %s\n", p.Source)
+	}
+	fmt.Println()
+	fmt.Print(p.YAML())
+
+	return nil
+}
@@ -4,45 +4,28 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/tuannvm/pagent/internal/config"
 	"github.com/tuannvm/pagent/internal/runner"
+	"github.com/tuannvm/pagent/internal/tui"
+	"golang.org/x/term"
 )
 
 func runMain(args []string) error {
 	fs := flag.NewFlagSet("run", flag.ContinueOnError)
 
-	// Initialize with defaults
-	opts := config.DefaultRunOptions(nil)
+	rf := addRunFlags(fs)
 
-	// Define flags
 	var (
-		agentsFlag  string
-		resumeMode  bool
-		forceMode   bool
-		stateless   bool
-		noStateless bool
+		useDaemon bool
+		detach    bool
 	)
-
-	fs.StringVar(&agentsFlag, "a", "", "comma-separated list of agents (default: all)")
-	fs.StringVar(&agentsFlag, "agents", "", "comma-separated list of agents (default: all)")
-	fs.StringVar(&opts.OutputDir, "o", opts.OutputDir, "output directory")
-	fs.StringVar(&opts.OutputDir, "output", opts.OutputDir, "output directory")
-	fs.BoolVar(&opts.Sequential, "s", false, "run agents in dependency order")
-	fs.BoolVar(&opts.Sequential, "sequential", false, "run agents in dependency order")
-	fs.StringVar(&opts.ConfigPath, "c", "", "config file path")
-	fs.StringVar(&opts.ConfigPath, "config", "", "config file path")
-	fs.IntVar(&opts.Timeout, "t", 0, "timeout per agent in seconds (0=infinite)")
-	fs.IntVar(&opts.Timeout, "timeout", 0, "timeout per agent in seconds (0=infinite)")
-	fs.BoolVar(&resumeMode, "r", false, "skip agents whose outputs are up-to-date")
-	fs.BoolVar(&resumeMode, "resume", false, "skip agents whose outputs are up-to-date")
-	fs.BoolVar(&forceMode, "f", false, "force regeneration, ignore existing outputs")
-	fs.BoolVar(&forceMode, "force", false, "force regeneration, ignore existing outputs")
-	fs.StringVar(&opts.Persona, "p", "", "implementation style: minimal, balanced, production")
-	fs.StringVar(&opts.Persona, "persona", "", "implementation style: minimal, balanced, production")
-	fs.BoolVar(&stateless, "stateless", false, "prefer stateless architecture")
-	fs.BoolVar(&noStateless, "no-stateless", false, "prefer traditional database-backed architecture")
+	fs.StringVar(&rf.opts.EventFormat, "events", "", "stream lifecycle events to stdout: ndjson")
+	fs.StringVar(&rf.opts.MetricsAddr, "metrics-addr", "", "serve Prometheus metrics at <addr>/metrics during the run")
+	fs.BoolVar(&useDaemon, "daemon", false, "submit the run to the pagent daemon")
+	fs.BoolVar(&detach, "detach", false, "with -daemon: print the run ID and return immediately")
 	parseGlobalFlags(fs)
 
 	fs.Usage = func() {
@@ -55,15 +38,25 @@ Arguments:
 
 Flags:
   -a, -agents string     Comma-separated list of agents (default: all)
-  -o, -output string     Output directory (default: ./outputs)
+  -o, -output string     Output directory (default: output_dir from config, ./outputs)
   -s, -sequential        Run agents in dependency order
   -c, -config string     Config file path
   -t, -timeout int       Timeout per agent in seconds (0=infinite)
   -r, -resume            Skip agents whose outputs are up-to-date
   -f, -force             Force regeneration, ignore existing outputs
-  -p, -persona string    Implementation style: minimal, balanced, production
+  -p, -persona string    Implementation style: minimal, balanced, production or a persona from the config
+  -preset string         Preset profile to layer the config on (e.g. go-api)
   -stateless             Prefer stateless architecture
   -no-stateless          Prefer traditional database-backed architecture
+  -resolve string        Resolve PRD-vs-config stack conflicts without asking:
+                         prefer-prd, prefer-config, or fail to stop the run
+                         (default: ask when interactive, else warn and let
+                         agents follow the PRD)
+  -events string         Stream lifecycle events to stdout: ndjson
+                         (human-readable output moves to stderr)
+  -metrics-addr string   Serve Prometheus metrics at <addr>/metrics (e.g. :9090)
+  -daemon                Submit the run to 'pagent daemon' and follow its events
+  -detach                With -daemon, print the run ID and return immediately
   -v, -verbose           Verbose output
   -q, -quiet             Quiet output (errors only)
 
@@ -71,7 +64,11 @@ Examples:
   pagent run ./prd.md
   pagent run ./prd.md -a architect,qa -s
   pagent run ./prd.md -p minimal
+  pagent run ./prd.md -preset python-ml
+  pagent run ./prd.md -resolve prefer-prd
   pagent run ./input/ -o ./docs/specs/
+  pagent run ./prd.md -events ndjson > events.ndjson
+  pagent run ./prd.md -daemon -detach
 `)
 	}
 
@@ -84,12 +81,77 @@ Examples:
 		return fmt.Errorf("missing required argument: input file or directory")
 	}
 
-	// Set input path
-	opts.InputPath = fs.Arg(0)
+	opts := rf.options(fs.Arg(0))
+
+	// Execute using the shared runner
+	logger := runner.NewStdLogger(verbose, quiet)
+	if opts.EventFormat != "" {
+		// Keep stdout reserved for the event stream
+		logger.WithOutput(os.Stderr)
+	}
+	if useDaemon {
+		if opts.MetricsAddr != "" {
+			return fmt.Errorf("-metrics-addr is not supported with -daemon")
+		}
+		return submitToDaemon(opts, detach, logger)
+	}
+	if detach {
+		return fmt.Errorf("-detach requires -daemon")
+	}
+	// Ask about PRD-vs-config conflicts only when someone can answer
+	var session runner.Session
+	if opts.EventFormat == "" && term.IsTerminal(int(os.Stdin.Fd())) {
+		session.ResolveConflicts = tui.ConflictResolver(false)
+	}
+	return runner.ExecuteSession(context.Background(), opts, logger, session)
+}
+
+// runFlags holds the pipeline flags shared by 'run' and 'submit'
+type runFlags struct {
+	opts        config.RunOptions
+	agents      string
+	resume      bool
+	force       bool
+	stateless   bool
+	noStateless bool
+}
+
+// addRunFlags registers the pipeline flags on fs
+func addRunFlags(fs *flag.FlagSet) *runFlags {
+	// Initialize with defaults
+	rf := &runFlags{opts: config.DefaultRunOptions(nil)}
+
+	fs.StringVar(&rf.agents, "a", "", "comma-separated list of agents (default: all)")
+	fs.StringVar(&rf.agents, "agents", "", "comma-separated list of agents (default: all)")
+	fs.StringVar(&rf.opts.OutputDir, "o", "", "output directory (default: output_dir from config)")
+	fs.StringVar(&rf.opts.OutputDir, "output", "", "output directory (default: output_dir from config)")
+	fs.BoolVar(&rf.opts.Sequential, "s", false, "run agents in dependency order")
+	fs.BoolVar(&rf.opts.Sequential, "sequential", false, "run agents in dependency order")
+	fs.StringVar(&rf.opts.ConfigPath, "c", "", "config file path")
+	fs.StringVar(&rf.opts.ConfigPath, "config", "", "config file path")
+	fs.IntVar(&rf.opts.Timeout, "t", 0, "timeout per agent in seconds (0=infinite)")
+	fs.IntVar(&rf.opts.Timeout, "timeout", 0, "timeout per agent in seconds (0=infinite)")
+	fs.BoolVar(&rf.resume, "r", false, "skip agents whose outputs are up-to-date")
+	fs.BoolVar(&rf.resume, "resume", false, "skip agents whose outputs are up-to-date")
+	fs.BoolVar(&rf.force, "f", false, "force regeneration, ignore existing outputs")
+	fs.BoolVar(&rf.force, "force", false, "force regeneration, ignore existing outputs")
+	fs.StringVar(&rf.opts.Persona, "p", "", "implementation style: minimal, balanced, production or a persona from the config")
+	fs.StringVar(&rf.opts.Persona, "persona", "", "implementation style: minimal, balanced, production or a persona from the config")
+	fs.StringVar(&rf.opts.Preset, "preset", "", "preset profile to layer the config on (see 'pagent presets list')")
+	fs.BoolVar(&rf.stateless, "stateless", false, "prefer stateless architecture")
+	fs.BoolVar(&rf.noStateless, "no-stateless", false, "prefer traditional database-backed architecture")
+	fs.StringVar(&rf.opts.Resolve, "resolve", "", "resolve PRD-vs-config stack conflicts: prefer-prd, prefer-config, fail")
+	return rf
+}
+
+// options returns the run options for input after flags were parsed
+func (rf *runFlags) options(input string) config.RunOptions {
+	opts := rf.opts
+	opts.InputPath = input
 
 	// Parse agents
-	if agentsFlag != "" {
-		agents := strings.Split(agentsFlag, ",")
+	if rf.agents != "" {
+		agents := strings.Split(rf.agents, ",")
 		for i := range agents {
 			agents[i] = strings.TrimSpace(agents[i])
 		}
@@ -97,15 +159,15 @@ Examples:
 	}
 
 	// Map boolean flags to options
-	if forceMode {
+	if rf.force {
 		opts.ResumeMode = config.ResumeModeForce
-	} else if resumeMode {
+	} else if rf.resume {
 		opts.ResumeMode = config.ResumeModeResume
 	}
 
-	if stateless {
+	if rf.stateless {
 		opts.Architecture = config.ArchitectureStateless
-	} else if noStateless {
+	} else if rf.noStateless {
 		opts.Architecture = config.ArchitectureDatabase
 	}
 
@@ -115,8 +177,5 @@ Examples:
 	} else if quiet {
 		opts.Verbosity = config.VerbosityQuiet
 	}
-
-	// Execute using the shared runner
-	logger := runner.NewStdLogger(verbose, quiet)
-	return runner.Execute(context.Background(), opts, logger)
+	return opts
 }
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/daemon"
+)
+
+func runsMain(args []string) error {
+	fs := flag.NewFlagSet("runs", flag.ContinueOnError)
+	var (
+		socketPath   string
+		outputFormat string
+	)
+	fs.StringVar(&socketPath, "socket", daemon.DefaultSocketPath(), "daemon unix socket path")
+	addOutputFlag(fs, &outputFormat)
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent runs [command] [flags] [args]
+
+List and control runs owned by 'pagent daemon'.
+
+Commands:
+  list                      List runs (default)
+  show <run>                Show a run and its agents
+  pause <run>               Stop starting new agents (running agents continue)
+  resume <run>              Resume a paused run
+  cancel <run>              Cancel a run and stop its agents
+  restart <run> <agent>     Restart a running agent with a fresh session
+  stop <run> <agent>        Stop a running agent (reported as failed)
+
+Flags:
+  -socket string    Daemon unix socket path
+  -output string    Output format: text, json (default: text)
+
+Examples:
+  pagent runs
+  pagent runs show -output json 20250101-120000-1a2b3c
+  pagent runs restart 20250101-120000-1a2b3c architect
+`)
+	}
+
+	command := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	positional := fs.Args()
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
+	}
+
+	client := daemon.NewClient(socketPath)
+	ctx := context.Background()
+
+	need := func(n int) error {
+		if len(positional) != n {
+			fs.Usage()
+			return fmt.Errorf("runs %s takes %d argument(s)", command, n)
+		}
+		return nil
+	}
+
+	var (
+		info daemon.RunInfo
+		err  error
+	)
+	switch command {
+	case "list":
+		runs, err := client.Runs(ctx)
+		if err != nil {
+			return err
+		}
+		return printRuns(runs, outputFormat)
+	case "show":
+		if err := need(1); err != nil {
+			return err
+		}
+		info, err = client.Run(ctx, positional[0])
+	case daemon.ActionPause, daemon.ActionResume, daemon.ActionCancel:
+		if err := need(1); err != nil {
+			return err
+		}
+		info, err = client.ControlRun(ctx, positional[0], command)
+	case daemon.ActionRestart, daemon.ActionStop:
+		if err := need(2); err != nil {
+			return err
+		}
+		info, err = client.ControlAgent(ctx, positional[0], positional[1], command)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown runs command: %s", command)
+	}
+	if err != nil {
+		return err
+	}
+	return printRun(info, outputFormat)
+}
+
+// printRuns renders a run list
+func printRuns(runs []daemon.RunInfo, outputFormat string) error {
+	if outputFormat == outputJSON {
+		if runs == nil {
+			runs = []daemon.RunInfo{}
+		}
+		return printJSON(map[string]interface{}{"runs": runs})
+	}
+
+	if len(runs) == 0 {
+		logInfo("No runs in the daemon")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RUN\tSTATE\tSUBMITTED\tAGENTS\tINPUT")
+	for _, r := range runs {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			r.ID,
+			runState(r),
+			r.SubmittedAt.Local().Format("2006-01-02 15:04:05"),
+			len(r.Agents),
+			r.Input,
+		)
+	}
+	return w.Flush()
+}
+
+// printRun renders a single run with its agents
+func printRun(info daemon.RunInfo, outputFormat string) error {
+	if outputFormat == outputJSON {
+		return printJSON(info)
+	}
+
+	fmt.Printf("Run:       %s\n", info.ID)
+	fmt.Printf("State:     %s\n", runState(info))
+	fmt.Printf("Input:     %s\n", info.Input)
+	fmt.Printf("Submitted: %s\n", info.SubmittedAt.Local().Format("2006-01-02 15:04:05"))
+	if info.FinishedAt != nil {
+		fmt.Printf("Duration:  %s\n", info.FinishedAt.Sub(info.SubmittedAt).Round(time.Second))
+	}
+	if info.Error != "" {
+		fmt.Printf("Error:     %s\n", info.Error)
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "AGENT\tSTATUS\tPORT\tDETAIL")
+	for _, a := range info.Agents {
+		port := "-"
+		if a.Active() {
+			port = fmt.Sprintf("%d", a.Port)
+		}
+		detail := a.OutputPath
+		if a.Error != "" {
+			detail = a.Error
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Name, a.Status, port, detail)
+	}
+	return w.Flush()
+}
+
+// runState returns the run state, noting when a running run is paused
+func runState(info daemon.RunInfo) string {
+	if info.Paused {
+		return info.State + " (paused)"
+	}
+	return info.State
+}
@@ -6,49 +6,54 @@ import (
 	"os"
 	"text/tabwriter"
 
-	"github.com/tuannvm/pagent/internal/agent"
 	"github.com/tuannvm/pagent/internal/api"
 )
 
+// statusEntry is the JSON representation of a running agent
+type statusEntry struct {
+	Name   string `json:"name"`
+	Port   int    `json:"port"`
+	Status string `json:"status"`
+	RunID  string `json:"run_id,omitempty"`
+}
+
 func statusMain(args []string) error {
 	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	var outputFormat string
+	addOutputFlag(fs, &outputFormat)
 	parseGlobalFlags(fs)
 
 	fs.Usage = func() {
-		fmt.Print(`Usage: pagent status
+		fmt.Print(`Usage: pagent status [flags]
 
 Check the status of all running agents.
 
 Shows each agent's current state (running/stable/not running)
-and port number.
+and port number. When 'pagent daemon' is running, agents of all
+daemon runs are listed with their run ID.
+
+Flags:
+  -output string    Output format: text, json (default: text)
 `)
 	}
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
-
-	// Read state file to find running agents
-	state, err := agent.LoadState()
-	if err != nil {
-		if os.IsNotExist(err) {
-			logInfo("No agents currently running")
-			return nil
-		}
-		return fmt.Errorf("failed to read state: %w", err)
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return err
 	}
 
-	if len(state) == 0 {
-		logInfo("No agents currently running")
-		return nil
+	// Find running agents (daemon or state file)
+	agents, err := findRunningAgents()
+	if err != nil {
+		return err
 	}
 
 	// Check status of each agent
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "AGENT\tPORT\tSTATUS")
-
-	for name, port := range state {
-		client := api.NewClient(port)
+	entries := make([]statusEntry, 0, len(agents))
+	for _, a := range agents {
+		client := api.NewClient(a.Port)
 		status, err := client.GetStatus()
 
 		statusStr := "not responding"
@@ -56,7 +61,26 @@ and port number.
 			statusStr = status.Status
 		}
 
-		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\n", name, port, statusStr)
+		entries = append(entries, statusEntry{Name: a.Name, Port: a.Port, Status: statusStr, RunID: a.RunID})
+	}
+
+	if outputFormat == outputJSON {
+		return printJSON(map[string]interface{}{"agents": entries})
+	}
+
+	if len(entries) == 0 {
+		logInfo("No agents currently running")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "AGENT\tPORT\tSTATUS\tRUN")
+	for _, e := range entries {
+		runID := e.RunID
+		if runID == "" {
+			runID = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", e.Name, e.Port, e.Status, runID)
 	}
 
 	_ = w.Flush()
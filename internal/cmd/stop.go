@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/tuannvm/pagent/internal/agent"
+	"github.com/tuannvm/pagent/internal/daemon"
 )
 
 func stopMain(args []string) error {
@@ -43,6 +45,11 @@ Examples:
 		return fmt.Errorf("specify an agent name or use -all")
 	}
 
+	// A running daemon owns its agents; ask it to stop them
+	if client := daemonClient(); client != nil {
+		return stopDaemonAgents(client, fs.Arg(0), stopAll)
+	}
+
 	// Read state file
 	state, err := agent.LoadState()
 	if err != nil {
@@ -53,28 +60,69 @@ Examples:
 		return fmt.Errorf("failed to read state: %w", err)
 	}
 
-	if len(state) == 0 {
+	agents := state.Agents()
+	if len(agents) == 0 {
 		logInfo("No agents currently running")
 		return nil
 	}
 
-	if stopAll {
-		for name, port := range state {
-			stopAgentByPort(name, port)
+	// An agent name may be running in several runs; stop it in each
+	agentName := fs.Arg(0)
+	stopped := 0
+	for _, a := range agents {
+		if !stopAll && a.Name != agentName {
+			continue
 		}
-		agent.ClearState()
-		logInfo("All agents stopped")
-	} else {
-		agentName := fs.Arg(0)
-		port, ok := state[agentName]
-		if !ok {
-			return fmt.Errorf("agent '%s' not found", agentName)
+		stopAgentByPort(a.Name, a.Port)
+		if err := agent.RemoveAgentFromState(a.RunID, a.Name); err != nil {
+			logVerbose("Failed to update state: %v", err)
 		}
+		stopped++
+	}
 
-		stopAgentByPort(agentName, port)
-		logInfo("Agent %s stopped", agentName)
+	if stopAll {
+		logInfo("All agents stopped")
+		return nil
+	}
+	if stopped == 0 {
+		return fmt.Errorf("agent '%s' not found", agentName)
 	}
+	logInfo("Agent %s stopped", agentName)
+	return nil
+}
 
+// stopDaemonAgents stops one or all agents owned by the daemon
+func stopDaemonAgents(client *daemon.Client, agentName string, stopAll bool) error {
+	ctx := context.Background()
+	agents, err := client.Agents(ctx)
+	if err != nil {
+		return err
+	}
+	if len(agents) == 0 {
+		logInfo("No agents currently running")
+		return nil
+	}
+
+	stopped := 0
+	for _, a := range agents {
+		if !stopAll && a.Name != agentName {
+			continue
+		}
+		logVerbose("Stopping agent %s of run %s", a.Name, a.RunID)
+		if _, err := client.ControlAgent(ctx, a.RunID, a.Name, daemon.ActionStop); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", a.Name, err)
+		}
+		stopped++
+	}
+
+	if stopAll {
+		logInfo("All agents stopped")
+		return nil
+	}
+	if stopped == 0 {
+		return fmt.Errorf("agent '%s' not found", agentName)
+	}
+	logInfo("Agent %s stopped", agentName)
 	return nil
 }
 
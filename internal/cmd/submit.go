@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/tuannvm/pagent/internal/audit"
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/queue"
+)
+
+func submitMain(args []string) error {
+	fs := flag.NewFlagSet("submit", flag.ContinueOnError)
+	rf := addRunFlags(fs)
+
+	var (
+		priority int
+		wait     bool
+	)
+	fs.IntVar(&priority, "priority", 0, "queue priority; higher runs first")
+	fs.BoolVar(&wait, "wait", false, "wait for the job to finish")
+	parseGlobalFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`Usage: pagent submit [flags] <input>
+
+Add a pipeline run to the persistent job queue and print its job ID.
+
+Queued jobs are run by 'pagent daemon' or 'pagent mcp', highest priority
+first, up to queue.concurrency at a time. Jobs survive restarts of the
+process running them. Use 'pagent jobs' to list or cancel them.
+
+Arguments:
+  <input>    Input file or directory (.md, .yaml, .yml, .json, .txt)
+
+Flags:
+  -priority int          Queue priority; higher runs first (default: 0)
+  -wait                  Wait for the job to finish
+  -a, -agents string     Comma-separated list of agents (default: all)
+  -o, -output string     Output directory (default: output_dir from config, ./outputs)
+  -s, -sequential        Run agents in dependency order
+  -c, -config string     Config file path (also selects the queue)
+  -t, -timeout int       Timeout per agent in seconds (0=infinite)
+  -r, -resume            Skip agents whose outputs are up-to-date
+  -f, -force             Force regeneration, ignore existing outputs
+  -p, -persona string    Implementation style: minimal, balanced, production or a persona from the config
+  -preset string         Preset profile to layer the config on (e.g. go-api)
+  -stateless             Prefer stateless architecture
+  -no-stateless          Prefer traditional database-backed architecture
+  -resolve string        Resolve PRD-vs-config stack conflicts: prefer-prd,
+                         prefer-config, or fail to stop the job (default: the
+                         worker's -resolve, else warn and agents follow the PRD)
+  -v, -verbose           Verbose output
+  -q, -quiet             Quiet output (errors only)
+
+Examples:
+  pagent submit ./prd.md
+  pagent submit -priority 10 -a architect,qa ./prd.md
+  pagent submit -wait ./prd.md
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("missing required argument: input file or directory")
+	}
+	if _, err := os.Stat(fs.Arg(0)); err != nil {
+		return fmt.Errorf("input not found: %s", fs.Arg(0))
+	}
+
+	// The job runs in another process, so resolve paths here
+	opts, err := absRunOptions(rf.options(fs.Arg(0)))
+	if err != nil {
+		return err
+	}
+	if opts.Preset != "" {
+		if _, err := config.LoadPreset(opts.Preset); err != nil {
+			return err
+		}
+	}
+
+	q, _, err := openQueue(opts.ConfigPath)
+	if err != nil {
+		return err
+	}
+	job, err := q.Enqueue(context.Background(), opts, priority, audit.SourceCLI, audit.LocalUser())
+	if err != nil {
+		return err
+	}
+
+	if !q.WorkerActive() {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: no worker is serving %s; start 'pagent daemon' to run queued jobs\n", q.Dir())
+	}
+
+	if !wait {
+		// Print only the job ID so scripts can capture it
+		fmt.Println(job.ID)
+		return nil
+	}
+	logInfo("Queued job %s (Ctrl-C stops waiting; the job stays queued)", job.ID)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	job, err = q.Wait(ctx, job.ID)
+	if err != nil {
+		if ctx.Err() != nil {
+			logInfo("\nStopped waiting for job %s (see 'pagent jobs show %s')", job.ID, job.ID)
+			return nil
+		}
+		return err
+	}
+
+	if err := printJob(job, outputText); err != nil {
+		return err
+	}
+	if job.State != queue.StateSucceeded {
+		return fmt.Errorf("job %s %s", job.ID, job.State)
+	}
+	return nil
+}
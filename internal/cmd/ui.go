@@ -85,5 +85,7 @@ Examples:
 
 	// Execute directly using the shared runner - NO TRANSLATION LAYER!
 	logger := runner.NewStdLogger(opts.IsVerbose(), opts.IsQuiet())
-	return runner.Execute(context.Background(), *opts, logger)
+	return runner.ExecuteSession(context.Background(), *opts, logger, runner.Session{
+		ResolveConflicts: tui.ConflictResolver(accessible),
+	})
 }
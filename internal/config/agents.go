@@ -0,0 +1,60 @@
+// agents.go classifies agents by the kind of output they produce, which
+// decides where that output is written.
+package config
+
+// Agent kinds
+const (
+	AgentKindSpec  = "spec"  // Writes specification documents to specs_output_dir
+	AgentKindCode  = "code"  // Writes or changes code, in the target codebase in modify mode
+	AgentKindOther = "other" // Writes to output_dir
+)
+
+// ValidAgentKinds lists all valid agent kinds
+var ValidAgentKinds = []string{AgentKindSpec, AgentKindCode, AgentKindOther}
+
+// AgentKind returns the kind of agent name; agents without one are other
+func (c *Config) AgentKind(name string) string {
+	if kind := c.Agents[name].Kind; kind != "" {
+		return kind
+	}
+	return AgentKindOther
+}
+
+// AgentOutputRoot returns the directory the output of agent name is
+// relative to: its output_root, or the directory of its kind
+func (c *Config) AgentOutputRoot(name string) string {
+	if root := c.Agents[name].OutputRoot; root != "" {
+		return root
+	}
+	switch c.AgentKind(name) {
+	case AgentKindSpec:
+		return c.GetEffectiveSpecsOutputDir()
+	case AgentKindCode:
+		if c.IsModifyMode() {
+			return c.GetEffectiveCodeOutputDir()
+		}
+	}
+	// In create mode code outputs such as "code/.complete" carry the
+	// code/ prefix themselves
+	return c.OutputDir
+}
+
+// inheritBuiltinAgents gives agents named like built-in ones the kind and
+// description of the built-in agent when they set none, so configs that
+// redefine the pipeline keep them
+func (c *Config) inheritBuiltinAgents() {
+	builtin := Default().Agents
+	for name, agent := range c.Agents {
+		b, ok := builtin[name]
+		if !ok {
+			continue
+		}
+		if agent.Kind == "" {
+			agent.Kind = b.Kind
+		}
+		if agent.Description == "" {
+			agent.Description = b.Description
+		}
+		c.Agents[name] = agent
+	}
+}
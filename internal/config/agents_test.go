@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAgentOutputRoot(t *testing.T) {
+	cfg := Default()
+	cfg.Agents["docs-writer"] = AgentConfig{Kind: AgentKindSpec, Output: "docs.md"}
+	cfg.Agents["changelog"] = AgentConfig{Output: "CHANGELOG.md", OutputRoot: "/repo"}
+	cfg.Agents["notes"] = AgentConfig{Output: "notes.md"}
+	cfg.SpecsOutputDir = "./specs"
+
+	tests := []struct {
+		agent, mode, want string
+	}{
+		{"architect", ModeCreate, "./specs"},
+		{"docs-writer", ModeCreate, "./specs"},
+		{"implementer", ModeCreate, "./outputs"},
+		{"implementer", ModeModify, "/target"},
+		{"notes", ModeModify, "./outputs"},
+		{"changelog", ModeModify, "/repo"},
+	}
+	for _, tt := range tests {
+		cfg.Mode, cfg.TargetCodebase = tt.mode, "/target"
+		if got := cfg.AgentOutputRoot(tt.agent); got != tt.want {
+			t.Errorf("AgentOutputRoot(%s) in %s mode = %q, want %q", tt.agent, tt.mode, got, tt.want)
+		}
+	}
+	if got := cfg.AgentKind("notes"); got != AgentKindOther {
+		t.Errorf("AgentKind(notes) = %q, want other", got)
+	}
+}
+
+func TestLoadAgentsInheritBuiltin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "agents:\n  architect:\n    output: design.md\n  docs-writer:\n    kind: spec\n    output: docs.md\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	architect := cfg.Agents["architect"]
+	if architect.Kind != AgentKindSpec || architect.Description == "" {
+		t.Errorf("architect = %+v, want the kind and description of the built-in agent", architect)
+	}
+	if docs := cfg.Agents["docs-writer"]; docs.Kind != AgentKindSpec || docs.Description != "" {
+		t.Errorf("docs-writer = %+v", docs)
+	}
+}
+
+func TestValidateAgentKind(t *testing.T) {
+	cfg := Default()
+	cfg.Agents["docs-writer"] = AgentConfig{Kind: "docs", Output: "docs.md"}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `agents.docs-writer.kind must be one of spec, code, other, got "docs"`) {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
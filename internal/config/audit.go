@@ -0,0 +1,58 @@
+// audit.go defines settings for the audit log.
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Audit log rotation defaults
+const (
+	DefaultAuditMaxSizeMB = 10
+	DefaultAuditMaxFiles  = 5
+)
+
+// AuditConfig configures the append-only audit log of MCP tool calls and runs
+type AuditConfig struct {
+	Disabled  bool   `yaml:"disabled"`    // Turn the audit log off
+	Path      string `yaml:"path"`        // Log file (default: ~/.pagent/audit.jsonl)
+	MaxSizeMB int    `yaml:"max_size_mb"` // Size at which the log is rotated (default: 10)
+	MaxFiles  int    `yaml:"max_files"`   // Rotated files kept (default: 5)
+}
+
+// EffectivePath returns the audit log path with ~ expanded
+func (a AuditConfig) EffectivePath() string {
+	path := a.Path
+	if path == "" {
+		path = filepath.Join("~", ".pagent", "audit.jsonl")
+	}
+	return expandHome(path)
+}
+
+// EffectiveMaxSize returns the rotation size in bytes, applying the default
+func (a AuditConfig) EffectiveMaxSize() int64 {
+	mb := a.MaxSizeMB
+	if mb <= 0 {
+		mb = DefaultAuditMaxSizeMB
+	}
+	return int64(mb) * 1024 * 1024
+}
+
+// EffectiveMaxFiles returns the number of rotated files kept, applying the default
+func (a AuditConfig) EffectiveMaxFiles() int {
+	if a.MaxFiles <= 0 {
+		return DefaultAuditMaxFiles
+	}
+	return a.MaxFiles
+}
+
+// Validate checks the rotation limits
+func (a AuditConfig) Validate() error {
+	if a.MaxSizeMB < 0 {
+		return fmt.Errorf("audit.max_size_mb must not be negative, got %d", a.MaxSizeMB)
+	}
+	if a.MaxFiles < 0 {
+		return fmt.Errorf("audit.max_files must not be negative, got %d", a.MaxFiles)
+	}
+	return nil
+}
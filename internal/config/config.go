@@ -7,7 +7,6 @@ import (
 	"sort"
 
 	"github.com/tuannvm/pagent/internal/types"
-	"gopkg.in/yaml.v3"
 )
 
 // Persona constants define implementation styles
@@ -38,9 +37,10 @@ type (
 
 // Config represents the pagent configuration
 type Config struct {
+	Preset      string                  `yaml:"preset"` // Preset profile the config is layered on
 	OutputDir   string                  `yaml:"output_dir"`
 	Timeout     int                     `yaml:"timeout"`
-	Persona     string                  `yaml:"persona"`     // Implementation style: minimal, balanced, production
+	Persona     string                  `yaml:"persona"`     // Implementation style: minimal, balanced, production or a persona below
 	Stack       TechStack               `yaml:"stack"`       // Technology stack preferences
 	Preferences ArchitecturePreferences `yaml:"preferences"` // Architectural style preferences
 	ResumeMode  bool                    `yaml:"-"`           // Set via CLI flag, not config file
@@ -55,6 +55,33 @@ type Config struct {
 
 	// Post-processing options
 	PostProcessing PostProcessingConfig `yaml:"post_processing"`
+
+	// Notifications sent to external systems while runs progress
+	Notifications NotificationsConfig `yaml:"notifications"`
+
+	// OpenTelemetry tracing of runs
+	Tracing TracingConfig `yaml:"tracing"`
+
+	// Persistent queue for submitted runs
+	Queue QueueConfig `yaml:"queue"`
+
+	// MCP server workspace restrictions
+	MCP MCPConfig `yaml:"mcp"`
+
+	// Audit log of MCP tool calls and runs
+	Audit AuditConfig `yaml:"audit"`
+
+	// MCP servers given to agents without their own mcp_servers
+	MCPServers map[string]MCPServerConfig `yaml:"mcp_servers"`
+
+	// Personas beyond the built-in ones, by name
+	Personas map[string]PersonaConfig `yaml:"personas"`
+
+	// Origin of each value by dotted key, recorded while loading
+	origins map[string]valueOrigin
+
+	// Preferences replaced by the applied persona, restored when it changes
+	personaOverrides map[string]personaOverride
 }
 
 // PostProcessingConfig contains options for post-execution actions
@@ -92,6 +119,23 @@ func (c *Config) IsModifyMode() bool {
 	return c.Mode == ModeModify
 }
 
+// ValidateMode checks the mode and, in modify mode, that the target codebase exists
+func (c *Config) ValidateMode() error {
+	if !IsValidMode(c.Mode) {
+		return fmt.Errorf("invalid mode %q: must be one of %v", c.Mode, ValidModes)
+	}
+	if c.Mode == ModeModify {
+		if c.TargetCodebase == "" {
+			return fmt.Errorf("target_codebase is required when mode is %q", ModeModify)
+		}
+		// Verify target codebase exists
+		if _, err := os.Stat(c.TargetCodebase); os.IsNotExist(err) {
+			return fmt.Errorf("target_codebase %q does not exist", c.TargetCodebase)
+		}
+	}
+	return nil
+}
+
 // GetEffectiveCodeOutputDir returns the directory where code should be written
 // In modify mode, this is the target codebase; in create mode, it's output_dir/code
 func (c *Config) GetEffectiveCodeOutputDir() string {
@@ -115,54 +159,119 @@ func (c *Config) GetEffectiveSpecsOutputDir() string {
 
 // AgentConfig represents a single agent's configuration
 type AgentConfig struct {
-	Prompt     string   `yaml:"prompt"`      // Inline prompt (takes precedence)
-	PromptFile string   `yaml:"prompt_file"` // Path to prompt template file
-	Output     string   `yaml:"output"`
-	DependsOn  []string `yaml:"depends_on"`
+	Prompt      string   `yaml:"prompt"`      // Inline prompt (takes precedence)
+	PromptFile  string   `yaml:"prompt_file"` // Path to prompt template file
+	Output      string   `yaml:"output"`
+	DependsOn   []string `yaml:"depends_on"`
+	Kind        string   `yaml:"kind"`        // spec, code or other (default: other)
+	Description string   `yaml:"description"` // What the agent does, shown in listings
+	OutputRoot  string   `yaml:"output_root"` // Directory output is relative to (default: by kind)
+
+	// MCP servers for this agent only (replaces the global mcp_servers)
+	MCPServers map[string]MCPServerConfig `yaml:"mcp_servers,omitempty"`
 }
 
-// Load reads config from file, checking multiple locations
+// Load reads the layered config: built-in defaults, the preset named by the
+// config, ~/.pagent/config.yaml, then the project config (.pagent/config.yaml)
+// or the file at path, then PAGENT_* environment variables (see EnvName).
+// Later layers override earlier ones field by field.
 func Load(path string) (*Config, error) {
-	var configPath string
-
-	if path != "" {
-		configPath = path
-	} else {
-		// Check standard locations
-		locations := []string{
-			".pagent/config.yaml",
-			".pagent/config.yml",
-			filepath.Join(os.Getenv("HOME"), ".pagent/config.yaml"),
-		}
+	return LoadWithPreset(path, "")
+}
 
-		for _, loc := range locations {
-			if _, err := os.Stat(loc); err == nil {
-				configPath = loc
-				break
-			}
+// LoadWithPreset reads the layered config like Load. A non-empty preset
+// takes precedence over PAGENT_PRESET and the config's preset key.
+func LoadWithPreset(path, preset string) (*Config, error) {
+	files, err := configFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	fileLayers := make([]configLayer, 0, len(files))
+	var problems []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
 		}
+		l, err := parseLayer(file, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		l.file = true
+		fileLayers = append(fileLayers, l)
+		problems = append(problems, strictDecode(file, data, &Config{})...)
 	}
 
-	if configPath == "" {
-		return nil, os.ErrNotExist
+	presetOrigin := OriginFlag
+	if preset == "" {
+		if name := os.Getenv(EnvName("preset")); name != "" {
+			preset, presetOrigin = name, "env:"+EnvName("preset")
+		}
+	}
+	if preset == "" {
+		for _, l := range fileLayers {
+			if name := l.scalar("preset"); name != "" {
+				preset, presetOrigin = name, l.origin
+			}
+		}
 	}
 
-	data, err := os.ReadFile(configPath)
+	defaults, err := encodeLayer(OriginDefault, Default())
 	if err != nil {
 		return nil, err
 	}
+	layers := []configLayer{defaults.without("agents")}
+	definesAgents := false
+	for _, l := range fileLayers {
+		definesAgents = definesAgents || l.has("agents")
+	}
+	if preset != "" {
+		p, err := LoadPreset(preset)
+		if err != nil {
+			return nil, err
+		}
+		l, err := parseLayer("preset:"+preset, p.data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preset %s: %w", preset, err)
+		}
+		var presetFile struct {
+			Config      `yaml:",inline"`
+			Description string `yaml:"description"`
+		}
+		problems = append(problems, strictDecode(l.origin, p.data, &presetFile)...)
+		// Preset agents override the default agents rather than replace them
+		if l.has("agents") {
+			layers = append(layers, defaults.only("agents"))
+			definesAgents = true
+		}
+		layers = append(layers, l.without("description"))
+	}
+	// The default agents apply unless a config defines its own pipeline
+	if !definesAgents {
+		layers = append(layers, defaults.only("agents"))
+	}
+	layers = append(layers, fileLayers...)
+	if err := validationError(problems); err != nil {
+		return nil, err
+	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	cfg, err := mergeLayers(layers)
+	if err != nil {
 		return nil, err
 	}
+	if preset != "" {
+		cfg.Preset = preset
+		cfg.setOrigin("preset", presetOrigin)
+	}
 
 	// Apply defaults
 	if cfg.OutputDir == "" {
 		cfg.OutputDir = "./outputs"
 	}
-	if cfg.Timeout == 0 {
+	if cfg.Timeout == 0 && (len(files) > 0 || preset != "") {
+		// Loaded configs get a safety net; bare defaults wait indefinitely
 		cfg.Timeout = 300
+		cfg.setOrigin("timeout", OriginDefault)
 	}
 	if cfg.Persona == "" {
 		cfg.Persona = PersonaBalanced
@@ -171,60 +280,23 @@ func Load(path string) (*Config, error) {
 		cfg.Mode = ModeCreate
 	}
 
-	// Validate persona
-	if !IsValidPersona(cfg.Persona) {
-		return nil, fmt.Errorf("invalid persona %q: must be one of %v", cfg.Persona, ValidPersonas)
-	}
-
-	// Validate mode
-	if !IsValidMode(cfg.Mode) {
-		return nil, fmt.Errorf("invalid mode %q: must be one of %v", cfg.Mode, ValidModes)
-	}
-
-	// Validate modify mode requirements
-	if cfg.Mode == ModeModify {
-		if cfg.TargetCodebase == "" {
-			return nil, fmt.Errorf("target_codebase is required when mode is %q", ModeModify)
-		}
-		// Verify target codebase exists
-		if _, err := os.Stat(cfg.TargetCodebase); os.IsNotExist(err) {
-			return nil, fmt.Errorf("target_codebase %q does not exist", cfg.TargetCodebase)
-		}
-	}
-
 	// Apply default agents if none specified
 	if len(cfg.Agents) == 0 {
 		cfg.Agents = Default().Agents
+		cfg.setOrigin("agents", OriginDefault)
 	}
-
-	// Apply default stack if not specified
-	if cfg.Stack.Cloud == "" {
-		cfg.Stack = DefaultStack()
-	}
-
-	// Apply default preferences where not specified
-	defaultPrefs := DefaultPreferences()
-	if cfg.Preferences.Language == "" {
-		cfg.Preferences.Language = defaultPrefs.Language
-	}
+	cfg.inheritBuiltinAgents()
 
 	// Apply environment variable overrides
-	cfg.ApplyEnvOverrides()
-
-	return &cfg, nil
-}
-
-// ApplyEnvOverrides applies environment variable overrides to config
-func (c *Config) ApplyEnvOverrides() {
-	if envDir := os.Getenv("PAGENT_OUTPUT_DIR"); envDir != "" {
-		c.OutputDir = envDir
+	if err := cfg.ApplyEnvOverrides(); err != nil {
+		return nil, err
 	}
-	if envTimeout := os.Getenv("PAGENT_TIMEOUT"); envTimeout != "" {
-		var timeout int
-		if _, err := fmt.Sscanf(envTimeout, "%d", &timeout); err == nil && timeout > 0 {
-			c.Timeout = timeout
-		}
+	cfg.ApplyPersona()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
+	return cfg, nil
 }
 
 // DefaultPreferences returns the default architecture preferences
@@ -257,25 +329,35 @@ func Default() *Config {
 		Agents: map[string]AgentConfig{
 			// SPECIFICATION PHASE
 			"architect": {
-				Output:    "architecture.md",
-				DependsOn: []string{},
+				Output:      "architecture.md",
+				DependsOn:   []string{},
+				Kind:        AgentKindSpec,
+				Description: "Analyzes PRD and creates technical architecture document",
 			},
 			"qa": {
-				Output:    "test-plan.md",
-				DependsOn: []string{"architect"},
+				Output:      "test-plan.md",
+				DependsOn:   []string{"architect"},
+				Kind:        AgentKindSpec,
+				Description: "Creates comprehensive test plan based on architecture",
 			},
 			"security": {
-				Output:    "security-assessment.md",
-				DependsOn: []string{"architect"},
+				Output:      "security-assessment.md",
+				DependsOn:   []string{"architect"},
+				Kind:        AgentKindSpec,
+				Description: "Performs security assessment and threat modeling",
 			},
 			// IMPLEMENTATION PHASE
 			"implementer": {
-				Output:    "code/.complete",
-				DependsOn: []string{"architect", "security"},
+				Output:      "code/.complete",
+				DependsOn:   []string{"architect", "security"},
+				Kind:        AgentKindCode,
+				Description: "Implements the code based on architecture and security specs",
 			},
 			"verifier": {
-				Output:    "code/.verified",
-				DependsOn: []string{"implementer", "qa"},
+				Output:      "code/.verified",
+				DependsOn:   []string{"implementer", "qa"},
+				Kind:        AgentKindCode,
+				Description: "Verifies implementation against test plan and requirements",
 			},
 		},
 	}
@@ -403,7 +403,9 @@ func TestApplyEnvOverrides(t *testing.T) {
 	t.Setenv("PAGENT_OUTPUT_DIR", "/from/env")
 	t.Setenv("PAGENT_TIMEOUT", "600")
 
-	cfg.ApplyEnvOverrides()
+	if err := cfg.ApplyEnvOverrides(); err != nil {
+		t.Fatalf("ApplyEnvOverrides() error = %v", err)
+	}
 
 	if cfg.OutputDir != "/from/env" {
 		t.Errorf("OutputDir = %q, want %q", cfg.OutputDir, "/from/env")
@@ -419,13 +421,15 @@ func TestApplyEnvOverridesInvalidTimeout(t *testing.T) {
 		Timeout:   300,
 	}
 
-	// Invalid timeout should be ignored
+	// Invalid timeout is reported and leaves the value unchanged
 	t.Setenv("PAGENT_TIMEOUT", "invalid")
 
-	cfg.ApplyEnvOverrides()
+	if err := cfg.ApplyEnvOverrides(); err == nil {
+		t.Error("ApplyEnvOverrides() should return error for invalid timeout")
+	}
 
 	if cfg.Timeout != 300 {
-		t.Errorf("Invalid timeout should be ignored, got %d", cfg.Timeout)
+		t.Errorf("Invalid timeout should not be applied, got %d", cfg.Timeout)
 	}
 }
 
@@ -456,3 +460,195 @@ agents:
 		t.Error("Load() should return error for invalid YAML")
 	}
 }
+
+func TestNotificationsValidate(t *testing.T) {
+	t.Setenv("TEST_HOOK_URL", "https://hooks.example.com/abc")
+
+	tests := []struct {
+		name    string
+		webhook WebhookConfig
+		wantErr bool
+	}{
+		{"valid json webhook", WebhookConfig{URL: "https://example.com/hook"}, false},
+		{"valid slack webhook", WebhookConfig{URL: "https://example.com/hook", Format: WebhookFormatSlack}, false},
+		{"url from environment", WebhookConfig{URL: "${TEST_HOOK_URL}"}, false},
+		{"missing url", WebhookConfig{}, true},
+		{"unset env url", WebhookConfig{URL: "${TEST_HOOK_UNSET}"}, true},
+		{"non-http url", WebhookConfig{URL: "ftp://example.com"}, true},
+		{"unknown format", WebhookConfig{URL: "https://example.com", Format: "teams"}, true},
+		{"unknown event", WebhookConfig{URL: "https://example.com", Events: []string{"agent_started"}}, true},
+		{"known events", WebhookConfig{URL: "https://example.com", Events: []string{"run_completed", "agent_failed"}}, false},
+		{"negative retries", WebhookConfig{URL: "https://example.com", MaxRetries: intPtr(-1)}, true},
+		{"zero retries", WebhookConfig{URL: "https://example.com", MaxRetries: intPtr(0)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NotificationsConfig{Webhooks: []WebhookConfig{tt.webhook}}
+			err := n.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTracingConfig(t *testing.T) {
+	tests := []struct {
+		exporter    string
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{"", false, false},
+		{TracingExporterNone, false, false},
+		{TracingExporterOTLP, true, false},
+		{TracingExporterFile, true, false},
+		{"jaeger", true, true},
+	}
+
+	for _, tt := range tests {
+		tc := TracingConfig{Exporter: tt.exporter}
+		if got := tc.IsEnabled(); got != tt.wantEnabled {
+			t.Errorf("IsEnabled(%q) = %v, want %v", tt.exporter, got, tt.wantEnabled)
+		}
+		if err := tc.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", tt.exporter, err, tt.wantErr)
+		}
+	}
+}
+
+func TestQueueConfig(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	q := QueueConfig{}
+	if got, want := q.EffectiveDir(), filepath.Join(home, ".pagent", "queue"); got != want {
+		t.Errorf("EffectiveDir() = %q, want %q", got, want)
+	}
+	if got := q.EffectiveConcurrency(); got != DefaultQueueConcurrency {
+		t.Errorf("EffectiveConcurrency() = %d, want %d", got, DefaultQueueConcurrency)
+	}
+
+	q = QueueConfig{Dir: "/srv/queue", Concurrency: 4}
+	if got := q.EffectiveDir(); got != "/srv/queue" {
+		t.Errorf("EffectiveDir() = %q, want /srv/queue", got)
+	}
+	if got := q.EffectiveConcurrency(); got != 4 {
+		t.Errorf("EffectiveConcurrency() = %d, want 4", got)
+	}
+
+	if err := (QueueConfig{Concurrency: -1}).Validate(); err == nil {
+		t.Error("Validate() should reject negative concurrency")
+	}
+}
+
+func TestApplyStackOverrides(t *testing.T) {
+	cfg := Default()
+	err := cfg.ApplyStackOverrides(
+		map[string]any{"database": "none", "cloud": "gcp"},
+		map[string]any{"stateless": true, "api_style": "grpc"},
+	)
+	if err != nil {
+		t.Fatalf("ApplyStackOverrides() error = %v", err)
+	}
+
+	if cfg.Stack.Database != "none" || cfg.Stack.Cloud != "gcp" {
+		t.Errorf("stack = %+v, want database none and cloud gcp", cfg.Stack)
+	}
+	if cfg.Stack.Cache != DefaultStack().Cache {
+		t.Errorf("cache = %q, want unchanged %q", cfg.Stack.Cache, DefaultStack().Cache)
+	}
+	if !cfg.Preferences.Stateless || cfg.Preferences.APIStyle != "grpc" {
+		t.Errorf("preferences = %+v, want stateless grpc", cfg.Preferences)
+	}
+	if cfg.Preferences.Language != DefaultPreferences().Language {
+		t.Errorf("language = %q, want unchanged %q", cfg.Preferences.Language, DefaultPreferences().Language)
+	}
+
+	if err := cfg.ApplyStackOverrides(map[string]any{"databse": "none"}, nil); err == nil {
+		t.Error("ApplyStackOverrides() should reject unknown stack keys")
+	}
+	if err := cfg.ApplyStackOverrides(nil, map[string]any{"stateless": "yes please"}); err == nil {
+		t.Error("ApplyStackOverrides() should reject mistyped preference values")
+	}
+}
+
+func TestAgentMCPServers(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	content := `
+mcp_servers:
+  docs:
+    command: docs-mcp
+    args: [--readonly]
+agents:
+  architect:
+    prompt: design
+    output: architecture.md
+  implementer:
+    prompt: build
+    output: code/
+    mcp_servers:
+      db:
+        type: http
+        url: http://localhost:8080/mcp
+        tools: [describe_table]
+  qa:
+    prompt: test
+    output: test-plan.md
+    mcp_servers:
+      docs:
+        tools: [search]
+  security:
+    prompt: review
+    output: security.md
+    mcp_servers: {}
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.AgentMCPServers("architect"); got["docs"].Command != "docs-mcp" || len(got) != 1 {
+		t.Errorf("architect servers = %v, want the global docs server", got)
+	}
+	if got := cfg.AgentMCPServers("implementer"); len(got) != 1 || got["db"].URL == "" {
+		t.Errorf("implementer servers = %v, want only db", got)
+	}
+	qa := cfg.AgentMCPServers("qa")["docs"]
+	if qa.Command != "docs-mcp" || len(qa.Tools) != 1 || qa.Tools[0] != "search" {
+		t.Errorf("qa docs server = %+v, want the global server narrowed to search", qa)
+	}
+	if got := cfg.AgentMCPServers("security"); len(got) != 0 {
+		t.Errorf("security servers = %v, want none", got)
+	}
+}
+
+func TestValidateMCPServers(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"stdio without command", Config{MCPServers: map[string]MCPServerConfig{"docs": {Args: []string{"x"}}}}},
+		{"http without url", Config{MCPServers: map[string]MCPServerConfig{"docs": {Type: MCPServerHTTP}}}},
+		{"invalid type", Config{MCPServers: map[string]MCPServerConfig{"docs": {Type: "ws", URL: "ws://x"}}}},
+		{"unknown reference", Config{Agents: map[string]AgentConfig{
+			"qa": {MCPServers: map[string]MCPServerConfig{"docs": {}}},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.ValidateMCPServers(); err == nil {
+				t.Error("ValidateMCPServers() error = nil, want error")
+			}
+		})
+	}
+}
+
+func intPtr(v int) *int { return &v }
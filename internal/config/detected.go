@@ -0,0 +1,102 @@
+// detected.go merges the stack detected in the target codebase into the
+// config and reports where the two disagree.
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tuannvm/pagent/internal/detect"
+)
+
+// Disagreement is a setting configured explicitly to something other than
+// what the target codebase uses
+type Disagreement struct {
+	Key        string `json:"key"`
+	Configured string `json:"configured"`
+	Origin     string `json:"origin"` // Where the configured value came from
+	Detected   string `json:"detected"`
+	Evidence   string `json:"evidence"` // What revealed the detected value
+}
+
+func (d Disagreement) String() string {
+	return fmt.Sprintf("%s is %q (%s) but the codebase uses %q (%s)", d.Key, d.Configured, d.Origin, d.Detected, d.Evidence)
+}
+
+// IsExplicit reports whether a key was set by a config file, the
+// environment or a flag, rather than left to the defaults or a preset
+func (c *Config) IsExplicit(key string) bool {
+	origin := c.Origin(key)
+	return origin != OriginDefault && !strings.HasPrefix(origin, "preset:")
+}
+
+// ApplyDetected merges settings detected in the target codebase. Settings
+// left to the defaults or a preset take the detected value; settings set
+// explicitly are kept and returned as disagreements when they differ.
+func (c *Config) ApplyDetected(settings []detect.Setting) ([]Disagreement, error) {
+	current, err := c.Settings()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(current))
+	for _, s := range current {
+		values[s.Key] = s.Value
+	}
+
+	var disagreements []Disagreement
+	for _, s := range settings {
+		if values[s.Key] == s.Value {
+			continue
+		}
+		if c.IsExplicit(s.Key) {
+			disagreements = append(disagreements, Disagreement{
+				Key:        s.Key,
+				Configured: values[s.Key],
+				Origin:     c.Location(s.Key),
+				Detected:   s.Value,
+				Evidence:   s.Evidence.String(),
+			})
+			continue
+		}
+
+		section, key, _ := strings.Cut(s.Key, ".")
+		var value any = s.Value
+		if b, err := strconv.ParseBool(s.Value); err == nil {
+			value = b
+		}
+		override := map[string]any{key: value}
+		switch section {
+		case "stack":
+			err = c.ApplyStackOverrides(override, nil)
+		case "preferences":
+			err = c.ApplyStackOverrides(nil, override)
+		default:
+			err = fmt.Errorf("cannot apply detected setting %s", s.Key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.setOrigin(s.Key, "detected:"+s.Evidence.File)
+	}
+	return disagreements, nil
+}
+
+// ApplyTargetStack detects the stack of the target codebase in modify mode
+// and merges it with ApplyDetected. Create mode has no codebase to detect.
+func (c *Config) ApplyTargetStack() ([]Disagreement, error) {
+	if !c.IsModifyMode() {
+		return nil, nil
+	}
+	result, err := detect.Detect(c.TargetCodebase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect the stack of %s: %w", c.TargetCodebase, err)
+	}
+	// Name evidence from where pagent runs rather than from the codebase
+	settings := result.Settings()
+	for i := range settings {
+		settings[i].Evidence.File = filepath.Join(c.TargetCodebase, settings[i].Evidence.File)
+	}
+	return c.ApplyDetected(settings)
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tuannvm/pagent/internal/detect"
+)
+
+func TestApplyDetected(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("stack:\n  database: mongodb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	disagreements, err := cfg.ApplyDetected([]detect.Setting{
+		{Key: "stack.database", Value: "postgres", Evidence: detect.Evidence{File: "go.mod", Detail: "github.com/jackc/pgx"}},
+		{Key: "stack.message_queue", Value: "kafka", Evidence: detect.Evidence{File: "go.mod", Detail: "github.com/segmentio/kafka-go"}},
+		{Key: "preferences.language", Value: "python", Evidence: detect.Evidence{File: "pyproject.toml"}},
+		{Key: "preferences.containerized", Value: "true", Evidence: detect.Evidence{File: "Dockerfile"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyDetected() error = %v", err)
+	}
+
+	// Explicit settings are kept and reported
+	if cfg.Stack.Database != "mongodb" {
+		t.Errorf("stack.database = %q, want the configured mongodb", cfg.Stack.Database)
+	}
+	if len(disagreements) != 1 {
+		t.Fatalf("disagreements = %v, want one for stack.database", disagreements)
+	}
+	d := disagreements[0]
+	if d.Key != "stack.database" || d.Configured != "mongodb" || d.Detected != "postgres" || d.Origin != path+":2" {
+		t.Errorf("disagreement = %+v", d)
+	}
+
+	// Defaults take the detected value
+	if cfg.Stack.MessageQueue != "kafka" || cfg.Preferences.Language != "python" || !cfg.Preferences.Containerized {
+		t.Errorf("stack.message_queue, preferences.language, preferences.containerized = %q, %q, %v",
+			cfg.Stack.MessageQueue, cfg.Preferences.Language, cfg.Preferences.Containerized)
+	}
+	if got := cfg.Origin("stack.message_queue"); got != "detected:go.mod" {
+		t.Errorf("Origin(stack.message_queue) = %q", got)
+	}
+}
+
+func TestApplyTargetStackCreateMode(t *testing.T) {
+	cfg := Default()
+	disagreements, err := cfg.ApplyTargetStack()
+	if err != nil || disagreements != nil {
+		t.Errorf("ApplyTargetStack() in create mode = %v, %v", disagreements, err)
+	}
+}
@@ -0,0 +1,150 @@
+// env.go maps PAGENT_* environment variables onto config settings. The
+// variable of a setting is PAGENT_ followed by its dotted key in upper case
+// with dots replaced by underscores, e.g. stack.database is
+// PAGENT_STACK_DATABASE and agents.qa.output is PAGENT_AGENTS_QA_OUTPUT.
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts every config environment variable
+const EnvPrefix = "PAGENT_"
+
+// EnvVar is an environment variable that overrides a setting
+type EnvVar struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	Type string `json:"type"` // string, integer, boolean or list (comma-separated)
+}
+
+// EnvName returns the environment variable of the setting at a dotted key
+func EnvName(key string) string {
+	return EnvPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// EnvVars lists the environment variables that override settings of the
+// config, including the settings of each configured agent. Maps other than
+// agents and lists of objects (webhooks) can only be set in files.
+func (c *Config) EnvVars() []EnvVar {
+	vars := []EnvVar{{Name: EnvName("preset"), Key: "preset", Type: "string"}}
+	c.walkEnv(func(key string, v reflect.Value) {
+		vars = append(vars, EnvVar{Name: EnvName(key), Key: key, Type: envType(v)})
+	})
+	return vars
+}
+
+// ApplyEnvOverrides sets every setting whose environment variable is set
+// and not empty. Values that do not convert to the setting's type are
+// reported together in a *ValidationError. PAGENT_PRESET is read by Load,
+// since the preset is a layer beneath the config files.
+func (c *Config) ApplyEnvOverrides() error {
+	var problems []string
+	c.walkEnv(func(key string, v reflect.Value) {
+		name := EnvName(key)
+		raw := os.Getenv(name)
+		if raw == "" {
+			return
+		}
+		if err := setEnvValue(v, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("env:%s: %s %v, got %q", name, key, err, raw))
+			return
+		}
+		c.setOrigin(key, "env:"+name)
+	})
+	return validationError(problems)
+}
+
+// walkEnv calls fn with the dotted key and settable value of every setting
+// that can be overridden from the environment
+func (c *Config) walkEnv(fn func(key string, v reflect.Value)) {
+	root := reflect.ValueOf(c).Elem()
+	t := root.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _ := yamlFieldName(t.Field(i))
+		switch name {
+		case "", "preset":
+			continue
+		case "agents":
+			for _, agent := range c.GetAgentNames() {
+				// Map entries are not addressable, so walk a copy and store it back
+				cfg := c.Agents[agent]
+				walkEnvValue(reflect.ValueOf(&cfg).Elem(), "agents."+agent, fn)
+				c.Agents[agent] = cfg
+			}
+		default:
+			walkEnvValue(root.Field(i), name, fn)
+		}
+	}
+}
+
+func walkEnvValue(v reflect.Value, key string, fn func(key string, v reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if name, _ := yamlFieldName(t.Field(i)); name != "" {
+				walkEnvValue(v.Field(i), joinKey(key, name), fn)
+			}
+		}
+	case reflect.String, reflect.Int, reflect.Bool:
+		fn(key, v)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			fn(key, v)
+		}
+	}
+}
+
+func envType(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Int:
+		return "integer"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice:
+		return "list"
+	}
+	return "string"
+}
+
+// setEnvValue converts raw to the type of v and stores it
+func setEnvValue(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		v.SetString(raw)
+	}
+	return nil
+}
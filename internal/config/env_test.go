@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"output_dir":                    "PAGENT_OUTPUT_DIR",
+		"stack.database":                "PAGENT_STACK_DATABASE",
+		"preferences.api_style":         "PAGENT_PREFERENCES_API_STYLE",
+		"agents.security-review.output": "PAGENT_AGENTS_SECURITY_REVIEW_OUTPUT",
+	}
+	for key, want := range tests {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestApplyEnvOverridesEveryField(t *testing.T) {
+	cfg := Default()
+	t.Setenv("PAGENT_PERSONA", "production")
+	t.Setenv("PAGENT_STACK_DATABASE", "mongodb")
+	t.Setenv("PAGENT_PREFERENCES_STATELESS", "true")
+	t.Setenv("PAGENT_PREFERENCES_CONTAINERIZED", "false")
+	t.Setenv("PAGENT_QUEUE_CONCURRENCY", "4")
+	t.Setenv("PAGENT_MCP_ROOTS", "/srv/a, /srv/b")
+	t.Setenv("PAGENT_AGENTS_QA_DEPENDS_ON", "architect,security")
+	t.Setenv("PAGENT_AGENTS_QA_OUTPUT", "qa.md")
+
+	if err := cfg.ApplyEnvOverrides(); err != nil {
+		t.Fatalf("ApplyEnvOverrides() error = %v", err)
+	}
+
+	if cfg.Persona != PersonaProduction || cfg.Stack.Database != "mongodb" {
+		t.Errorf("persona, stack.database = %q, %q", cfg.Persona, cfg.Stack.Database)
+	}
+	if !cfg.Preferences.Stateless || cfg.Preferences.Containerized {
+		t.Errorf("Preferences = %+v, want stateless and not containerized", cfg.Preferences)
+	}
+	if cfg.Queue.Concurrency != 4 {
+		t.Errorf("queue.concurrency = %d, want 4", cfg.Queue.Concurrency)
+	}
+	if want := []string{"/srv/a", "/srv/b"}; !reflect.DeepEqual(cfg.MCP.Roots, want) {
+		t.Errorf("mcp.roots = %q, want %q", cfg.MCP.Roots, want)
+	}
+	qa := cfg.Agents["qa"]
+	if qa.Output != "qa.md" || !reflect.DeepEqual(qa.DependsOn, []string{"architect", "security"}) {
+		t.Errorf("agents.qa = %+v", qa)
+	}
+	if got := cfg.Origin("agents.qa.depends_on"); got != "env:PAGENT_AGENTS_QA_DEPENDS_ON" {
+		t.Errorf("Origin(agents.qa.depends_on) = %q", got)
+	}
+	if got := cfg.Origin("agents.qa.prompt"); got != OriginDefault {
+		t.Errorf("Origin(agents.qa.prompt) = %q, want default", got)
+	}
+}
+
+func TestApplyEnvOverridesInvalidValues(t *testing.T) {
+	cfg := Default()
+	t.Setenv("PAGENT_PREFERENCES_STATELESS", "maybe")
+	t.Setenv("PAGENT_QUEUE_CONCURRENCY", "many")
+
+	err := cfg.ApplyEnvOverrides()
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 2 {
+		t.Fatalf("ApplyEnvOverrides() error = %v, want two problems", err)
+	}
+	want := `env:PAGENT_PREFERENCES_STATELESS: preferences.stateless must be true or false, got "maybe"`
+	if verr.Problems[0] != want && verr.Problems[1] != want {
+		t.Errorf("Problems = %q, want %q among them", verr.Problems, want)
+	}
+}
+
+func TestLoadValidatesEnvValues(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	t.Setenv("PAGENT_PERSONA", "enterprise")
+
+	_, err := Load("")
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Problems[0] != `env:PAGENT_PERSONA: persona must be one of minimal, balanced, production, got "enterprise"` {
+		t.Errorf("Load() error = %v, want the invalid persona reported against PAGENT_PERSONA", err)
+	}
+}
+
+func TestLoadPresetFromEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "preset: go-api\n")
+	t.Setenv("PAGENT_PRESET", "python-ml")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Preset != "python-ml" || cfg.Preferences.Language != "python" {
+		t.Errorf("Load() preset = %q, language = %q, want python-ml from the environment", cfg.Preset, cfg.Preferences.Language)
+	}
+	if got := cfg.Origin("preset"); got != "env:PAGENT_PRESET" {
+		t.Errorf("Origin(preset) = %q", got)
+	}
+}
@@ -0,0 +1,187 @@
+// init.go holds the answers of 'pagent init', shared by the interactive
+// wizard and its flags, and writes them into a config file.
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InitStackKeys are the stack settings 'pagent init' asks about
+var InitStackKeys = []string{"cloud", "compute", "database", "cache", "message_queue", "ci", "iac"}
+
+// InitPreferenceKeys are the preferences 'pagent init' asks about
+var InitPreferenceKeys = []string{"language", "api_style", "testing_depth", "stateless", "containerized", "include_ci", "include_iac"}
+
+// InitOptions are the settings 'pagent init' writes. Stack and Preferences
+// are keyed like the config file.
+type InitOptions struct {
+	Mode           string
+	TargetCodebase string // Written in modify mode only
+	Persona        string
+	Stack          map[string]string
+	Preferences    map[string]any // Strings and booleans
+
+	Personas map[string]PersonaConfig // Defined in the config, offered besides the built-in ones; not written
+}
+
+// InitOptionsFrom returns the answers matching a config, for pre-filling
+func InitOptionsFrom(cfg *Config) InitOptions {
+	o := InitOptions{
+		Mode:           cfg.Mode,
+		TargetCodebase: cfg.TargetCodebase,
+		Persona:        cfg.Persona,
+		Stack:          map[string]string{},
+		Preferences:    map[string]any{},
+		Personas:       cfg.Personas,
+	}
+	stack := cfg.stackValues()
+	for _, key := range InitStackKeys {
+		o.Stack[key] = stack[key]
+	}
+	prefs := cfg.preferenceValues()
+	for _, key := range InitPreferenceKeys {
+		switch key {
+		case "stateless":
+			o.Preferences[key] = cfg.Preferences.Stateless
+		case "containerized":
+			o.Preferences[key] = cfg.Preferences.Containerized
+		case "include_ci":
+			o.Preferences[key] = cfg.Preferences.IncludeCI
+		case "include_iac":
+			o.Preferences[key] = cfg.Preferences.IncludeIaC
+		default:
+			o.Preferences[key] = prefs[key]
+		}
+	}
+	return o
+}
+
+// KeyValue is a setting by dotted key
+type KeyValue struct {
+	Key   string
+	Value any
+}
+
+// Values returns the settings to write, in file order
+func (o InitOptions) Values() []KeyValue {
+	values := []KeyValue{{"mode", o.Mode}}
+	if o.Mode == ModeModify {
+		values = append(values, KeyValue{"target_codebase", o.TargetCodebase})
+	}
+	values = append(values, KeyValue{"persona", o.Persona})
+	for _, key := range sortedKeys(o.Stack) {
+		values = append(values, KeyValue{"stack." + key, o.Stack[key]})
+	}
+	for _, key := range sortedKeys(o.Preferences) {
+		values = append(values, KeyValue{"preferences." + key, o.Preferences[key]})
+	}
+	return values
+}
+
+// PersonaChoices returns the personas that can be chosen
+func (o InitOptions) PersonaChoices() []Option {
+	return (&Config{Personas: o.Personas}).PersonaChoices()
+}
+
+// Validate checks the answers as they would apply to the default config
+func (o InitOptions) Validate() error {
+	cfg := Default()
+	cfg.Mode, cfg.Persona = o.Mode, o.Persona
+	cfg.Personas = make(map[string]PersonaConfig, len(o.Personas))
+	for name, p := range o.Personas {
+		p.Agents = nil // They may name agents of the config rather than the default ones
+		cfg.Personas[name] = p
+	}
+	if o.Mode == ModeModify {
+		cfg.TargetCodebase = o.TargetCodebase
+	}
+	stack := make(map[string]any, len(o.Stack))
+	for k, v := range o.Stack {
+		stack[k] = v
+	}
+	if err := cfg.ApplyStackOverrides(stack, o.Preferences); err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+// SetValues sets settings by dotted key in a YAML config document, keeping
+// its other settings and comments, and returns the updated document
+func SetValues(data []byte, values []KeyValue) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping of settings at the top level")
+	}
+
+	for _, kv := range values {
+		var value yaml.Node
+		if err := value.Encode(kv.Value); err != nil {
+			return nil, fmt.Errorf("%s: %w", kv.Key, err)
+		}
+		parts := strings.Split(kv.Key, ".")
+		node := root
+		for _, part := range parts[:len(parts)-1] {
+			i := mappingIndex(node, part)
+			if i < 0 {
+				node.Content = append(node.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part},
+					&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+				i = len(node.Content) - 2
+			}
+			if child := node.Content[i+1]; child.Kind != yaml.MappingNode {
+				// Replace an empty or scalar value with a mapping
+				node.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", LineComment: child.LineComment}
+			}
+			node = node.Content[i+1]
+		}
+
+		last := parts[len(parts)-1]
+		if i := mappingIndex(node, last); i >= 0 {
+			value.LineComment = node.Content[i+1].LineComment
+			node.Content[i+1] = &value
+		} else {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: last}, &value)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// StackOptions returns the documented values of a stack key, preceded by
+// current when it is not one of them (including "" for unset)
+func StackOptions(key, current string) []string {
+	return withCurrent(StackValues[key], current)
+}
+
+// PreferenceOptions returns the documented values of a preferences key,
+// preceded by current when it is not one of them
+func PreferenceOptions(key, current string) []string {
+	return withCurrent(PreferenceValues[key], current)
+}
+
+func withCurrent(values []string, current string) []string {
+	if contains(values, current) {
+		return values
+	}
+	return append([]string{current}, values...)
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetValuesKeepsComments(t *testing.T) {
+	data := []byte(`# project config
+output_dir: ./out # where outputs go
+stack:
+  database: postgres # main db
+`)
+	got, err := SetValues(data, []KeyValue{
+		{"persona", "production"},
+		{"stack.database", "mongodb"},
+		{"preferences.stateless", true},
+	})
+	if err != nil {
+		t.Fatalf("SetValues() error = %v", err)
+	}
+
+	want := `# project config
+output_dir: ./out # where outputs go
+stack:
+  database: mongodb # main db
+persona: production
+preferences:
+  stateless: true
+`
+	if string(got) != want {
+		t.Errorf("SetValues() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInitOptionsValidate(t *testing.T) {
+	o := InitOptionsFrom(Default())
+	if err := o.Validate(); err != nil {
+		t.Fatalf("Validate() of the defaults error = %v", err)
+	}
+
+	o.Persona = "lavish"
+	o.Preferences["api_style"] = "soap"
+	err := o.Validate()
+	if err == nil {
+		t.Fatal("Validate() succeeded with an invalid persona and api_style")
+	}
+	for _, want := range []string{"lavish", "soap"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestInitOptionsValuesTarget(t *testing.T) {
+	o := InitOptionsFrom(Default())
+	o.TargetCodebase = "./service"
+	for _, kv := range o.Values() {
+		if kv.Key == "target_codebase" {
+			t.Errorf("Values() in create mode includes target_codebase")
+		}
+	}
+}
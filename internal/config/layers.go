@@ -0,0 +1,317 @@
+// layers.go merges the config layers (built-in defaults, preset, user
+// global config, project config, environment, flags) and records where each
+// value came from.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Origins of config values. Files are named by their path, presets as
+// "preset:<name>", environment variables as "env:<NAME>" and settings
+// detected in the target codebase as "detected:<file>".
+const (
+	OriginDefault    = "default"
+	OriginFlag       = "flag"
+	OriginResolution = "resolution" // Chosen when resolving a PRD-vs-config conflict
+)
+
+// valueOrigin is the layer a value came from and its line in that layer
+type valueOrigin struct {
+	source string
+	line   int // 0 unless the layer is a file
+}
+
+// configLayer is one source of settings, lowest precedence first
+type configLayer struct {
+	origin string
+	node   *yaml.Node // Top-level mapping; nil for an empty document
+	file   bool       // Lines refer to the file named by origin
+}
+
+// parseLayer parses a YAML document into a layer
+func parseLayer(origin string, data []byte) (configLayer, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return configLayer{}, err
+	}
+	l := configLayer{origin: origin}
+	if len(doc.Content) == 0 {
+		return l, nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return configLayer{}, fmt.Errorf("expected a mapping of settings at the top level")
+	}
+	l.node = doc.Content[0]
+	return l, nil
+}
+
+// encodeLayer builds a layer from a value, such as the built-in defaults
+func encodeLayer(origin string, v any) (configLayer, error) {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return configLayer{}, err
+	}
+	return configLayer{origin: origin, node: &node}, nil
+}
+
+// has reports whether the layer sets the top-level key
+func (l configLayer) has(key string) bool {
+	return l.node != nil && mappingIndex(l.node, key) >= 0
+}
+
+// without returns the layer with a top-level key removed
+func (l configLayer) without(key string) configLayer {
+	if !l.has(key) {
+		return l
+	}
+	i := mappingIndex(l.node, key)
+	node := *l.node
+	node.Content = append(append([]*yaml.Node{}, l.node.Content[:i]...), l.node.Content[i+2:]...)
+	return configLayer{origin: l.origin, node: &node, file: l.file}
+}
+
+// only returns the layer with just a top-level key
+func (l configLayer) only(key string) configLayer {
+	if !l.has(key) {
+		return configLayer{origin: l.origin, file: l.file}
+	}
+	i := mappingIndex(l.node, key)
+	node := *l.node
+	node.Content = []*yaml.Node{l.node.Content[i], l.node.Content[i+1]}
+	return configLayer{origin: l.origin, node: &node, file: l.file}
+}
+
+// scalar returns the string value of a top-level key
+func (l configLayer) scalar(key string) string {
+	if !l.has(key) {
+		return ""
+	}
+	if v := l.node.Content[mappingIndex(l.node, key)+1]; v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+// mergeLayers deep-merges the layers in order and decodes the result.
+// Mappings merge key by key; scalars, lists and empty mappings replace.
+func mergeLayers(layers []configLayer) (*Config, error) {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	origins := map[string]valueOrigin{}
+	for _, l := range layers {
+		if l.node != nil {
+			mergeNode(merged, l.node, "", l, origins)
+		}
+	}
+
+	var cfg Config
+	if err := merged.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	cfg.origins = origins
+	return &cfg, nil
+}
+
+// mergeNode overlays the mapping src onto dst, recording the origin of
+// every value src sets
+func mergeNode(dst, src *yaml.Node, prefix string, l configLayer, origins map[string]valueOrigin) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		path := joinKey(prefix, key.Value)
+		if j := mappingIndex(dst, key.Value); j >= 0 {
+			if isNonEmptyMapping(dst.Content[j+1]) && isNonEmptyMapping(value) {
+				mergeNode(dst.Content[j+1], value, path, l, origins)
+				continue
+			}
+			dst.Content[j+1] = cloneNode(value)
+		} else {
+			dst.Content = append(dst.Content, cloneNode(key), cloneNode(value))
+		}
+		for k := range origins {
+			if k == path || strings.HasPrefix(k, path+".") {
+				delete(origins, k)
+			}
+		}
+		recordOrigins(value, path, l, origins)
+	}
+}
+
+// cloneNode deep-copies a node, so later layers merging into the merged
+// tree never change the nodes of the layer it came from
+func cloneNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	if n.Content != nil {
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content {
+			c.Content[i] = cloneNode(child)
+		}
+	}
+	return &c
+}
+
+func recordOrigins(node *yaml.Node, path string, l configLayer, origins map[string]valueOrigin) {
+	if !isNonEmptyMapping(node) {
+		o := valueOrigin{source: l.origin}
+		if l.file {
+			o.line = node.Line
+		}
+		origins[path] = o
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		recordOrigins(node.Content[i+1], joinKey(path, node.Content[i].Value), l, origins)
+	}
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func isNonEmptyMapping(node *yaml.Node) bool {
+	return node.Kind == yaml.MappingNode && len(node.Content) > 0
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// configFiles returns the config files to layer, lowest precedence first:
+// the user's global config, then the explicit path or the project config
+func configFiles(path string) ([]string, error) {
+	var files []string
+	global := filepath.Join(os.Getenv("HOME"), ".pagent", "config.yaml")
+	if _, err := os.Stat(global); err == nil {
+		files = append(files, global)
+	}
+
+	project := path
+	if project == "" {
+		for _, loc := range []string{".pagent/config.yaml", ".pagent/config.yml"} {
+			if _, err := os.Stat(loc); err == nil {
+				project = loc
+				break
+			}
+		}
+	} else if _, err := os.Stat(project); err != nil {
+		return nil, err
+	}
+	if project != "" && !sameFile(project, global) {
+		files = append(files, project)
+	}
+	return files, nil
+}
+
+func sameFile(a, b string) bool {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	ib, err := os.Stat(b)
+	return err == nil && os.SameFile(ia, ib)
+}
+
+// Origin returns where the value at a dotted key (e.g. "stack.database")
+// came from. Keys inside a value set as a whole report that value's origin.
+func (c *Config) Origin(key string) string {
+	return c.origin(key).source
+}
+
+// Location returns the origin of a key with its line when it was set in a
+// file, e.g. ".pagent/config.yaml:12"
+func (c *Config) Location(key string) string {
+	o := c.origin(key)
+	if o.line > 0 {
+		return fmt.Sprintf("%s:%d", o.source, o.line)
+	}
+	return o.source
+}
+
+func (c *Config) origin(key string) valueOrigin {
+	for k := key; k != ""; {
+		if o, ok := c.origins[k]; ok {
+			return o
+		}
+		i := strings.LastIndex(k, ".")
+		if i < 0 {
+			break
+		}
+		k = k[:i]
+	}
+	return valueOrigin{source: OriginDefault}
+}
+
+// setOrigin records the origin of a value set after the layers were merged
+func (c *Config) setOrigin(key, origin string) {
+	if c.origins == nil {
+		c.origins = map[string]valueOrigin{}
+	}
+	for k := range c.origins {
+		if strings.HasPrefix(k, key+".") {
+			delete(c.origins, k)
+		}
+	}
+	c.origins[key] = valueOrigin{source: origin}
+}
+
+// Setting is one effective config value
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Origin string `json:"origin"`
+}
+
+// Settings flattens the config into dotted keys in file order, each with
+// the layer it came from
+func (c *Config) Settings() ([]Setting, error) {
+	var node yaml.Node
+	if err := node.Encode(c); err != nil {
+		return nil, err
+	}
+	var settings []Setting
+	var walk func(n *yaml.Node, key string)
+	walk = func(n *yaml.Node, key string) {
+		if key == "" || isNonEmptyMapping(n) {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				walk(n.Content[i+1], joinKey(key, n.Content[i].Value))
+			}
+			return
+		}
+		settings = append(settings, Setting{Key: key, Value: formatValue(n), Origin: c.Origin(key)})
+	}
+	walk(&node, "")
+	return settings, nil
+}
+
+// formatValue renders a leaf value on one line
+func formatValue(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return n.Value
+	case yaml.SequenceNode:
+		items := make([]string, len(n.Content))
+		for i, item := range n.Content {
+			items[i] = formatValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case yaml.MappingNode:
+		pairs := make([]string, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			pairs = append(pairs, n.Content[i].Value+": "+formatValue(n.Content[i+1]))
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMergesLayers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	global := filepath.Join(home, ".pagent", "config.yaml")
+	writeFile(t, global, `
+persona: production
+stack:
+  cloud: gcp
+preferences:
+  language: python
+mcp_servers:
+  docs:
+    command: docs-mcp
+    args: [--readonly]
+`)
+	project := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, project, `
+stack:
+  database: mongodb
+mcp_servers:
+  docs:
+    command: docs-mcp-v2
+`)
+	t.Setenv("PAGENT_OUTPUT_DIR", "/from/env")
+
+	cfg, err := Load(project)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// A partial stack keeps the other defaults instead of replacing them
+	want := DefaultStack()
+	want.Cloud, want.Database = "gcp", "mongodb"
+	if cfg.Stack.Cloud != want.Cloud || cfg.Stack.Database != want.Database || cfg.Stack.Cache != want.Cache {
+		t.Errorf("Stack = %+v, want defaults with cloud and database overridden", cfg.Stack)
+	}
+	if !cfg.Preferences.Containerized || cfg.Preferences.Language != "python" {
+		t.Errorf("Preferences = %+v, want defaults with language overridden", cfg.Preferences)
+	}
+	docs := cfg.MCPServers["docs"]
+	if docs.Command != "docs-mcp-v2" || len(docs.Args) != 1 {
+		t.Errorf("mcp_servers.docs = %+v, want the project command with the global args", docs)
+	}
+	if len(cfg.Agents) != len(Default().Agents) {
+		t.Errorf("Agents = %v, want the default agents", cfg.GetAgentNames())
+	}
+
+	origins := map[string]string{
+		"persona":                  global,
+		"stack.cloud":              global,
+		"stack.database":           project,
+		"stack.cache":              OriginDefault,
+		"mcp_servers.docs.command": project,
+		"mcp_servers.docs.args":    global,
+		"output_dir":               "env:PAGENT_OUTPUT_DIR",
+		"agents.qa.output":         OriginDefault,
+	}
+	for key, want := range origins {
+		if got := cfg.Origin(key); got != want {
+			t.Errorf("Origin(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadWithoutFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Timeout != 0 || cfg.Persona != PersonaBalanced || len(cfg.Agents) != len(Default().Agents) {
+		t.Errorf("Load() = %+v, want the built-in defaults", cfg)
+	}
+}
+
+func TestApplyRunOptionsOrigins(t *testing.T) {
+	cfg := Default()
+	opts := RunOptions{
+		Persona:      PersonaMinimal,
+		Timeout:      cfg.Timeout,
+		Stack:        map[string]any{"database": "none"},
+		Architecture: ArchitectureStateless,
+	}
+	if err := cfg.ApplyRunOptions(opts); err != nil {
+		t.Fatalf("ApplyRunOptions() error = %v", err)
+	}
+	if cfg.Persona != PersonaMinimal || cfg.Stack.Database != "none" || !cfg.Preferences.Stateless {
+		t.Errorf("ApplyRunOptions() did not apply the options: %+v", cfg)
+	}
+	for _, key := range []string{"persona", "stack.database", "preferences.stateless"} {
+		if got := cfg.Origin(key); got != OriginFlag {
+			t.Errorf("Origin(%q) = %q, want flag", key, got)
+		}
+	}
+	for _, key := range []string{"output_dir", "timeout"} {
+		if got := cfg.Origin(key); got != OriginDefault {
+			t.Errorf("Origin(%q) = %q, want default for options left unset", key, got)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// mcp.go defines settings for the MCP server.
+package config
+
+import "path/filepath"
+
+// MCPConfig configures the MCP server
+type MCPConfig struct {
+	// Roots are the directories MCP clients may read input from and write
+	// output to. Empty allows any path the server user can access.
+	Roots []string `yaml:"roots"`
+
+	// SubjectWorkspaces, when set, gives every OAuth subject an isolated
+	// workspace directory below it, used instead of Roots for that subject
+	SubjectWorkspaces string `yaml:"subject_workspaces"`
+
+	// Policy is a file mapping OAuth claims to the tools, agents, personas
+	// and roots each caller may use. Empty allows every authenticated caller
+	// everything.
+	Policy string `yaml:"policy"`
+
+	// ConfigPaths are the config files clients connected over HTTP may pass
+	// as config_path. A config can run commands (validation commands, stdio
+	// MCP servers), so any other file is rejected.
+	ConfigPaths []string `yaml:"config_paths"`
+}
+
+// EffectiveRoots returns the workspace roots with ~ expanded
+func (m MCPConfig) EffectiveRoots() []string {
+	roots := make([]string, 0, len(m.Roots))
+	for _, r := range m.Roots {
+		roots = append(roots, expandHome(r))
+	}
+	return roots
+}
+
+// EffectiveSubjectWorkspaces returns the subject workspace directory with ~
+// expanded, or "" when per-subject workspaces are disabled
+func (m MCPConfig) EffectiveSubjectWorkspaces() string {
+	return expandHome(m.SubjectWorkspaces)
+}
+
+// AllowsConfigPath reports whether path is one of ConfigPaths, comparing
+// absolute paths with ~ expanded
+func (m MCPConfig) AllowsConfigPath(path string) bool {
+	abs, err := filepath.Abs(expandHome(path))
+	if err != nil {
+		return false
+	}
+	for _, p := range m.ConfigPaths {
+		if allowed, err := filepath.Abs(expandHome(p)); err == nil && allowed == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectivePolicy returns the policy file path with ~ expanded
+func (m MCPConfig) EffectivePolicy() string {
+	return expandHome(m.Policy)
+}
@@ -0,0 +1,122 @@
+// mcpservers.go defines the MCP servers made available to spawned agents.
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MCP server transports understood by the agent CLI
+const (
+	MCPServerStdio = "stdio"
+	MCPServerHTTP  = "http"
+	MCPServerSSE   = "sse"
+)
+
+// MCPServerConfig describes an MCP server an agent may use. Stdio servers
+// set command; http and sse servers set url.
+type MCPServerConfig struct {
+	Type    string            `yaml:"type" json:"type,omitempty"` // stdio (default), http, sse
+	Command string            `yaml:"command" json:"command,omitempty"`
+	Args    []string          `yaml:"args" json:"args,omitempty"`
+	Env     map[string]string `yaml:"env" json:"env,omitempty"`
+	URL     string            `yaml:"url" json:"url,omitempty"`
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"`
+	Tools   []string          `yaml:"tools" json:"-"` // Tools the agent may call (default: all)
+}
+
+// IsReference reports whether the entry only names a global server
+func (s MCPServerConfig) IsReference() bool {
+	return s.Command == "" && s.URL == "" && s.Type == ""
+}
+
+// EffectiveType returns the transport, defaulting to stdio
+func (s MCPServerConfig) EffectiveType() string {
+	if s.Type == "" {
+		return MCPServerStdio
+	}
+	return s.Type
+}
+
+// Validate checks that the server can be started or reached
+func (s MCPServerConfig) Validate() error {
+	switch s.EffectiveType() {
+	case MCPServerStdio:
+		if s.Command == "" {
+			return fmt.Errorf("command is required for stdio servers")
+		}
+		if s.URL != "" {
+			return fmt.Errorf("url is not used by stdio servers")
+		}
+	case MCPServerHTTP, MCPServerSSE:
+		if s.URL == "" {
+			return fmt.Errorf("url is required for %s servers", s.Type)
+		}
+		if s.Command != "" {
+			return fmt.Errorf("command is not used by %s servers", s.Type)
+		}
+	default:
+		return fmt.Errorf("invalid type %q (use: stdio, http, sse)", s.Type)
+	}
+	return nil
+}
+
+// AgentMCPServers returns the MCP servers of an agent. An agent with its own
+// mcp_servers gets exactly those, where an empty entry stands for the global
+// server of that name; other agents get the global mcp_servers.
+func (c *Config) AgentMCPServers(name string) map[string]MCPServerConfig {
+	agent, ok := c.Agents[name]
+	if !ok || agent.MCPServers == nil {
+		return c.MCPServers
+	}
+	servers := make(map[string]MCPServerConfig, len(agent.MCPServers))
+	for server, cfg := range agent.MCPServers {
+		if global, ok := c.MCPServers[server]; ok && cfg.IsReference() {
+			if cfg.Tools != nil {
+				global.Tools = cfg.Tools
+			}
+			cfg = global
+		}
+		servers[server] = cfg
+	}
+	return servers
+}
+
+// ValidateMCPServers checks the global and per-agent MCP servers
+func (c *Config) ValidateMCPServers() error {
+	for _, name := range sortedKeys(c.MCPServers) {
+		if err := c.MCPServers[name].Validate(); err != nil {
+			return fmt.Errorf("mcp_servers.%s: %w", name, err)
+		}
+	}
+	agents := make([]string, 0, len(c.Agents))
+	for name := range c.Agents {
+		agents = append(agents, name)
+	}
+	sort.Strings(agents)
+	for _, agent := range agents {
+		servers := c.Agents[agent].MCPServers
+		for _, name := range sortedKeys(servers) {
+			server := servers[name]
+			if server.IsReference() {
+				if _, ok := c.MCPServers[name]; !ok {
+					return fmt.Errorf("agents.%s.mcp_servers.%s: no global mcp_servers entry named %q", agent, name, name)
+				}
+				continue
+			}
+			if err := server.Validate(); err != nil {
+				return fmt.Errorf("agents.%s.mcp_servers.%s: %w", agent, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
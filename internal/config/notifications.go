@@ -0,0 +1,102 @@
+// notifications.go defines webhook notification settings.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/tuannvm/pagent/internal/events"
+)
+
+// Webhook payload formats
+const (
+	WebhookFormatJSON  = "json"  // Generic JSON payload (the lifecycle event)
+	WebhookFormatSlack = "slack" // Slack-compatible incoming webhook payload
+)
+
+// ValidWebhookFormats lists all valid webhook formats
+var ValidWebhookFormats = []string{WebhookFormatJSON, WebhookFormatSlack}
+
+// NotifiableEvents lists the events webhooks can subscribe to.
+// It is also the default filter when a webhook lists no events.
+var NotifiableEvents = []string{
+	string(events.RunStarted),
+	string(events.RunCompleted),
+	string(events.AgentFailed),
+	string(events.ApprovalNeeded),
+}
+
+// NotificationsConfig configures notifications sent while runs progress
+type NotificationsConfig struct {
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+}
+
+// WebhookConfig describes a single HTTP webhook target.
+// URL and Secret are expanded with environment variables (e.g. ${SLACK_WEBHOOK_URL})
+// so credentials don't need to be committed to the config file.
+type WebhookConfig struct {
+	Name       string            `yaml:"name"`        // Label used in error messages
+	URL        string            `yaml:"url"`         // Target endpoint (required)
+	Format     string            `yaml:"format"`      // json (default) or slack
+	Events     []string          `yaml:"events"`      // Event filter (default: NotifiableEvents)
+	Secret     string            `yaml:"secret"`      // HMAC-SHA256 signing key (optional)
+	Headers    map[string]string `yaml:"headers"`     // Extra request headers
+	MaxRetries *int              `yaml:"max_retries"` // Retries after the first attempt (default: 3; 0 disables retries)
+	Timeout    int               `yaml:"timeout"`     // Per-request timeout in seconds (default: 10)
+}
+
+// ExpandedURL returns the webhook URL with environment variables expanded
+func (w WebhookConfig) ExpandedURL() string {
+	return os.ExpandEnv(w.URL)
+}
+
+// ExpandedSecret returns the signing secret with environment variables expanded
+func (w WebhookConfig) ExpandedSecret() string {
+	return os.ExpandEnv(w.Secret)
+}
+
+// HasWebhooks returns true if any webhook targets are configured
+func (n NotificationsConfig) HasWebhooks() bool {
+	return len(n.Webhooks) > 0
+}
+
+// Validate checks webhook definitions for missing or invalid values
+func (n NotificationsConfig) Validate() error {
+	for i, w := range n.Webhooks {
+		label := w.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+		}
+
+		expanded := w.ExpandedURL()
+		if expanded == "" {
+			return fmt.Errorf("notifications webhook %s: url is required", label)
+		}
+		if u, err := url.Parse(expanded); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notifications webhook %s: invalid url %q (must be http or https)", label, w.URL)
+		}
+		if w.Format != "" && !contains(ValidWebhookFormats, w.Format) {
+			return fmt.Errorf("notifications webhook %s: invalid format %q: must be one of %v", label, w.Format, ValidWebhookFormats)
+		}
+		for _, e := range w.Events {
+			if !contains(NotifiableEvents, e) {
+				return fmt.Errorf("notifications webhook %s: unknown event %q: must be one of %v", label, e, NotifiableEvents)
+			}
+		}
+		if (w.MaxRetries != nil && *w.MaxRetries < 0) || w.Timeout < 0 {
+			return fmt.Errorf("notifications webhook %s: max_retries and timeout must not be negative", label)
+		}
+	}
+	return nil
+}
+
+// contains reports whether values includes v
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 // options.go provides shared option definitions for CLI and TUI.
 package config
 
+import "fmt"
+
 // Option represents a selectable option with value and label
 type Option struct {
 	Value       string
@@ -9,18 +11,30 @@ type Option struct {
 }
 
 // RunOptions contains all parameters for running agents.
-// This is the single source of truth used by both CLI and TUI.
+// This is the single source of truth used by both CLI and TUI; the JSON form
+// is what clients submit to the daemon.
 type RunOptions struct {
-	InputPath    string
-	Agents       []string
-	Persona      string
-	OutputDir    string
-	Sequential   bool
-	ResumeMode   string // "normal", "resume", "force"
-	Architecture string // "config", "stateless", "database"
-	Timeout      int
-	ConfigPath   string
-	Verbosity    string // "normal", "verbose", "quiet"
+	InputPath    string   `json:"input_path"`
+	Agents       []string `json:"agents,omitempty"`
+	Persona      string   `json:"persona,omitempty"`
+	Preset       string   `json:"preset,omitempty"` // Preset profile beneath the config file
+	OutputDir    string   `json:"output_dir,omitempty"`
+	Sequential   bool     `json:"sequential,omitempty"`
+	ResumeMode   string   `json:"resume_mode,omitempty"`  // "normal", "resume", "force"
+	Architecture string   `json:"architecture,omitempty"` // "config", "stateless", "database"
+	Timeout      int      `json:"timeout"`
+	ConfigPath   string   `json:"config_path,omitempty"`
+	Verbosity    string   `json:"verbosity,omitempty"`    // "normal", "verbose", "quiet"
+	EventFormat  string   `json:"event_format,omitempty"` // "" (disabled) or "ndjson"
+	MetricsAddr  string   `json:"metrics_addr,omitempty"` // Serve Prometheus metrics on this address during the run
+	Resolve      string   `json:"resolve,omitempty"`      // PRD-vs-config stack conflicts: "prefer-prd", "prefer-config", "fail"; empty asks when interactive
+
+	// Overrides of config file settings; empty keeps the config value
+	Mode           string         `json:"mode,omitempty"`             // "create" or "modify"
+	TargetCodebase string         `json:"target_codebase,omitempty"`  // Existing codebase for modify mode
+	SpecsOutputDir string         `json:"specs_output_dir,omitempty"` // Directory for spec outputs
+	Stack          map[string]any `json:"stack,omitempty"`            // Stack fields by YAML key, e.g. {"database": "none"}
+	Preferences    map[string]any `json:"preferences,omitempty"`      // Preference fields by YAML key
 }
 
 // Shared option definitions - SINGLE SOURCE OF TRUTH
@@ -30,6 +44,11 @@ var PersonaOptions = []Option{
 	{Value: PersonaProduction, Label: "Production", Description: "Enterprise"},
 }
 
+var ModeOptions = []Option{
+	{Value: ModeCreate, Label: "Create", Description: "New codebase"},
+	{Value: ModeModify, Label: "Modify", Description: "Existing codebase"},
+}
+
 // VerbosityNormal, VerbosityVerbose, VerbosityQuiet are verbosity constants
 const (
 	VerbosityNormal  = "normal"
@@ -80,6 +99,24 @@ var ArchitectureOptions = []Option{
 	{Value: ArchitectureDatabase, Label: "Database", Description: "DB-backed"},
 }
 
+// ResolvePreferPRD, ResolvePreferConfig resolve every PRD-vs-config stack
+// conflict without asking. ResolveFail stops the run instead of letting it
+// go ahead with conflicts nobody resolved, for hosts that can't ask.
+const (
+	ResolvePreferPRD    = "prefer-prd"
+	ResolvePreferConfig = "prefer-config"
+	ResolveFail         = "fail"
+)
+
+var ResolveOptions = []Option{
+	{Value: "", Label: "Ask", Description: "Choose per conflict"},
+	{Value: ResolvePreferPRD, Label: "Prefer PRD", Description: "Use what the PRD asks for"},
+	{Value: ResolvePreferConfig, Label: "Prefer config", Description: "Keep the configured stack"},
+}
+
+// EventFormatNDJSON streams lifecycle events as newline-delimited JSON to stdout
+const EventFormatNDJSON = "ndjson"
+
 // DefaultRunOptions returns RunOptions with sensible defaults from config
 func DefaultRunOptions(cfg *Config) RunOptions {
 	if cfg == nil {
@@ -96,6 +133,47 @@ func DefaultRunOptions(cfg *Config) RunOptions {
 	}
 }
 
+// Validate checks the enumerated option values; empty values are allowed
+// and fall back to defaults. Only built-in personas are accepted.
+func (o RunOptions) Validate() error {
+	return o.ValidateFor(nil)
+}
+
+// ValidateFor is Validate accepting the personas defined in cfg as well
+func (o RunOptions) ValidateFor(cfg *Config) error {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if o.Persona != "" && !cfg.HasPersona(o.Persona) {
+		return fmt.Errorf("invalid persona %q: must be one of %v", o.Persona, cfg.PersonaNames())
+	}
+	if o.ResumeMode != "" && !validOption(ResumeModeOptions, o.ResumeMode) {
+		return fmt.Errorf("invalid resume mode %q: must be normal, resume or force", o.ResumeMode)
+	}
+	if o.Architecture != "" && !validOption(ArchitectureOptions, o.Architecture) {
+		return fmt.Errorf("invalid architecture %q: must be config, stateless or database", o.Architecture)
+	}
+	if o.Resolve != "" && o.Resolve != ResolveFail && !validOption(ResolveOptions, o.Resolve) {
+		return fmt.Errorf("invalid resolve strategy %q: must be prefer-prd, prefer-config or fail", o.Resolve)
+	}
+	if !IsValidMode(o.Mode) {
+		return fmt.Errorf("invalid mode %q: must be one of %v", o.Mode, ValidModes)
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %d: must be 0 (no limit) or positive", o.Timeout)
+	}
+	return nil
+}
+
+func validOption(options []Option, value string) bool {
+	for _, o := range options {
+		if o.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
 // IsVerbose returns true if verbosity is set to verbose
 func (o RunOptions) IsVerbose() bool {
 	return o.Verbosity == VerbosityVerbose
@@ -105,3 +183,76 @@ func (o RunOptions) IsVerbose() bool {
 func (o RunOptions) IsQuiet() bool {
 	return o.Verbosity == VerbosityQuiet
 }
+
+// ApplyRunOptions applies the options of a run on top of the config, as
+// its highest-precedence layer
+func (c *Config) ApplyRunOptions(opts RunOptions) error {
+	if err := opts.ValidateFor(c); err != nil {
+		return err
+	}
+
+	// Override output directory if specified
+	c.applyFlag("output_dir", &c.OutputDir, opts.OutputDir)
+
+	if c.Timeout != opts.Timeout {
+		c.Timeout = opts.Timeout
+		c.setOrigin("timeout", OriginFlag)
+	}
+
+	// Handle resume mode
+	switch opts.ResumeMode {
+	case ResumeModeResume:
+		c.ResumeMode = true
+		c.ForceMode = false
+	case ResumeModeForce:
+		c.ResumeMode = false
+		c.ForceMode = true
+	default:
+		c.ResumeMode = false
+		c.ForceMode = false
+	}
+
+	// Override persona if specified
+	c.applyFlag("persona", &c.Persona, opts.Persona)
+	c.ApplyPersona()
+
+	// Override mode and its directories
+	c.applyFlag("mode", &c.Mode, opts.Mode)
+	c.applyFlag("target_codebase", &c.TargetCodebase, opts.TargetCodebase)
+	c.applyFlag("specs_output_dir", &c.SpecsOutputDir, opts.SpecsOutputDir)
+	if err := c.ValidateMode(); err != nil {
+		return err
+	}
+
+	// Override individual stack and preference fields
+	if err := c.ApplyStackOverrides(opts.Stack, opts.Preferences); err != nil {
+		return err
+	}
+	for key := range opts.Stack {
+		c.setOrigin("stack."+key, OriginFlag)
+	}
+	for key := range opts.Preferences {
+		c.setOrigin("preferences."+key, OriginFlag)
+	}
+
+	// Override architecture preference (after the preference overrides)
+	switch opts.Architecture {
+	case ArchitectureStateless:
+		c.Preferences.Stateless = true
+		c.setOrigin("preferences.stateless", OriginFlag)
+	case ArchitectureDatabase:
+		c.Preferences.Stateless = false
+		c.setOrigin("preferences.stateless", OriginFlag)
+		// "config" means use whatever is in config
+	}
+
+	return nil
+}
+
+// applyFlag sets a string field from a run option when one was given
+func (c *Config) applyFlag(key string, field *string, value string) {
+	if value != "" && value != *field {
+		*field = value
+		c.setOrigin(key, OriginFlag)
+	}
+}
@@ -109,3 +109,30 @@ func TestArchitectureOptions(t *testing.T) {
 		t.Errorf("expected 3 architecture options, got %d", len(ArchitectureOptions))
 	}
 }
+
+func TestRunOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    RunOptions
+		wantErr bool
+	}{
+		{"empty", RunOptions{}, false},
+		{"defaults", DefaultRunOptions(nil), false},
+		{"all set", RunOptions{Persona: PersonaMinimal, ResumeMode: ResumeModeForce, Architecture: ArchitectureStateless, Mode: ModeModify, Timeout: 60}, false},
+		{"bad persona", RunOptions{Persona: "maximal"}, true},
+		{"bad resume mode", RunOptions{ResumeMode: "skip"}, true},
+		{"bad architecture", RunOptions{Architecture: "serverless"}, true},
+		{"bad mode", RunOptions{Mode: "rewrite"}, true},
+		{"negative timeout", RunOptions{Timeout: -1}, true},
+		{"fail resolve", RunOptions{Resolve: ResolveFail}, false},
+		{"bad resolve", RunOptions{Resolve: "prefer-both"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tuannvm/pagent/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ApplyStackOverrides sets the stack and preference fields named in stack
+// and preferences, keyed like the config file (e.g. "database", "api_style").
+// Fields not named keep their current value; unknown keys are an error.
+func (c *Config) ApplyStackOverrides(stack, preferences map[string]any) error {
+	if err := overlay(&c.Stack, stack); err != nil {
+		return fmt.Errorf("invalid stack override: %w", err)
+	}
+	if err := overlay(&c.Preferences, preferences); err != nil {
+		return fmt.Errorf("invalid preferences override: %w", err)
+	}
+	return nil
+}
+
+// overlay decodes values onto dst through YAML, so only the given keys change
+func overlay(dst any, values map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(dst)
+}
+
+// ApplyStackResolution sets the stack fields of the resolved conflicts to
+// their resolution and records the resulting stack as the effective one
+func (c *Config) ApplyStackResolution(r *types.StackResolution) error {
+	if r == nil {
+		return nil
+	}
+	for _, conflict := range r.Conflicts {
+		if !conflict.Resolved {
+			continue
+		}
+		if err := c.ApplyStackOverrides(map[string]any{conflict.Category: conflict.Resolution}, nil); err != nil {
+			return err
+		}
+		c.setOrigin("stack."+conflict.Category, OriginResolution)
+	}
+	stack := c.Stack
+	r.EffectiveStack = &stack
+	return nil
+}
@@ -0,0 +1,190 @@
+// personas.go holds the personas defined under personas: in the config.
+// Each builds on a built-in persona, whose template behavior it inherits,
+// and can change preferences and the agents run by default.
+package config
+
+import (
+	"strings"
+
+	"github.com/tuannvm/pagent/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// PersonaConfig defines a persona beyond the built-in ones
+type PersonaConfig struct {
+	Label       string         `yaml:"label"`       // Display name (default: the persona name)
+	Description string         `yaml:"description"` // Shown in the TUI and given to agents
+	Base        string         `yaml:"base"`        // Built-in persona templates follow: minimal, balanced, production (default: balanced)
+	Preferences map[string]any `yaml:"preferences"` // Preference overrides, keyed like preferences:
+	Agents      []string       `yaml:"agents"`      // Agents run when none are selected (default: all)
+}
+
+// personaOverride is a preference value replaced by a persona, kept so
+// that choosing another persona can restore it
+type personaOverride struct {
+	value  any
+	origin valueOrigin
+	had    bool // The key had an origin of its own
+}
+
+// HasPersona reports whether name is a built-in or configured persona
+func (c *Config) HasPersona(name string) bool {
+	_, ok := c.Personas[name]
+	return ok || IsValidPersona(name)
+}
+
+// PersonaNames returns the built-in personas followed by the configured
+// ones in name order
+func (c *Config) PersonaNames() []string {
+	names := append([]string{}, ValidPersonas...)
+	for _, name := range sortedKeys(c.Personas) {
+		if !IsValidPersona(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// PersonaChoices returns PersonaOptions followed by the configured personas
+func (c *Config) PersonaChoices() []Option {
+	choices := append([]Option{}, PersonaOptions...)
+	for _, name := range c.PersonaNames()[len(ValidPersonas):] {
+		traits := c.PersonaTraits(name)
+		choices = append(choices, Option{Value: name, Label: traits.Label, Description: traits.Description})
+	}
+	return choices
+}
+
+// PersonaTraits describes a persona for prompt templates
+func (c *Config) PersonaTraits(name string) types.PersonaTraits {
+	p, ok := c.Personas[name]
+	if !ok || IsValidPersona(name) {
+		traits := types.PersonaTraits{Name: name, Label: name, Base: name}
+		for _, o := range PersonaOptions {
+			if o.Value == name {
+				traits.Label, traits.Description = o.Label, o.Description
+			}
+		}
+		return traits
+	}
+	traits := types.PersonaTraits{
+		Name:        name,
+		Label:       p.Label,
+		Description: p.Description,
+		Base:        p.Base,
+		Custom:      true,
+	}
+	if traits.Label == "" {
+		traits.Label = name
+	}
+	if traits.Base == "" {
+		traits.Base = PersonaBalanced
+	}
+	return traits
+}
+
+// PersonaAgents returns the agents the persona runs when none are
+// selected: its agents: list, or every agent
+func (c *Config) PersonaAgents() []string {
+	if p, ok := c.Personas[c.Persona]; ok && len(p.Agents) > 0 {
+		return p.Agents
+	}
+	return c.GetAgentNames()
+}
+
+// ApplyPersona applies the preference overrides of the current persona,
+// first restoring those of a persona applied before. Overrides replace
+// values from the defaults and presets only, so preferences set in a
+// config file, the environment or a flag win. Invalid overrides are left
+// for Validate to report.
+func (c *Config) ApplyPersona() {
+	for key, o := range c.personaOverrides {
+		_ = c.ApplyStackOverrides(nil, map[string]any{key: o.value})
+		if o.had {
+			c.origins["preferences."+key] = o.origin
+		} else {
+			delete(c.origins, "preferences."+key)
+		}
+	}
+	c.personaOverrides = nil
+
+	p, ok := c.Personas[c.Persona]
+	if !ok || IsValidPersona(c.Persona) {
+		return
+	}
+	current := c.preferenceMap()
+	for _, key := range sortedKeys(p.Preferences) {
+		dotted := "preferences." + key
+		origin := c.Origin(dotted)
+		if origin != OriginDefault && !strings.HasPrefix(origin, "preset:") {
+			continue
+		}
+		if v, ok := p.Preferences[key].(string); ok && PreferenceValues[key] != nil && !contains(PreferenceValues[key], v) {
+			continue
+		}
+		if err := c.ApplyStackOverrides(nil, map[string]any{key: p.Preferences[key]}); err != nil {
+			continue
+		}
+		if c.personaOverrides == nil {
+			c.personaOverrides = map[string]personaOverride{}
+		}
+		prev, had := c.origins[dotted]
+		c.personaOverrides[key] = personaOverride{value: current[key], origin: prev, had: had}
+		c.setOrigin(dotted, "persona:"+c.Persona)
+	}
+}
+
+// preferenceMap returns the preferences keyed like the config file
+func (c *Config) preferenceMap() map[string]any {
+	var m map[string]any
+	data, err := yaml.Marshal(c.Preferences)
+	if err == nil {
+		_ = yaml.Unmarshal(data, &m)
+	}
+	return m
+}
+
+// personaProblems checks the configured personas
+func (c *Config) personaProblems(add func(key, format string, args ...any)) {
+	for _, name := range sortedKeys(c.Personas) {
+		p := c.Personas[name]
+		key := "personas." + name
+		if IsValidPersona(name) {
+			add(key, "%s redefines the built-in persona %q", key, name)
+			continue
+		}
+		if p.Base != "" && !IsValidPersona(p.Base) {
+			add(key+".base", "%s.base must be one of %s, got %q", key, strings.Join(ValidPersonas, ", "), p.Base)
+		}
+
+		// Apply the overrides to a copy to check their keys and types
+		prefs := Config{Preferences: c.Preferences}
+		known := c.preferenceMap()
+		for _, pref := range sortedKeys(p.Preferences) {
+			current, ok := known[pref]
+			if !ok {
+				add(key+".preferences", "%s.preferences: unknown preference %q", key, pref)
+				continue
+			}
+			if err := prefs.ApplyStackOverrides(nil, map[string]any{pref: p.Preferences[pref]}); err != nil {
+				want := "a string"
+				if _, isBool := current.(bool); isBool {
+					want = "true or false"
+				}
+				add(key+".preferences", "%s.preferences.%s must be %s, got %v", key, pref, want, p.Preferences[pref])
+				continue
+			}
+			if allowed, ok := PreferenceValues[pref]; ok {
+				if v := prefs.preferenceValues()[pref]; !contains(allowed, v) {
+					add(key+".preferences", "%s.preferences.%s must be one of %s, got %q", key, pref, strings.Join(allowed, ", "), v)
+				}
+			}
+		}
+
+		for _, agent := range p.Agents {
+			if _, ok := c.Agents[agent]; !ok {
+				add(key+".agents", "%s.agents: unknown agent %q (available: %s)", key, agent, strings.Join(c.GetAgentNames(), ", "))
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const personasYAML = `persona: startup
+preferences:
+  api_style: grpc
+personas:
+  startup:
+    label: Startup
+    description: Ship in a week
+    base: minimal
+    preferences:
+      api_style: graphql
+      testing_depth: none
+      containerized: false
+    agents: [architect, implementer]
+`
+
+func TestLoadPersonas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(personasYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// The persona sets defaults but not what the file sets itself
+	if cfg.Preferences.TestingDepth != "none" || cfg.Preferences.Containerized {
+		t.Errorf("testing_depth, containerized = %q, %v, want the persona's", cfg.Preferences.TestingDepth, cfg.Preferences.Containerized)
+	}
+	if cfg.Preferences.APIStyle != "grpc" {
+		t.Errorf("api_style = %q, want grpc from the file", cfg.Preferences.APIStyle)
+	}
+	if got := cfg.Origin("preferences.testing_depth"); got != "persona:startup" {
+		t.Errorf("Origin(preferences.testing_depth) = %q", got)
+	}
+	if got := cfg.PersonaAgents(); strings.Join(got, ",") != "architect,implementer" {
+		t.Errorf("PersonaAgents() = %v", got)
+	}
+
+	traits := cfg.PersonaTraits("startup")
+	if !traits.Custom || traits.Label != "Startup" || traits.Base != PersonaMinimal {
+		t.Errorf("PersonaTraits(startup) = %+v", traits)
+	}
+	if traits := cfg.PersonaTraits(PersonaProduction); traits.Custom || traits.Base != PersonaProduction {
+		t.Errorf("PersonaTraits(production) = %+v", traits)
+	}
+	if names := cfg.PersonaNames(); len(names) != 4 || names[3] != "startup" {
+		t.Errorf("PersonaNames() = %v", names)
+	}
+
+	// Choosing a built-in persona restores the replaced values
+	opts := DefaultRunOptions(cfg)
+	opts.Persona = PersonaProduction
+	if err := cfg.ApplyRunOptions(opts); err != nil {
+		t.Fatalf("ApplyRunOptions() error = %v", err)
+	}
+	defaults := DefaultPreferences()
+	if cfg.Preferences.TestingDepth != defaults.TestingDepth || cfg.Preferences.Containerized != defaults.Containerized {
+		t.Errorf("testing_depth, containerized = %q, %v, want the defaults", cfg.Preferences.TestingDepth, cfg.Preferences.Containerized)
+	}
+	if got := cfg.Origin("preferences.testing_depth"); got != OriginDefault {
+		t.Errorf("Origin(preferences.testing_depth) = %q, want default", got)
+	}
+	if got := cfg.PersonaAgents(); len(got) != len(cfg.Agents) {
+		t.Errorf("PersonaAgents() = %v, want every agent", got)
+	}
+}
+
+func TestRunOptionsPersona(t *testing.T) {
+	cfg := Default()
+	cfg.Personas = map[string]PersonaConfig{"startup": {Base: PersonaMinimal}}
+
+	opts := RunOptions{Persona: "startup"}
+	if err := opts.Validate(); err == nil {
+		t.Error("Validate() accepted a persona that is not built in")
+	}
+	if err := opts.ValidateFor(cfg); err != nil {
+		t.Errorf("ValidateFor() error = %v", err)
+	}
+	if err := (RunOptions{Persona: "maximal"}).ValidateFor(cfg); err == nil {
+		t.Error("ValidateFor() accepted an unknown persona")
+	}
+}
+
+func TestValidatePersonas(t *testing.T) {
+	cfg := Default()
+	cfg.Personas = map[string]PersonaConfig{
+		"minimal": {Description: "redefined"},
+		"startup": {
+			Base:        "tiny",
+			Preferences: map[string]any{"api_style": "soap", "stateless": "maybe", "colour": "blue"},
+			Agents:      []string{"architect", "designer"},
+		},
+	}
+
+	var verr *ValidationError
+	if !errors.As(cfg.Validate(), &verr) {
+		t.Fatal("Validate() should return a *ValidationError")
+	}
+	got := strings.Join(verr.Problems, "\n")
+	for _, want := range []string{
+		`personas.minimal redefines the built-in persona "minimal"`,
+		`personas.startup.base must be one of minimal, balanced, production, got "tiny"`,
+		`personas.startup.preferences.api_style must be one of rest, graphql, grpc, got "soap"`,
+		`personas.startup.preferences.stateless must be true or false, got maybe`,
+		`personas.startup.preferences: unknown preference "colour"`,
+		`personas.startup.agents: unknown agent "designer"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("problems do not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestSchemaPersonas(t *testing.T) {
+	s := Schema()
+	if persona := s.Properties["persona"]; persona.Enum != nil || len(persona.Examples) != 3 {
+		t.Errorf("persona = %+v, want examples without an enum", persona)
+	}
+	entry := s.Properties["personas"].AdditionalProperties.(*JSONSchema)
+	if len(entry.Properties["base"].Enum) != 3 {
+		t.Errorf("personas.*.base = %+v, want an enum", entry.Properties["base"])
+	}
+	prefs := entry.Properties["preferences"]
+	if apiStyle := prefs.Properties["api_style"]; apiStyle == nil || len(apiStyle.Enum) != 3 || apiStyle.Default != nil {
+		t.Errorf("personas.*.preferences.api_style = %+v, want an enum without a default", apiStyle)
+	}
+}
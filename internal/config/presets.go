@@ -0,0 +1,113 @@
+// presets.go loads preset profiles: named bundles of persona, stack,
+// preferences and agent overrides that a config is layered on top of.
+package config
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed presets/*.yaml
+var embeddedPresets embed.FS
+
+// Preset sources
+const (
+	PresetBuiltin = "builtin"
+	PresetUser    = "user"
+)
+
+// Preset is a named config layer applied beneath the config files
+type Preset struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Source      string `json:"source"`         // builtin or user
+	Path        string `json:"path,omitempty"` // File of a user preset
+
+	data []byte
+}
+
+// PresetDir returns the directory of user-defined presets
+func PresetDir() string {
+	return expandHome(filepath.Join("~", ".pagent", "presets"))
+}
+
+// LoadPreset returns the preset called name. User presets in PresetDir take
+// precedence over built-in presets of the same name.
+func LoadPreset(name string) (*Preset, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid preset name %q", name)
+	}
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(PresetDir(), name+ext)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read preset %s: %w", name, err)
+		}
+		return parsePreset(name, PresetUser, path, data)
+	}
+	data, err := embeddedPresets.ReadFile("presets/" + name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("unknown preset %q (see 'pagent presets list')", name)
+	}
+	return parsePreset(name, PresetBuiltin, "", data)
+}
+
+// ListPresets returns the built-in and user presets sorted by name
+func ListPresets() ([]*Preset, error) {
+	names := map[string]bool{}
+	builtin, err := fs.Glob(embeddedPresets, "presets/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	user, err := filepath.Glob(filepath.Join(PresetDir(), "*.y*ml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range append(builtin, user...) {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		names[name] = true
+	}
+
+	presets := make([]*Preset, 0, len(names))
+	for name := range names {
+		p, err := LoadPreset(name)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, p)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets, nil
+}
+
+// YAML returns the preset's settings as written in its file
+func (p *Preset) YAML() string {
+	return string(p.data)
+}
+
+func parsePreset(name, source, path string, data []byte) (*Preset, error) {
+	var header struct {
+		Description string `yaml:"description"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("invalid preset %s: %w", name, err)
+	}
+	return &Preset{
+		Name:        name,
+		Description: header.Description,
+		Source:      source,
+		Path:        path,
+		data:        data,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadWithPresetLayersConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+preset: python-ml
+stack:
+  database: mongodb
+preferences:
+  testing_depth: e2e
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Preset != "python-ml" {
+		t.Errorf("Preset = %q, want python-ml", cfg.Preset)
+	}
+	if cfg.Stack.Database != "mongodb" || cfg.Stack.DataEngine != "spark" {
+		t.Errorf("Stack = %+v, want the preset stack with database overridden", cfg.Stack)
+	}
+	if cfg.Preferences.Language != "python" || cfg.Preferences.TestingDepth != "e2e" {
+		t.Errorf("Preferences = %+v, want the preset preferences with testing_depth overridden", cfg.Preferences)
+	}
+	if len(cfg.Agents) != len(Default().Agents) {
+		t.Errorf("Agents = %v, want the default agents", cfg.GetAgentNames())
+	}
+
+	// The preset argument wins over the config's preset key
+	cfg, err = LoadWithPreset(configPath, "go-api")
+	if err != nil {
+		t.Fatalf("LoadWithPreset() error = %v", err)
+	}
+	if cfg.Preset != "go-api" || cfg.Preferences.Language != "go" || cfg.Stack.Database != "mongodb" {
+		t.Errorf("LoadWithPreset() = preset %q, language %q, database %q; want go-api, go, mongodb",
+			cfg.Preset, cfg.Preferences.Language, cfg.Stack.Database)
+	}
+}
+
+func TestUserPresetAgentOverrides(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".pagent", "presets")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	preset := `
+description: Team defaults
+persona: production
+agents:
+  implementer:
+    depends_on: [architect]
+  docs:
+    prompt: Write the docs
+    output: docs.md
+    depends_on: [architect]
+`
+	if err := os.WriteFile(filepath.Join(dir, "team.yaml"), []byte(preset), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A user preset shadows the built-in preset of the same name
+	if err := os.WriteFile(filepath.Join(dir, "go-api.yaml"), []byte("description: Ours\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadWithPreset(filepath.Join(home, "missing.yaml"), "team"); err == nil {
+		t.Fatal("LoadWithPreset() should fail for a missing explicit config file")
+	}
+
+	// No config file: the preset alone is layered on the defaults
+	cfg, err := LoadWithPreset("", "team")
+	if err != nil {
+		t.Fatalf("LoadWithPreset() error = %v", err)
+	}
+	if cfg.Persona != PersonaProduction {
+		t.Errorf("Persona = %q, want production", cfg.Persona)
+	}
+	implementer := cfg.Agents["implementer"]
+	if implementer.Output != "code/.complete" || len(implementer.DependsOn) != 1 {
+		t.Errorf("implementer = %+v, want default output with overridden depends_on", implementer)
+	}
+	if _, ok := cfg.Agents["docs"]; !ok || len(cfg.Agents) != len(Default().Agents)+1 {
+		t.Errorf("Agents = %v, want the defaults plus docs", cfg.GetAgentNames())
+	}
+
+	presets, err := ListPresets()
+	if err != nil {
+		t.Fatalf("ListPresets() error = %v", err)
+	}
+	sources := map[string]string{}
+	for _, p := range presets {
+		sources[p.Name] = p.Source
+	}
+	if sources["team"] != PresetUser || sources["go-api"] != PresetUser || sources["python-ml"] != PresetBuiltin {
+		t.Errorf("ListPresets() sources = %v", sources)
+	}
+}
+
+func TestLoadPresetInvalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, name := range []string{"", "nope", "../config", ".hidden"} {
+		if _, err := LoadPreset(name); err == nil {
+			t.Errorf("LoadPreset(%q) error = nil, want error", name)
+		}
+	}
+}
+
+func TestPresetWithoutAgentsKeepsItsValues(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	cfg, err := LoadWithPreset("", "prototype")
+	if err != nil {
+		t.Fatalf("LoadWithPreset() error = %v", err)
+	}
+	if cfg.Persona != PersonaMinimal {
+		t.Errorf("persona = %q, want minimal from the preset", cfg.Persona)
+	}
+	for _, key := range []string{"persona", "stack.cache", "preferences.testing_depth"} {
+		if got := cfg.Origin(key); got != "preset:prototype" {
+			t.Errorf("Origin(%s) = %q, want preset:prototype", key, got)
+		}
+	}
+	if got := cfg.Origin("agents.architect.output"); got != OriginDefault {
+		t.Errorf("Origin(agents.architect.output) = %q, want default", got)
+	}
+	if len(cfg.Agents) != len(Default().Agents) {
+		t.Errorf("Agents = %v, want the default agents", cfg.GetAgentNames())
+	}
+
+	// Loading again must not see values leaked from the previous merge
+	cfg, err = Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Persona != PersonaBalanced || cfg.Stack.Cache != Default().Stack.Cache {
+		t.Errorf("persona, stack.cache = %q, %q; want the defaults", cfg.Persona, cfg.Stack.Cache)
+	}
+}
@@ -0,0 +1,54 @@
+// queue.go defines settings for the persistent job queue.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultQueueConcurrency is the number of jobs run at once when unset
+const DefaultQueueConcurrency = 1
+
+// QueueConfig configures the job queue used by 'pagent submit', the daemon
+// and the MCP server
+type QueueConfig struct {
+	Dir         string `yaml:"dir"`         // Queue directory (default: ~/.pagent/queue)
+	Concurrency int    `yaml:"concurrency"` // Jobs run at once (default: 1)
+}
+
+// EffectiveDir returns the queue directory with ~ expanded
+func (q QueueConfig) EffectiveDir() string {
+	dir := q.Dir
+	if dir == "" {
+		dir = filepath.Join("~", ".pagent", "queue")
+	}
+	return expandHome(dir)
+}
+
+// expandHome replaces a leading ~ in path with the user's home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+// EffectiveConcurrency returns the concurrency limit, applying the default
+func (q QueueConfig) EffectiveConcurrency() int {
+	if q.Concurrency <= 0 {
+		return DefaultQueueConcurrency
+	}
+	return q.Concurrency
+}
+
+// Validate checks the concurrency limit
+func (q QueueConfig) Validate() error {
+	if q.Concurrency < 0 {
+		return fmt.Errorf("queue.concurrency must not be negative, got %d", q.Concurrency)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package config
+
+// RedactedValue is shown in place of secret values
+const RedactedValue = "<redacted>"
+
+// Redacted returns a copy of the config safe to print: webhook secrets and
+// headers, and the env and headers of MCP servers, which usually carry
+// credentials, have their values replaced by RedactedValue
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.Notifications.Webhooks = make([]WebhookConfig, len(c.Notifications.Webhooks))
+	for i, w := range c.Notifications.Webhooks {
+		if w.Secret != "" {
+			w.Secret = RedactedValue
+		}
+		w.Headers = redactValues(w.Headers)
+		r.Notifications.Webhooks[i] = w
+	}
+	r.MCPServers = redactMCPServers(c.MCPServers)
+	if c.Agents != nil {
+		r.Agents = make(map[string]AgentConfig, len(c.Agents))
+		for name, a := range c.Agents {
+			a.MCPServers = redactMCPServers(a.MCPServers)
+			r.Agents[name] = a
+		}
+	}
+	return &r
+}
+
+func redactMCPServers(servers map[string]MCPServerConfig) map[string]MCPServerConfig {
+	if servers == nil {
+		return nil
+	}
+	redacted := make(map[string]MCPServerConfig, len(servers))
+	for name, s := range servers {
+		s.Env = redactValues(s.Env)
+		s.Headers = redactValues(s.Headers)
+		redacted[name] = s
+	}
+	return redacted
+}
+
+// redactValues returns a copy of m with every value redacted
+func redactValues(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(m))
+	for k := range m {
+		redacted[k] = RedactedValue
+	}
+	return redacted
+}
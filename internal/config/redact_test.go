@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestRedacted(t *testing.T) {
+	cfg := &Config{
+		Notifications: NotificationsConfig{Webhooks: []WebhookConfig{{
+			URL:     "https://hooks.example.com/x",
+			Secret:  "hmac-secret",
+			Headers: map[string]string{"Authorization": "Bearer hook-token"},
+		}}},
+		MCPServers: map[string]MCPServerConfig{
+			"github": {Command: "github-mcp", Env: map[string]string{"GITHUB_TOKEN": "ghp_secret"}},
+		},
+		Agents: map[string]AgentConfig{
+			"architect": {MCPServers: map[string]MCPServerConfig{
+				"docs": {Type: MCPServerHTTP, URL: "https://docs.example.com", Headers: map[string]string{"X-Api-Key": "docs-key"}},
+			}},
+		},
+	}
+
+	data, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, secret := range []string{"hmac-secret", "hook-token", "ghp_secret", "docs-key"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("redacted config contains %q:\n%s", secret, out)
+		}
+	}
+	for _, kept := range []string{"https://hooks.example.com/x", "GITHUB_TOKEN", "X-Api-Key", RedactedValue} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("redacted config lacks %q:\n%s", kept, out)
+		}
+	}
+
+	if cfg.Notifications.Webhooks[0].Secret != "hmac-secret" || cfg.MCPServers["github"].Env["GITHUB_TOKEN"] != "ghp_secret" {
+		t.Error("Redacted() modified the original config")
+	}
+}
@@ -0,0 +1,275 @@
+// schema.go generates a JSON Schema for the config file from the Config
+// struct, so editors can complete and check .pagent/config.yaml.
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// SchemaFile is the name of the schema written next to the config by init
+const SchemaFile = "config.schema.json"
+
+// JSONSchema is the subset of JSON Schema (draft 7) used for the config
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 any                    `json:"type,omitempty"` // A type name or a list of them
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"` // false or a *JSONSchema
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Examples             []string               `json:"examples,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+}
+
+// Schema returns the JSON Schema of the config file. Keys of maps (agent
+// and server names) are written as "*" in the description and enum tables.
+func Schema() *JSONSchema {
+	s := schemaFor(reflect.TypeOf(Config{}), reflect.ValueOf(*Default()), "")
+	s.Schema = "http://json-schema.org/draft-07/schema#"
+	s.Title = "pagent configuration"
+	s.Description = "Configuration for pagent (.pagent/config.yaml)"
+	return s
+}
+
+// SchemaJSON returns the schema as indented JSON
+func SchemaJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(Schema(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaFor builds the schema of type t at path. def is the default value,
+// or invalid when there is none (inside maps and lists).
+func schemaFor(t reflect.Type, def reflect.Value, path string) *JSONSchema {
+	key := path
+	if rest, ok := strings.CutPrefix(path, "agents.*."); ok && strings.HasPrefix(rest, "mcp_servers.") {
+		key = rest // Agent MCP servers take the same settings as global ones
+	}
+	if path == "personas.*.preferences" {
+		t = reflect.TypeOf(ArchitecturePreferences{}) // Overrides take the keys of preferences:
+	} else if rest, ok := strings.CutPrefix(path, "personas.*."); ok && strings.HasPrefix(rest, "preferences.") {
+		key = rest
+	}
+	s := &JSONSchema{Description: schemaDescriptions[key], Enum: schemaEnums[key]}
+	if name, ok := strings.CutPrefix(key, "stack."); ok {
+		// Other stack values are allowed, so offer these as suggestions only
+		s.Examples = StackValues[name]
+	}
+	if key == "persona" {
+		// Personas defined under personas: are allowed too
+		s.Examples = ValidPersonas
+	}
+
+	if t.Kind() == reflect.Pointer {
+		// Optional values, where nil means "use the default"
+		t = t.Elem()
+		if def.IsValid() && !def.IsNil() {
+			def = def.Elem()
+		} else {
+			def = reflect.Value{}
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s.Type = "object"
+		s.Properties = map[string]*JSONSchema{}
+		s.AdditionalProperties = false
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, inline := yamlFieldName(field)
+			if name == "" && !inline {
+				continue
+			}
+			var fieldDef reflect.Value
+			if def.IsValid() {
+				fieldDef = def.Field(i)
+			}
+			child := schemaFor(field.Type, fieldDef, joinKey(path, name))
+			if inline {
+				for k, v := range child.Properties {
+					s.Properties[k] = v
+				}
+				continue
+			}
+			s.Properties[name] = child
+		}
+	case reflect.Map:
+		s.Type = "object"
+		elem := schemaFor(t.Elem(), reflect.Value{}, joinKey(path, "*"))
+		if t.Elem().Kind() == reflect.Struct {
+			// An entry without settings, such as "docs:", decodes to the zero value
+			elem.Type = []string{"object", "null"}
+		}
+		s.AdditionalProperties = elem
+	case reflect.Slice:
+		s.Type = "array"
+		s.Items = schemaFor(t.Elem(), reflect.Value{}, joinKey(path, "*"))
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int64:
+		s.Type = "integer"
+	}
+
+	if def.IsValid() && !def.IsZero() && t.Kind() != reflect.Struct && t.Kind() != reflect.Map {
+		s.Default = def.Interface()
+	}
+	return s
+}
+
+// yamlFieldName returns the YAML key of a struct field, or "" for fields
+// that are not read from YAML
+func yamlFieldName(field reflect.StructField) (name string, inline bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if opts == "inline" {
+		return "", true
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, false
+}
+
+// schemaEnums lists the allowed values of enumerated settings
+var schemaEnums = map[string][]string{
+	"personas.*.base":                   ValidPersonas,
+	"mode":                              ValidModes,
+	"agents.*.kind":                     ValidAgentKinds,
+	"preferences.api_style":             PreferenceValues["api_style"],
+	"preferences.language":              PreferenceValues["language"],
+	"preferences.testing_depth":         PreferenceValues["testing_depth"],
+	"preferences.documentation_level":   PreferenceValues["documentation_level"],
+	"preferences.dependency_style":      PreferenceValues["dependency_style"],
+	"preferences.error_handling":        PreferenceValues["error_handling"],
+	"notifications.webhooks.*.format":   ValidWebhookFormats,
+	"notifications.webhooks.*.events.*": NotifiableEvents,
+	"tracing.exporter":                  ValidTracingExporters,
+	"mcp_servers.*.type":                {MCPServerStdio, MCPServerHTTP, MCPServerSSE},
+}
+
+// schemaDescriptions documents each setting by dotted path
+var schemaDescriptions = map[string]string{
+	"preset":           "Preset profile the config is layered on: go-api, python-ml, typescript-web, prototype or a file in ~/.pagent/presets",
+	"output_dir":       "Directory for generated specs and code",
+	"timeout":          "Seconds to wait for each agent; 0 waits indefinitely (default when a config file is loaded: 300)",
+	"persona":          "Implementation style: a built-in persona or one defined under personas",
+	"mode":             "create builds a new codebase; modify changes target_codebase",
+	"target_codebase":  "Existing codebase to modify (required when mode is modify)",
+	"input_files":      "Input files (PRD, TRD, requirements) read by the agents",
+	"specs_output_dir": "Directory for spec outputs (default: output_dir)",
+
+	"stack":               "Technology stack the agents design and implement for. Values outside the suggestions are passed to agents as written",
+	"stack.cloud":         "Cloud provider",
+	"stack.compute":       "Compute platform",
+	"stack.database":      "Primary database",
+	"stack.cache":         "Cache",
+	"stack.search":        "Search engine",
+	"stack.message_queue": "Message queue or event bus",
+	"stack.iac":           "Infrastructure as code tool",
+	"stack.gitops":        "GitOps tool",
+	"stack.ci":            "CI/CD system",
+	"stack.data_lake":     "Data lake storage",
+	"stack.data_engine":   "Data processing engine",
+	"stack.query_engine":  "Query engine",
+	"stack.monitoring":    "Monitoring system",
+	"stack.alerting":      "Alerting system",
+	"stack.logging":       "Log destination",
+	"stack.chat":          "Chat platform",
+	"stack.additional":    "Other tools, free-form",
+
+	"preferences":                     "Architectural style preferences",
+	"preferences.stateless":           "Prefer event-driven, stateless designs over database-backed CRUD",
+	"preferences.api_style":           "API paradigm",
+	"preferences.language":            "Primary programming language",
+	"preferences.testing_depth":       "How much test code to generate; each level includes the ones before it",
+	"preferences.documentation_level": "Documentation verbosity",
+	"preferences.dependency_style":    "Third-party dependency usage: minimal prefers the standard library, batteries favours feature-rich libraries",
+	"preferences.error_handling":      "Error handling sophistication",
+	"preferences.containerized":       "Containerize the application",
+	"preferences.include_ci":          "Generate CI/CD pipelines",
+	"preferences.include_iac":         "Generate infrastructure as code",
+
+	"agents":               "Agents in the pipeline by name. Defining agents replaces the built-in pipeline",
+	"agents.*":             "An agent",
+	"agents.*.prompt":      "Inline prompt template (takes precedence over prompt_file)",
+	"agents.*.prompt_file": "Path to a prompt template file",
+	"agents.*.output":      "Output file, relative to output_dir",
+	"agents.*.depends_on":  "Agents whose outputs this agent needs",
+	"agents.*.kind":        "spec writes to specs_output_dir, code to the target codebase in modify mode, other to output_dir (default: other, or the kind of the built-in agent of that name)",
+	"agents.*.description": "What the agent does, shown in agent listings, the TUI and the MCP list_agents tool",
+	"agents.*.output_root": "Directory output is relative to, replacing the one of the kind",
+	"agents.*.mcp_servers": "MCP servers for this agent only, replacing mcp_servers; {} gives the agent none",
+
+	"personas":               "Personas beyond minimal, balanced and production, by name",
+	"personas.*":             "A persona",
+	"personas.*.label":       "Display name (default: the persona name)",
+	"personas.*.description": "What the persona is for, shown in the TUI and given to agents",
+	"personas.*.base":        "Built-in persona whose template behavior this one inherits (default: balanced)",
+	"personas.*.preferences": "Preferences this persona sets unless a config file, the environment or a flag sets them",
+	"personas.*.agents":      "Agents run when none are selected (default: all)",
+
+	"post_processing":                         "Actions after the pipeline finishes",
+	"post_processing.generate_diff_summary":   "Generate a git diff summary",
+	"post_processing.generate_pr_description": "Generate a PR description from the changes",
+	"post_processing.validation_commands":     "Commands run after implementation",
+
+	"notifications":                        "Notifications sent while runs progress",
+	"notifications.webhooks":               "Webhooks called on lifecycle events",
+	"notifications.webhooks.*":             "A webhook",
+	"notifications.webhooks.*.name":        "Label used in error messages",
+	"notifications.webhooks.*.url":         "Target endpoint; ${VAR} is expanded from the environment",
+	"notifications.webhooks.*.format":      "Payload format (default: json)",
+	"notifications.webhooks.*.events":      "Events to send (default: all notifiable events)",
+	"notifications.webhooks.*.secret":      "HMAC-SHA256 signing key",
+	"notifications.webhooks.*.headers":     "Extra request headers",
+	"notifications.webhooks.*.max_retries": "Retries after the first attempt (default: 3; 0 disables retries)",
+	"notifications.webhooks.*.timeout":     "Per-request timeout in seconds (default: 10)",
+
+	"tracing":              "OpenTelemetry tracing of runs",
+	"tracing.exporter":     "Span exporter (default: none)",
+	"tracing.endpoint":     "OTLP host:port (default: OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318)",
+	"tracing.insecure":     "Use plain HTTP for OTLP",
+	"tracing.file":         "Output file for the file exporter (default: pagent-traces.json)",
+	"tracing.service_name": "Reported service.name (default: pagent)",
+
+	"queue":             "Persistent queue for submitted runs",
+	"queue.dir":         "Queue directory (default: ~/.pagent/queue)",
+	"queue.concurrency": "Jobs run at once (default: 1)",
+
+	"mcp":                    "Restrictions for the pagent MCP server",
+	"mcp.roots":              "Directories MCP clients may read input from and write output to (default: any path)",
+	"mcp.subject_workspaces": "Directory with an isolated workspace per OAuth subject, used instead of roots",
+	"mcp.policy":             "File mapping OAuth claims to the tools, agents, personas and roots each caller may use",
+	"mcp.config_paths":       "Config files clients connected over HTTP may pass as config_path (default: none)",
+
+	"audit":             "Audit log of MCP tool calls and runs",
+	"audit.disabled":    "Turn the audit log off",
+	"audit.path":        "Log file (default: ~/.pagent/audit.jsonl)",
+	"audit.max_size_mb": "Size in MB at which the log is rotated (default: 10)",
+	"audit.max_files":   "Rotated files kept (default: 5)",
+
+	"mcp_servers":           "MCP servers given to agents without their own mcp_servers. An empty entry in an agent's mcp_servers refers to the global server of that name",
+	"mcp_servers.*":         "An MCP server",
+	"mcp_servers.*.type":    "Transport (default: stdio)",
+	"mcp_servers.*.command": "Command that starts a stdio server",
+	"mcp_servers.*.args":    "Arguments of the command",
+	"mcp_servers.*.env":     "Environment of the command",
+	"mcp_servers.*.url":     "Endpoint of an http or sse server",
+	"mcp_servers.*.headers": "Request headers for an http or sse server",
+	"mcp_servers.*.tools":   "Tools the agent may call (default: all)",
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// schemaPaths returns every setting in the schema by dotted path
+func schemaPaths(s *JSONSchema, path string, paths map[string]*JSONSchema) {
+	for name, child := range s.Properties {
+		key := joinKey(path, name)
+		paths[key] = child
+		schemaPaths(child, key, paths)
+	}
+	elem, _ := s.AdditionalProperties.(*JSONSchema)
+	if elem == nil {
+		elem = s.Items
+	}
+	if elem != nil && elem.Properties != nil {
+		paths[joinKey(path, "*")] = elem
+		schemaPaths(elem, joinKey(path, "*"), paths)
+	}
+}
+
+func TestSchemaDescribesEverySetting(t *testing.T) {
+	paths := map[string]*JSONSchema{}
+	schemaPaths(Schema(), "", paths)
+
+	for path, s := range paths {
+		if s.Description == "" {
+			t.Errorf("%s has no description", path)
+		}
+	}
+	for path := range schemaDescriptions {
+		if _, ok := paths[path]; !ok {
+			t.Errorf("schemaDescriptions has %s, which is not a setting", path)
+		}
+	}
+}
+
+func TestSchemaEnumsAndDefaults(t *testing.T) {
+	s := Schema()
+	if s.AdditionalProperties != false {
+		t.Error("top level should reject unknown keys")
+	}
+
+	apiStyle := s.Properties["preferences"].Properties["api_style"]
+	if len(apiStyle.Enum) != 3 || apiStyle.Default != "rest" {
+		t.Errorf("preferences.api_style = %+v, want an enum with default rest", apiStyle)
+	}
+	database := s.Properties["stack"].Properties["database"]
+	if database.Enum != nil || len(database.Examples) == 0 || database.Default != "postgres" {
+		t.Errorf("stack.database = %+v, want examples without an enum", database)
+	}
+
+	server := s.Properties["agents"].AdditionalProperties.(*JSONSchema).
+		Properties["mcp_servers"].AdditionalProperties.(*JSONSchema)
+	if server.Properties["type"].Enum == nil || server.Properties["command"].Description == "" {
+		t.Errorf("agent mcp_servers entries should share the global server settings")
+	}
+
+	if _, err := json.Marshal(s); err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+}
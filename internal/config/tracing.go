@@ -0,0 +1,36 @@
+// tracing.go defines OpenTelemetry tracing settings.
+package config
+
+import "fmt"
+
+// Tracing exporters
+const (
+	TracingExporterNone = "none" // Tracing disabled (default)
+	TracingExporterOTLP = "otlp" // OTLP over HTTP to a collector
+	TracingExporterFile = "file" // JSON spans written to a local file
+)
+
+// ValidTracingExporters lists all valid tracing exporters
+var ValidTracingExporters = []string{TracingExporterNone, TracingExporterOTLP, TracingExporterFile}
+
+// TracingConfig configures OpenTelemetry tracing of runs
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter"`     // none (default), otlp, file
+	Endpoint    string `yaml:"endpoint"`     // OTLP host:port (default: OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318)
+	Insecure    bool   `yaml:"insecure"`     // Use plain HTTP for OTLP
+	File        string `yaml:"file"`         // Output file for the file exporter (default: pagent-traces.json)
+	ServiceName string `yaml:"service_name"` // Reported service.name (default: pagent)
+}
+
+// IsEnabled returns true if an exporter other than "none" is configured
+func (t TracingConfig) IsEnabled() bool {
+	return t.Exporter != "" && t.Exporter != TracingExporterNone
+}
+
+// Validate checks the exporter value
+func (t TracingConfig) Validate() error {
+	if t.Exporter != "" && !contains(ValidTracingExporters, t.Exporter) {
+		return fmt.Errorf("invalid tracing exporter %q: must be one of %v", t.Exporter, ValidTracingExporters)
+	}
+	return nil
+}
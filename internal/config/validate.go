@@ -0,0 +1,262 @@
+// validate.go checks a config beyond what YAML decoding enforces: unknown
+// keys, documented enum values, referenced files and the agent graph.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PreferenceValues lists the documented values of each preferences key
+var PreferenceValues = map[string][]string{
+	"api_style":           {"rest", "graphql", "grpc"},
+	"language":            {"go", "python", "typescript", "java", "rust"},
+	"testing_depth":       {"none", "unit", "integration", "e2e"},
+	"documentation_level": {"minimal", "standard", "comprehensive"},
+	"dependency_style":    {"minimal", "standard", "batteries"},
+	"error_handling":      {"simple", "structured", "comprehensive"},
+}
+
+// StackValues lists the documented values of each stack key
+var StackValues = map[string][]string{
+	"cloud":         {"aws", "gcp", "azure"},
+	"compute":       {"kubernetes", "eks", "gke", "aks", "ec2", "lambda", "github-actions", "none"},
+	"database":      {"postgres", "mongodb", "mysql", "none"},
+	"cache":         {"redis", "memcached", "kvrock", "none"},
+	"search":        {"elasticsearch", "opensearch", "none"},
+	"message_queue": {"kafka", "sqs", "rabbitmq", "nats", "none"},
+	"iac":           {"terraform", "pulumi", "cloudformation", "none"},
+	"gitops":        {"argocd", "flux", "none"},
+	"ci":            {"github-actions", "gitlab-ci", "jenkins", "none"},
+	"data_lake":     {"s3", "gcs", "adls", "none"},
+	"data_engine":   {"spark", "flink", "none"},
+	"query_engine":  {"trino", "presto", "athena", "none"},
+	"monitoring":    {"prometheus", "grafana", "datadog", "newrelic", "none"},
+	"alerting":      {"pagerduty", "opsgenie", "none"},
+	"logging":       {"stdout", "loki", "elasticsearch", "cloudwatch", "none"},
+	"chat":          {"slack", "teams", "none"},
+}
+
+// ValidationError lists every problem found in a config
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0]
+	}
+	return fmt.Sprintf("%d config problems:\n  %s", len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+// validationError returns nil when there are no problems
+func validationError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+var (
+	typeErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+	unknownField  = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+)
+
+// strictDecode decodes data into v rejecting unknown keys, and reports each
+// problem as "<origin>:<line>: <message>"
+func strictDecode(origin string, data []byte, v any) []string {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(v)
+	if err == nil || errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return []string{fmt.Sprintf("%s: %v", origin, err)}
+	}
+	problems := make([]string, 0, len(typeErr.Errors))
+	for _, msg := range typeErr.Errors {
+		m := typeErrorLine.FindStringSubmatch(msg)
+		if m == nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", origin, msg))
+			continue
+		}
+		msg = unknownField.ReplaceAllString(m[2], `unknown key "$1"`)
+		problems = append(problems, fmt.Sprintf("%s:%s: %s", origin, m[1], msg))
+	}
+	return problems
+}
+
+// Validate checks the merged config and returns a *ValidationError listing
+// every problem. Problems are prefixed with the file and line that set the
+// offending value when it came from a config file.
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(key, format string, args ...any) {
+		msg := fmt.Sprintf(format, args...)
+		if loc := c.Location(key); loc != OriginDefault {
+			msg = loc + ": " + msg
+		}
+		problems = append(problems, msg)
+	}
+
+	if c.Timeout < 0 {
+		add("timeout", "timeout must not be negative, got %d", c.Timeout)
+	}
+	if !c.HasPersona(c.Persona) {
+		add("persona", "persona must be one of %s, got %q", strings.Join(c.PersonaNames(), ", "), c.Persona)
+	}
+	c.personaProblems(add)
+	if err := c.ValidateMode(); err != nil {
+		add("mode", "%v", err)
+	} else if c.TargetCodebase != "" {
+		if info, err := os.Stat(c.TargetCodebase); err != nil {
+			add("target_codebase", "target_codebase %q does not exist", c.TargetCodebase)
+		} else if !info.IsDir() {
+			add("target_codebase", "target_codebase %q is not a directory", c.TargetCodebase)
+		}
+	}
+
+	prefs := c.preferenceValues()
+	for _, key := range sortedKeys(PreferenceValues) {
+		if v := prefs[key]; v != "" && !contains(PreferenceValues[key], v) {
+			add("preferences."+key, "preferences.%s must be one of %s, got %q",
+				key, strings.Join(PreferenceValues[key], ", "), v)
+		}
+	}
+	stack := c.stackValues()
+	for _, key := range sortedKeys(StackValues) {
+		if v := stack[key]; v != "" && !contains(StackValues[key], v) {
+			add("stack."+key, "stack.%s must be one of %s, got %q",
+				key, strings.Join(StackValues[key], ", "), v)
+		}
+	}
+
+	for _, name := range c.GetAgentNames() {
+		agent := c.Agents[name]
+		key := "agents." + name
+		if agent.Output == "" {
+			add(key, "%s.output is required", key)
+		}
+		if agent.Kind != "" && !contains(ValidAgentKinds, agent.Kind) {
+			add(key+".kind", "%s.kind must be one of %s, got %q", key, strings.Join(ValidAgentKinds, ", "), agent.Kind)
+		}
+		if agent.Prompt == "" && agent.PromptFile != "" {
+			if _, err := os.Stat(agent.PromptFile); err != nil {
+				add(key+".prompt_file", "%s.prompt_file %q does not exist", key, agent.PromptFile)
+			}
+		}
+		for _, dep := range agent.DependsOn {
+			if _, ok := c.Agents[dep]; !ok {
+				add(key+".depends_on", "%s.depends_on references unknown agent %q", key, dep)
+			}
+		}
+	}
+	if cycle := c.dependencyCycle(); cycle != nil {
+		add("agents."+cycle[0]+".depends_on", "agents form a dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	for _, section := range []struct {
+		key string
+		err error
+	}{
+		{"notifications", c.Notifications.Validate()},
+		{"tracing", c.Tracing.Validate()},
+		{"queue", c.Queue.Validate()},
+		{"audit", c.Audit.Validate()},
+		{"mcp_servers", c.ValidateMCPServers()},
+	} {
+		if section.err != nil {
+			add(section.key, "%v", section.err)
+		}
+	}
+
+	return validationError(problems)
+}
+
+func (c *Config) preferenceValues() map[string]string {
+	p := c.Preferences
+	return map[string]string{
+		"api_style":           p.APIStyle,
+		"language":            p.Language,
+		"testing_depth":       p.TestingDepth,
+		"documentation_level": p.DocumentationLevel,
+		"dependency_style":    p.DependencyStyle,
+		"error_handling":      p.ErrorHandling,
+	}
+}
+
+func (c *Config) stackValues() map[string]string {
+	s := c.Stack
+	return map[string]string{
+		"cloud":         s.Cloud,
+		"compute":       s.Compute,
+		"database":      s.Database,
+		"cache":         s.Cache,
+		"search":        s.Search,
+		"message_queue": s.MessageQueue,
+		"iac":           s.IaC,
+		"gitops":        s.GitOps,
+		"ci":            s.CI,
+		"data_lake":     s.DataLake,
+		"data_engine":   s.DataEngine,
+		"query_engine":  s.QueryEngine,
+		"monitoring":    s.Monitoring,
+		"alerting":      s.Alerting,
+		"logging":       s.Logging,
+		"chat":          s.Chat,
+	}
+}
+
+// dependencyCycle returns the agents of a depends_on cycle, starting and
+// ending with the same agent, or nil if the graph is acyclic
+func (c *Config) dependencyCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range c.Agents[name].DependsOn {
+			if _, ok := c.Agents[dep]; !ok {
+				continue
+			}
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+	for _, name := range c.GetAgentNames() {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, `
+agents:
+  architect:
+    output: architecture.md
+  qa:
+    output: test-plan.md
+    depend_on: [architect]
+`)
+
+	_, err := Load(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+	want := path + `:7: unknown key "depend_on"`
+	if len(verr.Problems) != 1 || verr.Problems[0] != want {
+		t.Errorf("Problems = %q, want [%q]", verr.Problems, want)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, `
+preferences:
+  api_style: soap
+target_codebase: /nonexistent/codebase
+agents:
+  architect:
+    output: architecture.md
+    depends_on: [qa]
+  qa:
+    output: test-plan.md
+    prompt_file: /nonexistent/qa.md
+    depends_on: [architect, ghost]
+`)
+
+	_, err := Load(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+	wants := []string{
+		path + `:4: target_codebase "/nonexistent/codebase" does not exist`,
+		path + `:3: preferences.api_style must be one of rest, graphql, grpc, got "soap"`,
+		path + `:11: agents.qa.prompt_file "/nonexistent/qa.md" does not exist`,
+		path + `:12: agents.qa.depends_on references unknown agent "ghost"`,
+		path + `:8: agents form a dependency cycle: architect -> qa -> architect`,
+	}
+	if strings.Join(verr.Problems, "\n") != strings.Join(wants, "\n") {
+		t.Errorf("Problems =\n%s\nwant\n%s", strings.Join(verr.Problems, "\n"), strings.Join(wants, "\n"))
+	}
+}
+
+func TestValidateRejectsUndocumentedStack(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v for the default config", err)
+	}
+
+	cfg.Stack.Database = "dynamodb"
+	err := cfg.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() error = %v, want *ValidationError", err)
+	}
+	want := `stack.database must be one of postgres, mongodb, mysql, none, got "dynamodb"`
+	if len(verr.Problems) != 1 || verr.Problems[0] != want {
+		t.Errorf("Problems = %q, want [%q]", verr.Problems, want)
+	}
+}
+
+func TestBuiltinPresetsValidate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	presets, err := ListPresets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range presets {
+		cfg, err := LoadWithPreset("", p.Name)
+		if err != nil {
+			t.Errorf("preset %s: %v", p.Name, err)
+			continue
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("preset %s: %v", p.Name, err)
+		}
+	}
+}
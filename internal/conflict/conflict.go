@@ -0,0 +1,224 @@
+// Package conflict finds technologies an input document asks for that
+// differ from the configured stack, and resolves them before agents run.
+package conflict
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/types"
+)
+
+// Categories are the stack settings documents are scanned for, by yaml key
+var Categories = []string{"database", "cache", "message_queue", "compute"}
+
+// mention is a phrase that, found in a document, suggests a stack value
+type mention struct {
+	category, value string
+	phrases         []string
+}
+
+// mentions lists the phrases per category. Phrases for "none" follow the
+// PRD wording the architect prompt treats as overriding the config. Words
+// common in ordinary prose ("stateless", "lambda") only count as part of an
+// architectural statement.
+var mentions = []mention{
+	{"database", "postgres", []string{"postgres", "postgresql", "aurora postgres"}},
+	{"database", "mysql", []string{"mysql", "mariadb"}},
+	{"database", "mongodb", []string{"mongodb", "mongo", "documentdb"}},
+	{"database", "none", []string{"no database", "without a database", "no persistent storage", "without persistent storage", "stateless service", "stateless architecture"}},
+	{"cache", "redis", []string{"redis", "elasticache", "valkey"}},
+	{"cache", "memcached", []string{"memcached", "memcache"}},
+	{"cache", "none", []string{"no cache", "no caching", "no caching layer", "without a cache", "without caching"}},
+	{"message_queue", "kafka", []string{"kafka", "msk", "redpanda"}},
+	{"message_queue", "rabbitmq", []string{"rabbitmq", "amqp"}},
+	{"message_queue", "nats", []string{"nats", "jetstream"}},
+	{"message_queue", "sqs", []string{"sqs"}},
+	{"message_queue", "none", []string{"no message queue", "no queue", "without a queue", "synchronous calls", "direct calls"}},
+	{"compute", "kubernetes", []string{"kubernetes", "k8s"}},
+	{"compute", "eks", []string{"eks"}},
+	{"compute", "gke", []string{"gke"}},
+	{"compute", "aks", []string{"aks"}},
+	{"compute", "lambda", []string{"aws lambda", "deploy to lambda", "deployed to lambda", "runs on lambda", "run on lambda"}},
+	{"compute", "ec2", []string{"ec2"}},
+	{"compute", "github-actions", []string{"github actions", "github action"}},
+}
+
+// negations before a phrase turn a mention into its opposite, as in
+// "no redis"; such mentions are ignored
+var negations = []string{"no ", "not ", "not need ", "no need for ", "without ", "instead of ", "replace ", "replacing "}
+
+// Analyze scans files for technology mentions and returns the categories
+// where the documents suggest something other than stack, in Categories
+// order. When a document mentions several values of a category, the most
+// mentioned one wins, then the earliest.
+func Analyze(files []string, stack types.TechStack) ([]types.StackConflict, error) {
+	type found struct {
+		count    int
+		first    int // Offset of the first mention across files
+		evidence string
+	}
+	hints := map[string]map[string]*found{}
+	offset := 0
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		for n, line := range strings.Split(strings.ToLower(string(data)), "\n") {
+			for _, m := range mentions {
+				for _, phrase := range m.phrases {
+					at := mentioned(line, phrase)
+					if at < 0 {
+						continue
+					}
+					if hints[m.category] == nil {
+						hints[m.category] = map[string]*found{}
+					}
+					f := hints[m.category][m.value]
+					if f == nil {
+						f = &found{first: offset + at, evidence: fmt.Sprintf("%s:%d", filepath.Base(file), n+1)}
+						hints[m.category][m.value] = f
+					}
+					f.count++
+					break
+				}
+			}
+			offset += len(line) + 1
+		}
+	}
+
+	configured := stackValues(stack)
+	var conflicts []types.StackConflict
+	for _, category := range Categories {
+		values := hints[category]
+		if len(values) == 0 {
+			continue
+		}
+		candidates := make([]string, 0, len(values))
+		for v := range values {
+			candidates = append(candidates, v)
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			a, b := values[candidates[i]], values[candidates[j]]
+			if a.count != b.count {
+				return a.count > b.count
+			}
+			return a.first < b.first
+		})
+		hint := candidates[0]
+		if agrees(category, configured[category], hint) {
+			continue
+		}
+		conflicts = append(conflicts, types.StackConflict{
+			Category:    category,
+			ConfigValue: configured[category],
+			PRDHint:     hint,
+			Evidence:    values[hint].evidence,
+		})
+	}
+	return conflicts, nil
+}
+
+// Resolve resolves every conflict with a strategy, config.ResolvePreferPRD
+// or config.ResolvePreferConfig; config.ResolveFail is handled by the runner
+func Resolve(conflicts []types.StackConflict, strategy string) ([]types.StackConflict, error) {
+	resolved := make([]types.StackConflict, len(conflicts))
+	for i, c := range conflicts {
+		switch strategy {
+		case config.ResolvePreferPRD:
+			c.Resolution = c.PRDHint
+		case config.ResolvePreferConfig:
+			c.Resolution = c.ConfigValue
+		default:
+			return nil, fmt.Errorf("unknown resolve strategy %q (valid: %s, %s)", strategy, config.ResolvePreferPRD, config.ResolvePreferConfig)
+		}
+		c.Resolved = true
+		resolved[i] = c
+	}
+	return resolved, nil
+}
+
+// Matches reports whether a saved resolution answers exactly these
+// conflicts, so it can be reused without asking again
+func Matches(saved *types.StackResolution, conflicts []types.StackConflict) bool {
+	if saved == nil || !saved.Resolved || len(saved.Conflicts) != len(conflicts) {
+		return false
+	}
+	for i, c := range conflicts {
+		s := saved.Conflicts[i]
+		if !s.Resolved || s.Category != c.Category || s.ConfigValue != c.ConfigValue || s.PRDHint != c.PRDHint {
+			return false
+		}
+	}
+	return true
+}
+
+// mentioned returns where line contains phrase as whole words and not
+// right after a negation, or -1
+func mentioned(line, phrase string) int {
+	for i := 0; ; {
+		j := strings.Index(line[i:], phrase)
+		if j < 0 {
+			return -1
+		}
+		start, end := i+j, i+j+len(phrase)
+		if (start == 0 || !isWordChar(line[start-1])) && (end == len(line) || !isWordChar(line[end])) && !negated(line[:start], phrase) {
+			return start
+		}
+		i = start + 1
+	}
+}
+
+// negated reports whether before ends with a negation. Phrases that are
+// negations themselves ("no database") are never negated.
+func negated(before, phrase string) bool {
+	for _, n := range negations {
+		if strings.HasPrefix(phrase, n) {
+			return false
+		}
+	}
+	for _, n := range negations {
+		if strings.HasSuffix(before, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// agrees reports whether a configured value satisfies a hint. An empty
+// value means none, and any managed Kubernetes counts as kubernetes.
+func agrees(category, configured, hint string) bool {
+	if configured == "" {
+		configured = "none"
+	}
+	if configured == hint {
+		return true
+	}
+	if category == "compute" && hint == "kubernetes" {
+		switch configured {
+		case "eks", "gke", "aks":
+			return true
+		}
+	}
+	return false
+}
+
+// stackValues returns the configured value of each category
+func stackValues(stack types.TechStack) map[string]string {
+	return map[string]string{
+		"database":      stack.Database,
+		"cache":         stack.Cache,
+		"message_queue": stack.MessageQueue,
+		"compute":       stack.Compute,
+	}
+}
@@ -0,0 +1,146 @@
+package conflict
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/types"
+)
+
+func writePRD(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prd.md")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAnalyze(t *testing.T) {
+	prd := writePRD(t, `# Orders service
+
+Orders are stored in MongoDB. The MongoDB collections are sharded by tenant.
+Postgres is used by the billing team, not by this service.
+There is no caching layer; we do not need Redis.
+Deploy to EKS.
+`)
+	stack := types.DefaultStack() // postgres, redis, kubernetes, no queue
+
+	conflicts, err := Analyze([]string{prd}, stack)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	want := []types.StackConflict{
+		{Category: "database", ConfigValue: "postgres", PRDHint: "mongodb", Evidence: "prd.md:3"},
+		{Category: "cache", ConfigValue: "redis", PRDHint: "none", Evidence: "prd.md:5"},
+		{Category: "compute", ConfigValue: "kubernetes", PRDHint: "eks", Evidence: "prd.md:6"},
+	}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("Analyze() =\n%+v\nwant\n%+v", conflicts, want)
+	}
+}
+
+func TestAnalyzeAgreement(t *testing.T) {
+	prd := writePRD(t, "Runs on Kubernetes with PostgreSQL and Redis. No message queue.\n")
+	stack := types.DefaultStack()
+	stack.Compute = "gke"
+
+	conflicts, err := Analyze([]string{prd}, stack)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("Analyze() = %+v, want no conflicts", conflicts)
+	}
+}
+
+func TestAnalyzeArchitecturalStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		prd  string
+		want []types.StackConflict
+	}{
+		{
+			name: "ordinary prose",
+			prd: `The API is stateless and uses JWTs.
+Preview links are ephemeral and expire after an hour.
+Sort handlers take a lambda comparator.
+`,
+		},
+		{
+			name: "architectural statements",
+			prd: `Deploy to AWS Lambda.
+The service has no persistent storage.
+`,
+			want: []types.StackConflict{
+				{Category: "database", ConfigValue: "postgres", PRDHint: "none", Evidence: "prd.md:2"},
+				{Category: "compute", ConfigValue: "kubernetes", PRDHint: "lambda", Evidence: "prd.md:1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts, err := Analyze([]string{writePRD(t, tt.prd)}, types.DefaultStack())
+			if err != nil {
+				t.Fatalf("Analyze() error = %v", err)
+			}
+			if !reflect.DeepEqual(conflicts, tt.want) {
+				t.Errorf("Analyze() =\n%+v\nwant\n%+v", conflicts, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	conflicts := []types.StackConflict{{Category: "database", ConfigValue: "postgres", PRDHint: "mongodb"}}
+
+	for strategy, want := range map[string]string{
+		config.ResolvePreferPRD:    "mongodb",
+		config.ResolvePreferConfig: "postgres",
+	} {
+		resolved, err := Resolve(conflicts, strategy)
+		if err != nil {
+			t.Fatalf("Resolve(%s) error = %v", strategy, err)
+		}
+		if !resolved[0].Resolved || resolved[0].Resolution != want {
+			t.Errorf("Resolve(%s) = %+v, want %s", strategy, resolved[0], want)
+		}
+	}
+	if conflicts[0].Resolved {
+		t.Error("Resolve() modified its input")
+	}
+	if _, err := Resolve(conflicts, "prefer-nobody"); err == nil {
+		t.Error("Resolve() accepted an unknown strategy")
+	}
+}
+
+func TestSaveLoadMatches(t *testing.T) {
+	dir := t.TempDir()
+	if r, err := Load(dir); err != nil || r != nil {
+		t.Fatalf("Load() without a file = %v, %v", r, err)
+	}
+
+	conflicts := []types.StackConflict{{Category: "cache", ConfigValue: "redis", PRDHint: "none"}}
+	resolved, err := Resolve(conflicts, config.ResolvePreferPRD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(dir, &types.StackResolution{Resolved: true, Conflicts: resolved}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	saved, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !Matches(saved, conflicts) {
+		t.Errorf("Matches() = false for the saved conflicts %+v", saved)
+	}
+	changed := []types.StackConflict{{Category: "cache", ConfigValue: "memcached", PRDHint: "none"}}
+	if Matches(saved, changed) {
+		t.Error("Matches() = true after the configured value changed")
+	}
+}
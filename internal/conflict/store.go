@@ -0,0 +1,47 @@
+package conflict
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tuannvm/pagent/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ResolutionFile is where the resolution of a run is kept, relative to the
+// output directory, so later runs reuse it without asking again
+const ResolutionFile = ".pagent/stack-resolution.yaml"
+
+// Load reads the saved resolution of an output directory.
+// Returns nil, nil if there is none.
+func Load(outputDir string) (*types.StackResolution, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, ResolutionFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read stack resolution: %w", err)
+	}
+	var r types.StackResolution
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse stack resolution: %w", err)
+	}
+	return &r, nil
+}
+
+// Save writes the resolution of an output directory
+func Save(outputDir string, r *types.StackResolution) error {
+	path := filepath.Join(outputDir, ResolutionFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stack resolution: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write stack resolution: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/events"
+)
+
+// ErrNotRunning is returned when no daemon is listening on the socket
+var ErrNotRunning = errors.New("pagent daemon is not running")
+
+// Client talks to a daemon over its unix socket
+type Client struct {
+	socketPath string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the daemon listening on socketPath
+func NewClient(socketPath string) *Client {
+	dialer := &net.Dialer{Timeout: 2 * time.Second}
+	return &Client{
+		socketPath: socketPath,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					if err := checkSocketDir(socketPath); err != nil {
+						return nil, err
+					}
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+// checkSocketDir refuses to talk to a socket in a directory another user
+// owns, which could serve a fake daemon. A missing directory means no
+// daemon, which the dial reports.
+func checkSocketDir(socketPath string) error {
+	dir := filepath.Dir(socketPath)
+	info, err := os.Lstat(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check socket directory: %w", err)
+	}
+	if err := checkOwner(dir, info); err != nil {
+		return fmt.Errorf("refusing to use daemon socket %s: %w", socketPath, err)
+	}
+	return nil
+}
+
+// Ping checks that the daemon is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/v1/health", nil, nil)
+}
+
+// Submit starts a run with opts
+func (c *Client) Submit(ctx context.Context, opts config.RunOptions) (RunInfo, error) {
+	var info RunInfo
+	err := c.do(ctx, http.MethodPost, "/v1/runs", opts, &info)
+	return info, err
+}
+
+// Runs lists runs, most recent first
+func (c *Client) Runs(ctx context.Context) ([]RunInfo, error) {
+	var resp struct {
+		Runs []RunInfo `json:"runs"`
+	}
+	err := c.do(ctx, http.MethodGet, "/v1/runs", nil, &resp)
+	return resp.Runs, err
+}
+
+// Run returns a run's current state
+func (c *Client) Run(ctx context.Context, id string) (RunInfo, error) {
+	var info RunInfo
+	err := c.do(ctx, http.MethodGet, "/v1/runs/"+url.PathEscape(id), nil, &info)
+	return info, err
+}
+
+// Agents lists agents with a live agentapi process across all runs
+func (c *Client) Agents(ctx context.Context) ([]AgentInfo, error) {
+	var resp struct {
+		Agents []AgentInfo `json:"agents"`
+	}
+	err := c.do(ctx, http.MethodGet, "/v1/agents", nil, &resp)
+	return resp.Agents, err
+}
+
+// ControlRun applies a run action (cancel, pause, resume)
+func (c *Client) ControlRun(ctx context.Context, id, action string) (RunInfo, error) {
+	var info RunInfo
+	path := fmt.Sprintf("/v1/runs/%s/%s", url.PathEscape(id), url.PathEscape(action))
+	err := c.do(ctx, http.MethodPost, path, nil, &info)
+	return info, err
+}
+
+// ControlAgent applies an agent action (stop, restart) within a run
+func (c *Client) ControlAgent(ctx context.Context, runID, agentName, action string) (RunInfo, error) {
+	var info RunInfo
+	path := fmt.Sprintf("/v1/runs/%s/agents/%s/%s", url.PathEscape(runID), url.PathEscape(agentName), url.PathEscape(action))
+	err := c.do(ctx, http.MethodPost, path, nil, &info)
+	return info, err
+}
+
+// Events streams a run's events (past and future) to fn until the run
+// finishes or ctx is cancelled
+func (c *Client) Events(ctx context.Context, id string, fn events.Handler) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://pagent/v1/runs/"+url.PathEscape(id)+"/events", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return c.wrapDialError(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("invalid event from daemon: %w", err)
+		}
+		fn(e)
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// do sends a JSON request and decodes a JSON response into out (if non-nil)
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	// The host is ignored; the transport always dials the socket
+	req, err := http.NewRequestWithContext(ctx, method, "http://pagent"+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return c.wrapDialError(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// wrapDialError maps connection failures to ErrNotRunning
+func (c *Client) wrapDialError(err error) error {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return fmt.Errorf("%w (socket: %s)", ErrNotRunning, c.socketPath)
+	}
+	return err
+}
+
+// decodeError turns an error response into a Go error
+func decodeError(resp *http.Response) error {
+	var e errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+		return fmt.Errorf("daemon returned %s", resp.Status)
+	}
+	return errors.New(e.Error)
+}
@@ -0,0 +1,321 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/events"
+	"github.com/tuannvm/pagent/internal/queue"
+	"github.com/tuannvm/pagent/internal/runner"
+)
+
+// fakeExecute publishes a minimal run lifecycle and finishes when release
+// is closed or the run is cancelled
+func fakeExecute(release chan struct{}) ExecuteFunc {
+	return func(ctx context.Context, opts config.RunOptions, _ runner.Logger, s runner.Session) error {
+		publish := func(e events.Event) {
+			e.RunID = s.RunID
+			s.Bus.Publish(e)
+		}
+
+		started := events.New(events.RunStarted)
+		started.Agents = []string{"architect"}
+		publish(started)
+
+		agentStarted := events.New(events.AgentStarted)
+		agentStarted.Agent = "architect"
+		agentStarted.Port = 3284
+		publish(agentStarted)
+
+		select {
+		case <-release:
+			completed := events.New(events.AgentCompleted)
+			completed.Agent = "architect"
+			completed.OutputPath = "/tmp/architecture.md"
+			publish(completed)
+			publish(events.New(events.RunCompleted))
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func startServer(t *testing.T, release chan struct{}) (*Server, *Client) {
+	t.Helper()
+
+	srv := NewServer("test")
+	srv.execute = fakeExecute(release)
+
+	socket := filepath.Join(t.TempDir(), "d.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe(ctx, socket) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	client := NewClient(socket)
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Ping(context.Background()) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("daemon did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return srv, client
+}
+
+func TestSubmitAndFollow(t *testing.T) {
+	release := make(chan struct{})
+	_, client := startServer(t, release)
+	ctx := context.Background()
+
+	info, err := client.Submit(ctx, config.RunOptions{InputPath: "/tmp/prd.md"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if info.ID == "" || info.State != RunRunning {
+		t.Fatalf("Submit() = %+v, want a running run", info)
+	}
+
+	// Wait until the agent is reported as active
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		agents, err := client.Agents(ctx)
+		if err != nil {
+			t.Fatalf("Agents() error = %v", err)
+		}
+		if len(agents) == 1 && agents[0].Name == "architect" && agents[0].Port == 3284 && agents[0].RunID == info.ID {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Agents() = %+v, want running architect", agents)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+
+	var types []events.Type
+	if err := client.Events(ctx, info.ID, func(e events.Event) { types = append(types, e.Type) }); err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	want := []events.Type{events.RunStarted, events.AgentStarted, events.AgentCompleted, events.RunCompleted}
+	if len(types) != len(want) {
+		t.Fatalf("Events() types = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("event %d = %s, want %s", i, types[i], want[i])
+		}
+	}
+
+	final, err := client.Run(ctx, info.ID)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if final.State != RunSucceeded || final.FinishedAt == nil {
+		t.Errorf("final run = %+v, want succeeded", final)
+	}
+	if len(final.Agents) != 1 || final.Agents[0].Status != AgentCompleted {
+		t.Errorf("final agents = %+v, want architect completed", final.Agents)
+	}
+}
+
+func TestCancelRun(t *testing.T) {
+	_, client := startServer(t, make(chan struct{}))
+	ctx := context.Background()
+
+	info, err := client.Submit(ctx, config.RunOptions{InputPath: "/tmp/prd.md"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// No manager was created by the fake executor, so pause is rejected
+	if _, err := client.ControlRun(ctx, info.ID, ActionPause); err == nil {
+		t.Error("ControlRun(pause) should fail before agents start")
+	}
+
+	if _, err := client.ControlRun(ctx, info.ID, ActionCancel); err != nil {
+		t.Fatalf("ControlRun(cancel) error = %v", err)
+	}
+	if err := client.Events(ctx, info.ID, func(events.Event) {}); err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+
+	final, err := client.Run(ctx, info.ID)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if final.State != RunCancelled {
+		t.Errorf("State = %s, want %s", final.State, RunCancelled)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	_, client := startServer(t, make(chan struct{}))
+	ctx := context.Background()
+
+	if _, err := client.Run(ctx, "missing"); err == nil {
+		t.Error("Run() of unknown ID should fail")
+	}
+	if _, err := client.Submit(ctx, config.RunOptions{}); err == nil {
+		t.Error("Submit() without input should fail")
+	}
+
+	down := NewClient(filepath.Join(t.TempDir(), "none.sock"))
+	if err := down.Ping(ctx); err == nil {
+		t.Error("Ping() without a daemon should fail")
+	}
+}
+
+func TestQueuedJobRunsAsDaemonRun(t *testing.T) {
+	release := make(chan struct{})
+	srv, client := startServer(t, release)
+	ctx := context.Background()
+
+	q, err := queue.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("queue.Open() error = %v", err)
+	}
+	serveCtx, cancel := context.WithCancel(ctx)
+	served := make(chan error, 1)
+	go func() { served <- q.Serve(serveCtx, 1, srv.ExecuteJob) }()
+	t.Cleanup(func() {
+		cancel()
+		<-served
+	})
+
+	job, err := q.Enqueue(context.Background(), config.RunOptions{InputPath: "/tmp/prd.md"}, 0, "mcp", "test")
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	// The job shows up as a daemon run under its job ID
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		info, err := client.Run(ctx, job.ID)
+		if err == nil && info.State == RunRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Run(%s) = %+v, %v; want running", job.ID, info, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+	final, err := q.Wait(waitCtx, job.ID)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if final.State != queue.StateSucceeded || len(final.Agents) != 1 || final.Agents[0].Name != "architect" {
+		t.Errorf("final job = %+v, want succeeded with architect result", final)
+	}
+}
+
+func TestServerResolveDefault(t *testing.T) {
+	var got []string
+	srv := NewServer("test").WithResolve(config.ResolveFail)
+	srv.execute = func(_ context.Context, _ config.RunOptions, _ runner.Logger, s runner.Session) error {
+		got = append(got, s.Resolve)
+		return nil
+	}
+
+	ctx := context.Background()
+	_ = srv.ExecuteJob(ctx, queue.Job{ID: "job-1"}, runner.Session{RunID: "job-1"})
+	_ = srv.ExecuteJob(ctx, queue.Job{ID: "job-2"}, runner.Session{RunID: "job-2", Resolve: config.ResolvePreferPRD})
+
+	want := []string{config.ResolveFail, config.ResolvePreferPRD}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Session.Resolve = %v, want %v", got, want)
+	}
+}
+
+func TestListenAndServeSocketPermissions(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv(SocketEnv, "")
+	socket := DefaultSocketPath()
+
+	srv := NewServer("test")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe(ctx, socket) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	client := NewClient(socket)
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Ping(context.Background()) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("daemon did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for path, want := range map[string]os.FileMode{filepath.Dir(socket): 0700, socket: 0600} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s mode = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestListenAndServeRejectsSharedSocketDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv(SocketEnv, "")
+	socket := DefaultSocketPath()
+
+	// Another user could have created the directory first
+	if err := os.Mkdir(filepath.Dir(socket), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Dir(socket), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewServer("test").ListenAndServe(context.Background(), socket)
+	if err == nil || !strings.Contains(err.Error(), "0700") {
+		t.Errorf("ListenAndServe() error = %v, want the shared directory rejected", err)
+	}
+}
+
+func TestSocketDirOwnedByAnotherUser(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("needs root to create a directory owned by another user")
+	}
+	dir := filepath.Join(t.TempDir(), "pagent")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	// A private directory planted by another user
+	if err := os.Chown(dir, 65534, 65534); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "daemon.sock")
+
+	err := NewServer("test").ListenAndServe(context.Background(), socket)
+	if err == nil || !strings.Contains(err.Error(), "not the current user") {
+		t.Errorf("ListenAndServe() error = %v, want the foreign directory rejected", err)
+	}
+
+	_, err = NewClient(socket).Runs(context.Background())
+	if err == nil || errors.Is(err, ErrNotRunning) || !strings.Contains(err.Error(), "not the current user") {
+		t.Errorf("client error = %v, want the foreign directory rejected", err)
+	}
+}
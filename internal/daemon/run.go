@@ -0,0 +1,202 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/agent"
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/events"
+)
+
+// run tracks one pipeline run owned by the daemon
+type run struct {
+	mu      sync.Mutex
+	info    RunInfo
+	agents  map[string]*AgentInfo
+	order   []string       // Agent names in the order they were requested
+	log     []events.Event // Every event published for the run
+	changed chan struct{}  // Closed and replaced whenever log grows
+	done    chan struct{}  // Closed when the run finishes
+	cancel  context.CancelFunc
+	manager *agent.Manager // Set once the runner has created it
+}
+
+func newRun(id string, opts config.RunOptions, cancel context.CancelFunc) *run {
+	r := &run{
+		info: RunInfo{
+			ID:          id,
+			Input:       opts.InputPath,
+			State:       RunRunning,
+			SubmittedAt: time.Now().UTC(),
+		},
+		agents:  make(map[string]*AgentInfo),
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+		cancel:  cancel,
+	}
+	for _, name := range opts.Agents {
+		r.agent(name)
+	}
+	return r
+}
+
+// agent returns the tracked agent, adding it if needed. Caller holds r.mu.
+func (r *run) agent(name string) *AgentInfo {
+	a, ok := r.agents[name]
+	if !ok {
+		a = &AgentInfo{Name: name, RunID: r.info.ID, Status: AgentPending}
+		r.agents[name] = a
+		r.order = append(r.order, name)
+	}
+	return a
+}
+
+// handle records e and updates agent statuses. It is an events.Handler.
+func (r *run) handle(e events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch e.Type {
+	case events.RunStarted:
+		for _, name := range e.Agents {
+			r.agent(name)
+		}
+	case events.AgentStarted:
+		a := r.agent(e.Agent)
+		a.Status, a.Port, a.Error = AgentStarting, e.Port, ""
+	case events.AgentStatusChanged:
+		r.agent(e.Agent).Status = e.Status
+	case events.AgentRestarted:
+		r.agent(e.Agent).Status = AgentRestarting
+	case events.AgentCompleted:
+		a := r.agent(e.Agent)
+		a.Status, a.OutputPath = AgentCompleted, e.OutputPath
+	case events.AgentFailed:
+		a := r.agent(e.Agent)
+		a.Status, a.Error = AgentFailed, e.Error
+	case events.AgentSkipped:
+		a := r.agent(e.Agent)
+		a.Status, a.OutputPath = AgentSkipped, e.OutputPath
+	}
+
+	r.log = append(r.log, e)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// setManager is the runner.Session OnManager hook
+func (r *run) setManager(m *agent.Manager) {
+	r.mu.Lock()
+	r.manager = m
+	r.mu.Unlock()
+}
+
+// finish records the run's outcome and wakes event followers
+func (r *run) finish(err error) {
+	r.mu.Lock()
+	now := time.Now().UTC()
+	r.info.FinishedAt = &now
+	switch {
+	case err == nil:
+		r.info.State = RunSucceeded
+	case errors.Is(err, context.Canceled):
+		r.info.State = RunCancelled
+		r.info.Error = err.Error()
+	default:
+		r.info.State = RunFailed
+		r.info.Error = err.Error()
+	}
+	r.mu.Unlock()
+
+	close(r.done)
+}
+
+// snapshot returns a copy of the run's current state
+func (r *run) snapshot() RunInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info := r.info
+	if r.manager != nil && !info.Done() {
+		info.Paused = r.manager.Paused()
+	}
+	info.Agents = make([]AgentInfo, 0, len(r.order))
+	for _, name := range r.order {
+		info.Agents = append(info.Agents, *r.agents[name])
+	}
+	return info
+}
+
+// eventsSince returns events from index i onwards, a channel closed when
+// more arrive, and whether the run has finished
+func (r *run) eventsSince(i int) ([]events.Event, <-chan struct{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []events.Event
+	if i < len(r.log) {
+		out = append(out, r.log[i:]...)
+	}
+	select {
+	case <-r.done:
+		return out, r.changed, true
+	default:
+		return out, r.changed, false
+	}
+}
+
+// control applies a run-level action
+func (r *run) control(action string) error {
+	if action == ActionCancel {
+		r.cancel()
+		return nil
+	}
+
+	m, err := r.activeManager()
+	if err != nil {
+		return err
+	}
+	switch action {
+	case ActionPause:
+		m.Pause()
+	case ActionResume:
+		m.Resume()
+	default:
+		return fmt.Errorf("unknown run action %q", action)
+	}
+	return nil
+}
+
+// controlAgent applies an agent-level action
+func (r *run) controlAgent(name, action string) error {
+	m, err := r.activeManager()
+	if err != nil {
+		return err
+	}
+	switch action {
+	case ActionStop:
+		return m.CancelAgent(name)
+	case ActionRestart:
+		return m.RestartAgent(name)
+	default:
+		return fmt.Errorf("unknown agent action %q", action)
+	}
+}
+
+// activeManager returns the run's manager if the run is still in progress
+func (r *run) activeManager() (*agent.Manager, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.info.Done() {
+		return nil, fmt.Errorf("run %s has already finished", r.info.ID)
+	}
+	if r.manager == nil {
+		return nil, fmt.Errorf("run %s has not started agents yet", r.info.ID)
+	}
+	return r.manager, nil
+}
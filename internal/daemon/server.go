@@ -0,0 +1,423 @@
+// Package daemon implements `pagent daemon`: a long-lived process that owns
+// agent managers for submitted runs and exposes a local JSON API over a unix
+// socket. The CLI uses Client to submit runs and to control running agents.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/audit"
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/events"
+	"github.com/tuannvm/pagent/internal/queue"
+	"github.com/tuannvm/pagent/internal/runner"
+	"github.com/tuannvm/pagent/internal/state"
+)
+
+// maxFinishedRuns bounds how many finished runs are kept for inspection
+const maxFinishedRuns = 50
+
+// ExecuteFunc runs a pipeline; runner.ExecuteSession in production
+type ExecuteFunc func(ctx context.Context, opts config.RunOptions, logger runner.Logger, s runner.Session) error
+
+// Server owns the runs submitted to the daemon
+type Server struct {
+	version string
+	execute ExecuteFunc
+	resolve string              // Stack conflict strategy for runs that don't set one
+	audit   *config.AuditConfig // Audit log of the daemon's config (nil = each run's)
+
+	mu    sync.Mutex
+	runs  map[string]*run
+	order []string // Run IDs in submission order
+
+	ctx    context.Context // Parent of every run; cancelled on shutdown
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewServer creates a daemon server that executes runs with the shared runner
+func NewServer(version string) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		version: version,
+		execute: runner.ExecuteSession,
+		runs:    make(map[string]*run),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// WithResolve sets the strategy for PRD-vs-config stack conflicts in runs
+// that don't choose one. The daemon can't ask, so config.ResolveFail makes
+// such runs fail instead of going ahead with the conflicts unresolved.
+func (s *Server) WithResolve(strategy string) *Server {
+	s.resolve = strategy
+	return s
+}
+
+// WithAudit records runs in the audit log of the daemon's own config, not
+// in the one named by a run's config_path, which the submitter controls
+func (s *Server) WithAudit(cfg config.AuditConfig) *Server {
+	s.audit = &cfg
+	return s
+}
+
+// Submit starts a run in the background and returns its initial state
+func (s *Server) Submit(opts config.RunOptions) (RunInfo, error) {
+	if opts.InputPath == "" {
+		return RunInfo{}, fmt.Errorf("input_path is required")
+	}
+
+	id := state.NewRunID()
+	ctx, cancel := context.WithCancel(s.ctx)
+	r := s.track(id, opts, cancel)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		_ = s.executeRun(ctx, r, opts, runner.Session{RunID: id, Bus: events.NewBus(), Source: audit.SourceDaemon})
+	}()
+
+	return r.snapshot(), nil
+}
+
+// ExecuteJob runs a queued job as a daemon run, so it can be listed and
+// controlled with 'pagent runs' while it executes. It is the daemon's
+// queue.ExecuteFunc.
+func (s *Server) ExecuteJob(ctx context.Context, job queue.Job, session runner.Session) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r := s.track(job.ID, job.Options, cancel)
+	return s.executeRun(ctx, r, job.Options, session)
+}
+
+// track registers a new run under id
+func (s *Server) track(id string, opts config.RunOptions, cancel context.CancelFunc) *run {
+	r := newRun(id, opts, cancel)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.runs[id]; ok {
+		// A requeued job runs again under the same ID: replace the old entry
+		for i, existing := range s.order {
+			if existing == id {
+				s.order = append(s.order[:i], s.order[i+1:]...)
+				break
+			}
+		}
+	}
+	s.runs[id] = r
+	s.order = append(s.order, id)
+	s.pruneLocked()
+	return r
+}
+
+// executeRun runs a tracked run to completion and records its outcome
+func (s *Server) executeRun(ctx context.Context, r *run, opts config.RunOptions, session runner.Session) error {
+	// Runs share the daemon's stdout, so per-run stdout streams and metrics
+	// listeners are not supported; use the events endpoint instead
+	opts.EventFormat = ""
+	opts.MetricsAddr = ""
+
+	if session.Bus == nil {
+		session.Bus = events.NewBus()
+	}
+	session.Bus.Subscribe(r.handle)
+	session.OnManager = r.setManager
+	session.NoSignals = true
+	if session.Resolve == "" {
+		session.Resolve = s.resolve
+	}
+	if session.Audit == nil {
+		session.Audit = s.audit
+	}
+
+	logger := runner.NewRunLogger(session.RunID, opts)
+	err := s.execute(ctx, opts, logger, session)
+	if err != nil {
+		logger.Error("%v", err)
+	}
+	r.finish(err)
+	return err
+}
+
+// pruneLocked drops the oldest finished runs beyond maxFinishedRuns.
+// Caller holds s.mu.
+func (s *Server) pruneLocked() {
+	finished := 0
+	for i := len(s.order) - 1; i >= 0; i-- {
+		id := s.order[i]
+		if !s.runs[id].snapshot().Done() {
+			continue
+		}
+		finished++
+		if finished > maxFinishedRuns {
+			delete(s.runs, id)
+			s.order = append(s.order[:i], s.order[i+1:]...)
+		}
+	}
+}
+
+// getRun looks up a run by ID
+func (s *Server) getRun(id string) (*run, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.runs[id]
+	return r, ok
+}
+
+// listRuns returns snapshots of all runs, most recent first
+func (s *Server) listRuns() []RunInfo {
+	s.mu.Lock()
+	runs := make([]*run, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		runs = append(runs, s.runs[s.order[i]])
+	}
+	s.mu.Unlock()
+
+	infos := make([]RunInfo, 0, len(runs))
+	for _, r := range runs {
+		infos = append(infos, r.snapshot())
+	}
+	return infos
+}
+
+// Handler returns the daemon's HTTP API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/health", s.handleHealth)
+	mux.HandleFunc("GET /v1/runs", s.handleListRuns)
+	mux.HandleFunc("POST /v1/runs", s.handleSubmit)
+	mux.HandleFunc("GET /v1/runs/{id}", s.handleGetRun)
+	mux.HandleFunc("GET /v1/runs/{id}/events", s.handleEvents)
+	mux.HandleFunc("POST /v1/runs/{id}/{action}", s.handleRunAction)
+	mux.HandleFunc("POST /v1/runs/{id}/agents/{agent}/{action}", s.handleAgentAction)
+	mux.HandleFunc("GET /v1/agents", s.handleListAgents)
+	return mux
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": s.version})
+}
+
+func (s *Server) handleListRuns(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"runs": s.listRuns()})
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var opts config.RunOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid run options: %w", err))
+		return
+	}
+	info, err := s.Submit(opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, info)
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.getRun(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, run.snapshot())
+}
+
+// handleEvents streams the run's events as NDJSON, following new events
+// until the run finishes or the client disconnects
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.getRun(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", r.PathValue("id")))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	writer := events.NewNDJSONWriter(w)
+
+	next := 0
+	for {
+		batch, changed, done := run.eventsSince(next)
+		for _, e := range batch {
+			writer.Handle(e)
+		}
+		next += len(batch)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-run.done:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) handleRunAction(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.getRun(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", r.PathValue("id")))
+		return
+	}
+	if err := run.control(r.PathValue("action")); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run.snapshot())
+}
+
+func (s *Server) handleAgentAction(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.getRun(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", r.PathValue("id")))
+		return
+	}
+	if err := run.controlAgent(r.PathValue("agent"), r.PathValue("action")); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run.snapshot())
+}
+
+// handleListAgents returns agents with a live agentapi process across all runs
+func (s *Server) handleListAgents(w http.ResponseWriter, _ *http.Request) {
+	agents := []AgentInfo{}
+	for _, info := range s.listRuns() {
+		for _, a := range info.Agents {
+			if a.Active() {
+				agents = append(agents, a)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"agents": agents})
+}
+
+// ListenAndServe serves the API on a unix socket until ctx is cancelled,
+// then cancels all runs and waits for them to stop agents
+func (s *Server) ListenAndServe(ctx context.Context, socketPath string) error {
+	if err := prepareSocketDir(filepath.Dir(socketPath)); err != nil {
+		return err
+	}
+	if err := removeStaleSocket(socketPath); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	defer func() { _ = os.Remove(socketPath) }()
+	// The directory already keeps others out; this covers custom paths
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		_ = ln.Close()
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		s.Shutdown()
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(shutdownCtx)
+	s.Shutdown()
+	return nil
+}
+
+// Shutdown cancels every run and waits for them to finish
+func (s *Server) Shutdown() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// prepareSocketDir creates the socket's directory with mode 0700. The
+// directory must belong to the current user, so another user can't plant
+// one (e.g. in the shared temp directory) and intercept the socket. The
+// default directory must also be private to its owner.
+func prepareSocketDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check socket directory: %w", err)
+	}
+	if err := checkOwner(dir, info); err != nil {
+		return err
+	}
+	if dir == defaultSocketDir() && (!info.IsDir() || info.Mode().Perm()&0077 != 0) {
+		return fmt.Errorf("socket directory %s must be a directory only its owner can access (mode 0700)", dir)
+	}
+	return nil
+}
+
+// checkOwner returns an error unless path, described by info, belongs to
+// the current user
+func checkOwner(path string, info os.FileInfo) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("failed to check the owner of %s", path)
+	}
+	if int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("%s belongs to uid %d, not the current user (uid %d)", path, st.Uid, os.Getuid())
+	}
+	return nil
+}
+
+// removeStaleSocket deletes a socket file left behind by a dead daemon
+func removeStaleSocket(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SocketEnv overrides the default socket path
+const SocketEnv = "PAGENT_SOCKET"
+
+// Run states
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+	RunCancelled = "cancelled"
+)
+
+// Run control actions (POST /v1/runs/{id}/{action})
+const (
+	ActionCancel = "cancel"
+	ActionPause  = "pause"
+	ActionResume = "resume"
+)
+
+// Agent control actions (POST /v1/runs/{id}/agents/{agent}/{action})
+const (
+	ActionStop    = "stop"
+	ActionRestart = "restart"
+)
+
+// Agent statuses reported by the daemon, derived from lifecycle events.
+// While an agent is working its agentapi status ("running", "stable") is used.
+const (
+	AgentPending    = "pending"
+	AgentStarting   = "starting"
+	AgentRestarting = "restarting"
+	AgentCompleted  = "completed"
+	AgentFailed     = "failed"
+	AgentSkipped    = "skipped"
+)
+
+// RunInfo describes a run owned by the daemon
+type RunInfo struct {
+	ID          string      `json:"id"`
+	Input       string      `json:"input"`
+	State       string      `json:"state"`
+	Paused      bool        `json:"paused,omitempty"`
+	SubmittedAt time.Time   `json:"submitted_at"`
+	FinishedAt  *time.Time  `json:"finished_at,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	Agents      []AgentInfo `json:"agents"`
+}
+
+// Done reports whether the run has finished
+func (r RunInfo) Done() bool {
+	return r.State != RunRunning
+}
+
+// AgentInfo describes an agent within a daemon run
+type AgentInfo struct {
+	Name       string `json:"name"`
+	RunID      string `json:"run_id"`
+	Status     string `json:"status"`
+	Port       int    `json:"port,omitempty"`
+	OutputPath string `json:"output_path,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Active reports whether the agent has a live agentapi process
+func (a AgentInfo) Active() bool {
+	switch a.Status {
+	case AgentPending, AgentCompleted, AgentFailed, AgentSkipped:
+		return false
+	}
+	return a.Port != 0
+}
+
+// errorResponse is the body of non-2xx API responses
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// DefaultSocketPath returns $PAGENT_SOCKET, or a socket in the per-user
+// directory under the temp directory
+func DefaultSocketPath() string {
+	if p := os.Getenv(SocketEnv); p != "" {
+		return p
+	}
+	return filepath.Join(defaultSocketDir(), "daemon.sock")
+}
+
+// defaultSocketDir is the per-user directory holding the default socket.
+// The daemon creates it with mode 0700, so other users can't connect.
+func defaultSocketDir() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("pagent-%d", os.Getuid()))
+}
@@ -0,0 +1,423 @@
+// Package detect infers the language and tech stack of an existing codebase
+// from the files it contains.
+package detect
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tuannvm/pagent/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// maxDepth limits how far below the root marker files are searched for
+const maxDepth = 2
+
+// skipDirs are never searched: dependencies, build output and VCS metadata
+var skipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+	".venv":        true,
+	"venv":         true,
+	"outputs":      true,
+}
+
+// Result is what was detected in a codebase. Fields that were not detected
+// are left empty.
+type Result struct {
+	Language      string          // go, python, typescript, java or rust
+	Stack         types.TechStack // Detected stack fields only
+	Containerized bool            // A Dockerfile was found
+
+	// Evidence maps each detected setting, keyed like the config file
+	// (e.g. "stack.ci"), to what revealed it
+	Evidence map[string]Evidence
+}
+
+// Evidence is the file that revealed a setting, relative to the root, and
+// the dependency or image in it when the file alone does not
+type Evidence struct {
+	File   string
+	Detail string // e.g. "github.com/jackc/pgx" in go.mod
+}
+
+func (e Evidence) String() string {
+	if e.Detail == "" {
+		return e.File
+	}
+	return e.File + ": " + e.Detail
+}
+
+// Setting is a detected config setting
+type Setting struct {
+	Key      string // Dotted config key, e.g. "stack.database"
+	Value    string
+	Evidence Evidence
+}
+
+// Empty reports whether nothing was detected
+func (r *Result) Empty() bool {
+	return len(r.Evidence) == 0
+}
+
+// Settings returns the detected settings sorted by key
+func (r *Result) Settings() []Setting {
+	stack := reflect.ValueOf(r.Stack)
+	settings := make([]Setting, 0, len(r.Evidence))
+	for key, evidence := range r.Evidence {
+		var value string
+		switch key {
+		case "preferences.language":
+			value = r.Language
+		case "preferences.containerized":
+			value = "true"
+		default:
+			name := strings.TrimPrefix(key, "stack.")
+			for i := 0; i < stack.NumField(); i++ {
+				if strings.Split(stack.Type().Field(i).Tag.Get("yaml"), ",")[0] == name {
+					value = stack.Field(i).String()
+				}
+			}
+		}
+		settings = append(settings, Setting{Key: key, Value: value, Evidence: evidence})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+// Detect scans dir for marker files such as go.mod, package.json,
+// Dockerfiles, Terraform files and CI definitions
+func Detect(dir string) (*Result, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Result{Evidence: map[string]Evidence{}}
+	s := scan{dir: dir, files: files, result: r}
+	s.language()
+	s.containers()
+	s.ci()
+	s.iac()
+	s.kubernetes()
+	// Dependencies name what the code talks to; compose services may only
+	// be local tooling, so they fill in what dependencies do not
+	s.dependencies()
+	s.compose()
+	return r, nil
+}
+
+type scan struct {
+	dir    string
+	files  []string // Paths relative to dir, slash-separated, shallowest first
+	result *Result
+}
+
+// find returns the first file whose path matches, or ""
+func (s *scan) find(match func(path, name string) bool) string {
+	for _, f := range s.files {
+		if match(f, filepath.Base(f)) {
+			return f
+		}
+	}
+	return ""
+}
+
+// findName returns the first file with one of the names, or ""
+func (s *scan) findName(names ...string) string {
+	return s.find(func(_, name string) bool {
+		for _, n := range names {
+			if name == n {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// read returns the content of a file relative to the root
+func (s *scan) read(path string) string {
+	data, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(path)))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// set records a detected setting revealed by a file
+func (s *scan) set(key, file string, apply func()) {
+	s.setEvidence(key, Evidence{File: file}, apply)
+}
+
+// setEvidence records a detected setting unless the file is empty or the
+// setting was already detected
+func (s *scan) setEvidence(key string, evidence Evidence, apply func()) {
+	if evidence.File == "" {
+		return
+	}
+	if _, ok := s.result.Evidence[key]; ok {
+		return
+	}
+	apply()
+	s.result.Evidence[key] = evidence
+}
+
+// setStack records a detected stack field by its yaml key
+func (s *scan) setStack(key, value string, evidence Evidence) {
+	s.setEvidence("stack."+key, evidence, func() {
+		stack := reflect.ValueOf(&s.result.Stack).Elem()
+		for i := 0; i < stack.NumField(); i++ {
+			if strings.Split(stack.Type().Field(i).Tag.Get("yaml"), ",")[0] == key {
+				stack.Field(i).SetString(value)
+			}
+		}
+	})
+}
+
+func (s *scan) language() {
+	markers := []struct {
+		language string
+		names    []string
+	}{
+		{"go", []string{"go.mod"}},
+		{"rust", []string{"Cargo.toml"}},
+		{"python", []string{"pyproject.toml", "requirements.txt", "setup.py", "Pipfile"}},
+		{"java", []string{"pom.xml", "build.gradle", "build.gradle.kts"}},
+	}
+	for _, m := range markers {
+		if f := s.findName(m.names...); f != "" {
+			s.set("preferences.language", f, func() { s.result.Language = m.language })
+			return
+		}
+	}
+
+	// Node projects count as TypeScript only when they use it
+	if f := s.findName("tsconfig.json"); f != "" {
+		s.set("preferences.language", f, func() { s.result.Language = "typescript" })
+	} else if f := s.findName("package.json"); f != "" && strings.Contains(s.read(f), `"typescript"`) {
+		s.set("preferences.language", f, func() { s.result.Language = "typescript" })
+	}
+}
+
+func (s *scan) containers() {
+	f := s.find(func(_, name string) bool {
+		return name == "Dockerfile" || strings.HasPrefix(name, "Dockerfile.") || strings.HasSuffix(name, ".Dockerfile")
+	})
+	s.set("preferences.containerized", f, func() { s.result.Containerized = true })
+}
+
+func (s *scan) ci() {
+	if f := s.find(func(path, _ string) bool { return strings.HasPrefix(path, ".github/workflows/") }); f != "" {
+		s.set("stack.ci", f, func() { s.result.Stack.CI = "github-actions" })
+	} else if f := s.findName(".gitlab-ci.yml"); f != "" {
+		s.set("stack.ci", f, func() { s.result.Stack.CI = "gitlab-ci" })
+	} else if f := s.findName("Jenkinsfile"); f != "" {
+		s.set("stack.ci", f, func() { s.result.Stack.CI = "jenkins" })
+	}
+}
+
+func (s *scan) iac() {
+	tf := s.find(func(_, name string) bool { return strings.HasSuffix(name, ".tf") })
+	if tf != "" {
+		s.set("stack.iac", tf, func() { s.result.Stack.IaC = "terraform" })
+	} else if f := s.findName("Pulumi.yaml", "Pulumi.yml"); f != "" {
+		s.set("stack.iac", f, func() { s.result.Stack.IaC = "pulumi" })
+	}
+
+	// The Terraform provider names the cloud
+	providers := []struct{ provider, cloud string }{
+		{`provider "aws"`, "aws"},
+		{`provider "google"`, "gcp"},
+		{`provider "azurerm"`, "azure"},
+	}
+	for _, f := range s.files {
+		if !strings.HasSuffix(f, ".tf") {
+			continue
+		}
+		content := s.read(f)
+		for _, p := range providers {
+			if strings.Contains(content, p.provider) {
+				s.setStack("cloud", p.cloud, Evidence{File: f, Detail: p.provider})
+				return
+			}
+		}
+	}
+}
+
+// kubernetes detects Helm charts and kustomize overlays
+func (s *scan) kubernetes() {
+	if f := s.findName("Chart.yaml", "kustomization.yaml", "kustomization.yml", "Kustomization"); f != "" {
+		s.setStack("compute", "kubernetes", Evidence{File: f})
+	}
+}
+
+// dependencyManifests are the files whose dependencies are searched
+var dependencyManifests = []string{
+	"go.mod", "package.json", "pyproject.toml", "requirements.txt", "Pipfile", "setup.py",
+	"pom.xml", "build.gradle", "build.gradle.kts", "Cargo.toml",
+}
+
+// drivers maps client libraries, as named in dependency manifests, to the
+// stack setting they imply
+var drivers = []struct {
+	key, value string
+	names      []string
+}{
+	{"database", "postgres", []string{"github.com/lib/pq", "github.com/jackc/pgx", "pg", "postgres", "psycopg", "psycopg2", "psycopg2-binary", "asyncpg", "org.postgresql", "tokio-postgres"}},
+	{"database", "mysql", []string{"github.com/go-sql-driver/mysql", "mysql", "mysql2", "pymysql", "mysqlclient", "mysql-connector-python", "mysql-connector-java", "com.mysql"}},
+	{"database", "mongodb", []string{"go.mongodb.org/mongo-driver", "mongodb", "mongoose", "pymongo", "motor", "org.mongodb"}},
+	{"cache", "redis", []string{"github.com/redis/go-redis", "github.com/go-redis/redis", "github.com/gomodule/redigo", "redis", "ioredis", "jedis", "lettuce-core"}},
+	{"cache", "memcached", []string{"github.com/bradfitz/gomemcache", "memjs", "pymemcache", "python-memcached"}},
+	{"message_queue", "kafka", []string{"github.com/segmentio/kafka-go", "github.com/IBM/sarama", "github.com/Shopify/sarama", "github.com/confluentinc/confluent-kafka-go", "kafkajs", "kafka-python", "confluent-kafka", "aiokafka", "kafka-clients", "spring-kafka", "rdkafka"}},
+	{"message_queue", "rabbitmq", []string{"github.com/rabbitmq/amqp091-go", "github.com/streadway/amqp", "amqplib", "pika", "aio-pika", "amqp-client", "lapin"}},
+	{"message_queue", "nats", []string{"github.com/nats-io/nats.go", "nats", "nats-py", "async-nats"}},
+	{"message_queue", "sqs", []string{"github.com/aws/aws-sdk-go-v2/service/sqs", "@aws-sdk/client-sqs"}},
+	{"search", "elasticsearch", []string{"github.com/elastic/go-elasticsearch", "@elastic/elasticsearch", "elasticsearch"}},
+	{"search", "opensearch", []string{"github.com/opensearch-project/opensearch-go", "@opensearch-project/opensearch", "opensearch-py"}},
+}
+
+// dependencies detects databases, caches, queues and search engines from
+// the client libraries in dependency manifests
+func (s *scan) dependencies() {
+	for _, f := range s.files {
+		if !contains(dependencyManifests, path.Base(f)) {
+			continue
+		}
+		content := s.read(f)
+		for _, d := range drivers {
+			for _, name := range d.names {
+				if mentions(content, name) {
+					s.setStack(d.key, d.value, Evidence{File: f, Detail: name})
+					break
+				}
+			}
+		}
+	}
+}
+
+// images maps container image names to the stack setting they imply
+var images = map[string]struct{ key, value string }{
+	"postgres":      {"database", "postgres"},
+	"postgresql":    {"database", "postgres"},
+	"postgis":       {"database", "postgres"},
+	"mysql":         {"database", "mysql"},
+	"mariadb":       {"database", "mysql"},
+	"mongo":         {"database", "mongodb"},
+	"mongodb":       {"database", "mongodb"},
+	"redis":         {"cache", "redis"},
+	"valkey":        {"cache", "redis"},
+	"memcached":     {"cache", "memcached"},
+	"kafka":         {"message_queue", "kafka"},
+	"cp-kafka":      {"message_queue", "kafka"},
+	"redpanda":      {"message_queue", "kafka"},
+	"rabbitmq":      {"message_queue", "rabbitmq"},
+	"nats":          {"message_queue", "nats"},
+	"elasticsearch": {"search", "elasticsearch"},
+	"opensearch":    {"search", "opensearch"},
+}
+
+// compose detects services from the images in Docker Compose files
+func (s *scan) compose() {
+	for _, f := range s.files {
+		switch path.Base(f) {
+		case "docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml":
+		default:
+			continue
+		}
+		s.set("preferences.containerized", f, func() { s.result.Containerized = true })
+
+		var file struct {
+			Services map[string]struct {
+				Image string `yaml:"image"`
+			} `yaml:"services"`
+		}
+		if err := yaml.Unmarshal([]byte(s.read(f)), &file); err != nil {
+			continue
+		}
+		names := make([]string, 0, len(file.Services))
+		for name := range file.Services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			image := file.Services[name].Image
+			// bitnami/postgresql:16 -> postgresql
+			base := path.Base(strings.SplitN(image, ":", 2)[0])
+			if m, ok := images[base]; ok {
+				s.setStack(m.key, m.value, Evidence{File: f, Detail: image})
+			}
+		}
+	}
+}
+
+// mentions reports whether content names a dependency as a whole word,
+// so "redis" does not match "go-redis" and "pg" does not match "pgx"
+func mentions(content, name string) bool {
+	for i := 0; ; {
+		j := strings.Index(content[i:], name)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(name)
+		if (start == 0 || !isNameChar(content[start-1])) && (end == len(content) || !isNameChar(content[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.'
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// listFiles returns the files up to maxDepth below dir, shallowest first.
+// Hidden directories other than .github are skipped.
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	level := []string{""}
+	for depth := 0; depth <= maxDepth && len(level) > 0; depth++ {
+		var next []string
+		for _, rel := range level {
+			entries, err := os.ReadDir(filepath.Join(dir, filepath.FromSlash(rel)))
+			if err != nil {
+				if rel == "" {
+					return nil, err
+				}
+				continue
+			}
+			for _, e := range entries {
+				path := e.Name()
+				if rel != "" {
+					path = rel + "/" + e.Name()
+				}
+				if !e.IsDir() {
+					files = append(files, path)
+				} else if !skipDirs[e.Name()] && (!strings.HasPrefix(e.Name(), ".") || path == ".github") {
+					next = append(next, path)
+				}
+			}
+		}
+		level = next
+	}
+	return files, nil
+}
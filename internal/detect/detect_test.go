@@ -0,0 +1,143 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                    "module example.com/app\n",
+		"deploy/Dockerfile":         "FROM scratch\n",
+		".github/workflows/ci.yml":  "on: push\n",
+		"infra/main.tf":             "",
+		"node_modules/x/Cargo.toml": "",
+	})
+
+	r, err := Detect(dir)
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if r.Language != "go" || r.Stack.CI != "github-actions" || r.Stack.IaC != "terraform" || !r.Containerized {
+		t.Errorf("Detect() = %+v", r)
+	}
+	want := map[string]string{
+		"preferences.language":      "go.mod",
+		"preferences.containerized": "deploy/Dockerfile",
+		"stack.ci":                  ".github/workflows/ci.yml",
+		"stack.iac":                 "infra/main.tf",
+	}
+	for key, file := range want {
+		if r.Evidence[key].File != file {
+			t.Errorf("Evidence[%q] = %q, want %q", key, r.Evidence[key].File, file)
+		}
+	}
+}
+
+func TestDetectTypeScript(t *testing.T) {
+	tests := map[string]struct {
+		files map[string]string
+		want  string
+	}{
+		"tsconfig":           {map[string]string{"tsconfig.json": "{}"}, "typescript"},
+		"typescript dep":     {map[string]string{"package.json": `{"devDependencies": {"typescript": "^5"}}`}, "typescript"},
+		"plain node project": {map[string]string{"package.json": `{"dependencies": {"express": "^4"}}`}, ""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			r, err := Detect(dir)
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if r.Language != tt.want {
+				t.Errorf("Language = %q, want %q", r.Language, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectEmpty(t *testing.T) {
+	r, err := Detect(t.TempDir())
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if !r.Empty() {
+		t.Errorf("Empty() = false, evidence %v", r.Evidence)
+	}
+	if _, err := Detect(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Detect() of a missing directory succeeded")
+	}
+}
+
+func TestDetectDependencies(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                    "module example.com/app\n\nrequire (\n\tgithub.com/jackc/pgx/v5 v5.5.0\n\tgithub.com/redis/go-redis/v9 v9.0.0\n)\n",
+		"docker-compose.yml":        "services:\n  db:\n    image: mysql:8\n  queue:\n    image: confluentinc/cp-kafka:7.6.0\n",
+		"deploy/kustomization.yaml": "resources: []\n",
+		"infra/main.tf":             "provider \"google\" {}\n",
+	})
+
+	r, err := Detect(dir)
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+
+	// The driver in go.mod wins over the database image in the compose file
+	want := map[string]string{
+		"preferences.containerized": "true",
+		"stack.cache":               "redis",
+		"stack.cloud":               "gcp",
+		"stack.compute":             "kubernetes",
+		"stack.database":            "postgres",
+		"stack.iac":                 "terraform",
+		"stack.message_queue":       "kafka",
+		"preferences.language":      "go",
+	}
+	got := map[string]string{}
+	for _, s := range r.Settings() {
+		got[s.Key] = s.Value
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Settings() = %v, want %v", got, want)
+	}
+	if e := r.Evidence["stack.database"]; e.String() != "go.mod: github.com/jackc/pgx" {
+		t.Errorf("Evidence[stack.database] = %q", e)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		content, name string
+		want          bool
+	}{
+		{`"redis": "^4"`, "redis", true},
+		{`"ioredis": "^5"`, "redis", false},
+		{"github.com/jackc/pgx/v5", "pg", false},
+		{"psycopg2-binary==2.9", "psycopg2-binary", true},
+		{"psycopg2-binary==2.9", "psycopg2", false},
+	}
+	for _, tt := range tests {
+		if got := mentions(tt.content, tt.name); got != tt.want {
+			t.Errorf("mentions(%q, %q) = %v, want %v", tt.content, tt.name, got, tt.want)
+		}
+	}
+}
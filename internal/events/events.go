@@ -0,0 +1,196 @@
+// Package events defines the lifecycle events emitted while running agents
+// and the in-process bus they are published on.
+// Events have a stable JSON schema so they can be consumed by CI wrappers
+// (see `pagent run --events ndjson`).
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is bumped whenever a field is removed or changes meaning.
+// Adding new optional fields does not change the version.
+const SchemaVersion = 1
+
+// Type identifies a lifecycle transition
+type Type string
+
+// Event types emitted during a run
+const (
+	RunStarted      Type = "run_started"
+	RunCompleted    Type = "run_completed"
+	AgentStarted    Type = "agent_started"
+	AgentHealthy    Type = "agent_healthy"
+	AgentPromptSent Type = "agent_prompt_sent"
+	AgentCompleted  Type = "agent_completed"
+	AgentFailed     Type = "agent_failed"
+	AgentSkipped    Type = "agent_skipped"
+
+	// AgentStatusChanged is published when the agentapi status of a running
+	// agent changes (e.g. "running" -> "stable")
+	AgentStatusChanged Type = "agent_status_changed"
+
+	// AgentRetry is published when an agent is started again after an
+	// attempt ended, with the number of the new attempt
+	AgentRetry Type = "agent_retry"
+
+	// AgentRestarted is published when a running agent is restarted on request
+	AgentRestarted Type = "agent_restarted"
+
+	// RunPaused and RunResumed are published when a run is paused (no new
+	// agents are started) or resumed
+	RunPaused  Type = "run_paused"
+	RunResumed Type = "run_resumed"
+
+	// PostProcessStep is published once per post-processing step (modify mode)
+	PostProcessStep Type = "post_process_step"
+
+	// ApprovalNeeded is published when a run is waiting on a human decision,
+	// or goes ahead without one it needed (e.g. unresolved stack conflicts)
+	ApprovalNeeded Type = "approval_needed"
+
+	// Log carries free-form diagnostic messages (only published in verbose mode)
+	Log Type = "log"
+)
+
+// Event is a single lifecycle transition.
+// Fields not relevant to a given Type are omitted from the JSON encoding.
+type Event struct {
+	Version    int       `json:"v"`
+	Type       Type      `json:"type"`
+	Time       time.Time `json:"time"`
+	RunID      string    `json:"run_id,omitempty"`
+	Agent      string    `json:"agent,omitempty"`
+	Port       int       `json:"port,omitempty"`
+	OutputPath string    `json:"output_path,omitempty"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Status     string    `json:"status,omitempty"`  // agent_status_changed
+	Step       string    `json:"step,omitempty"`    // post_process_step
+	Attempt    int       `json:"attempt,omitempty"` // agent_retry, agent_restarted: 2 for the first retry
+	Message    string    `json:"message,omitempty"` // log, post_process_step output
+
+	// Run-level fields (run_started / run_completed)
+	Agents    []string `json:"agents,omitempty"`
+	Succeeded int      `json:"succeeded,omitempty"`
+	Failed    int      `json:"failed,omitempty"`
+
+	// Conflicts are the PRD-vs-config stack conflicts awaiting a decision (approval_needed)
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+}
+
+// Conflict is a stack setting the input documents contradict
+type Conflict struct {
+	Category    string `json:"category"`           // "database", "compute", "cache", "message_queue"
+	ConfigValue string `json:"config_value"`       // What the config specifies
+	PRDHint     string `json:"prd_hint"`           // What the PRD asks for
+	Evidence    string `json:"evidence,omitempty"` // Where the PRD asks for it, e.g. "prd.md:12"
+}
+
+// New creates an event of the given type stamped with the current time
+func New(t Type) Event {
+	return Event{
+		Version: SchemaVersion,
+		Type:    t,
+		Time:    time.Now().UTC(),
+	}
+}
+
+// HasError reports whether the event carries an error
+func (e Event) HasError() bool {
+	return e.Error != ""
+}
+
+// Handler receives events. Handlers must be safe for concurrent use
+// because agents in the same level run in parallel.
+type Handler func(Event)
+
+// Bus fans out published events to all subscribers.
+// Delivery is synchronous and in subscription order, so handlers should
+// return quickly; a nil *Bus discards everything.
+type Bus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   []subscription
+}
+
+type subscription struct {
+	id      int
+	handler Handler
+	types   map[Type]bool // nil = all types
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers h for the given event types (all types if none given).
+// The returned function removes the subscription.
+func (b *Bus) Subscribe(h Handler, types ...Type) (unsubscribe func()) {
+	sub := subscription{handler: h}
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.nextID++
+	sub.id = b.nextID
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.subs {
+			if s.id == sub.id {
+				b.subs = append(b.subs[:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers e to every matching subscriber
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	subs := make([]subscription, len(b.subs))
+	copy(subs, b.subs)
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		if s.types == nil || s.types[e.Type] {
+			s.handler(e)
+		}
+	}
+}
+
+// NDJSONWriter writes one JSON-encoded event per line
+type NDJSONWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewNDJSONWriter creates a writer that encodes events to w
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{enc: json.NewEncoder(w)}
+}
+
+// Handle encodes a single event. Encoding errors are ignored so that a
+// broken pipe on the consumer side never aborts a run.
+func (w *NDJSONWriter) Handle(e Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.enc.Encode(e)
+}
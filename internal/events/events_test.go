@@ -0,0 +1,97 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	e := New(AgentStarted)
+	if e.Type != AgentStarted {
+		t.Errorf("Type = %q, want %q", e.Type, AgentStarted)
+	}
+	if e.Version != SchemaVersion {
+		t.Errorf("Version = %d, want %d", e.Version, SchemaVersion)
+	}
+	if e.Time.IsZero() {
+		t.Error("Time should be set")
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewNDJSONWriter(&buf)
+
+	started := New(AgentStarted)
+	started.RunID = "run-1"
+	started.Agent = "architect"
+	started.Port = 3284
+	w.Handle(started)
+
+	failed := New(AgentFailed)
+	failed.Agent = "qa"
+	failed.Error = "timeout"
+	w.Handle(failed)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+		t.Fatalf("line 1 is not valid JSON: %v", err)
+	}
+	for _, key := range []string{"v", "type", "time", "run_id", "agent", "port"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("expected key %q in %s", key, lines[0])
+		}
+	}
+	if _, ok := decoded["error"]; ok {
+		t.Errorf("empty error should be omitted: %s", lines[0])
+	}
+
+	if !strings.Contains(lines[1], `"type":"agent_failed"`) || !strings.Contains(lines[1], `"error":"timeout"`) {
+		t.Errorf("unexpected second event: %s", lines[1])
+	}
+}
+
+func TestBusPublishSubscribe(t *testing.T) {
+	bus := NewBus()
+
+	var all, failures []Type
+	bus.Subscribe(func(e Event) { all = append(all, e.Type) })
+	unsubscribe := bus.Subscribe(func(e Event) { failures = append(failures, e.Type) }, AgentFailed)
+
+	bus.Publish(New(AgentStarted))
+	bus.Publish(New(AgentFailed))
+
+	unsubscribe()
+	bus.Publish(New(AgentFailed))
+
+	if len(all) != 3 {
+		t.Errorf("expected 3 events for catch-all subscriber, got %v", all)
+	}
+	if len(failures) != 1 || failures[0] != AgentFailed {
+		t.Errorf("expected single filtered event before unsubscribe, got %v", failures)
+	}
+}
+
+func TestNilBusPublish(t *testing.T) {
+	var bus *Bus
+	// Must not panic
+	bus.Publish(New(RunStarted))
+}
+
+func TestEventHasError(t *testing.T) {
+	e := New(PostProcessStep)
+	if e.HasError() {
+		t.Error("new event should not have an error")
+	}
+	e.Error = "boom"
+	if !e.HasError() {
+		t.Error("expected HasError() to be true")
+	}
+}
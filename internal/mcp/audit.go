@@ -0,0 +1,137 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tuannvm/pagent/internal/audit"
+	"github.com/tuannvm/pagent/internal/policy"
+)
+
+// Audited MCP methods besides tools/call
+const (
+	methodGetPrompt    = "prompts/get"
+	methodReadResource = "resources/read"
+)
+
+// pathArgs are the tool and prompt arguments recorded as affected paths
+var pathArgs = []string{"prd_path", "output_dir", "target_codebase", "specs_output_dir", "config_path"}
+
+// auditMiddleware appends tool calls, prompt renders and resource reads to
+// the audit log, including calls the access policy denies
+func (h *Handlers) auditMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != methodCallTool && method != methodGetPrompt && method != methodReadResource {
+			return next(ctx, method, req)
+		}
+
+		startedAt := time.Now()
+		result, err := next(ctx, method, req)
+
+		rec := audit.Record{
+			StartedAt:  startedAt.UTC(),
+			FinishedAt: time.Now().UTC(),
+			Source:     audit.SourceMCP,
+			Actor:      auditActor(req.GetExtra()),
+		}
+		rec.Action, rec.Args = auditAction(req)
+		rec.RunID, _ = rec.Args["run_id"].(string)
+		for _, name := range pathArgs {
+			if p, ok := rec.Args[name].(string); ok && p != "" {
+				rec.Paths = append(rec.Paths, p)
+			}
+		}
+		rec.Outcome, rec.Error = auditOutcome(result, err)
+		if out := structuredOutput(result); out != nil && rec.RunID == "" {
+			rec.RunID, _ = out["run_id"].(string)
+		}
+
+		if err := audit.New(h.loadConfig().Audit).Append(rec); err != nil {
+			log.Printf("Warning: failed to write audit log: %v", err)
+		}
+		return result, err
+	}
+}
+
+// auditActor names the caller of a request
+func auditActor(extra *mcp.RequestExtra) string {
+	return tokenCaller(extra).actor()
+}
+
+// auditAction returns the audited action of a request and its arguments
+func auditAction(req mcp.Request) (string, map[string]any) {
+	switch params := req.GetParams().(type) {
+	case *mcp.CallToolParamsRaw:
+		var args map[string]any
+		_ = json.Unmarshal(params.Arguments, &args)
+		return params.Name, args
+	case *mcp.GetPromptParams:
+		args := make(map[string]any, len(params.Arguments))
+		for k, v := range params.Arguments {
+			args[k] = v
+		}
+		return "prompt:" + params.Name, args
+	case *mcp.ReadResourceParams:
+		args := map[string]any{"uri": params.URI}
+		if runID, _, ok := parseArtifactURI(params.URI); ok {
+			args["run_id"] = runID
+		}
+		return "read_resource", args
+	}
+	return "", nil
+}
+
+// auditOutcome classifies a result as ok, error or denied by the policy.
+// Tools such as run_agent report failures in their output's error field.
+func auditOutcome(result mcp.Result, err error) (string, string) {
+	msg := ""
+	switch {
+	case err != nil:
+		msg = err.Error()
+	case isToolError(result, nil):
+		for _, c := range result.(*mcp.CallToolResult).Content {
+			if text, ok := c.(*mcp.TextContent); ok {
+				msg = text.Text
+				break
+			}
+		}
+		if msg == "" {
+			msg = "tool returned an error"
+		}
+	default:
+		if out := structuredOutput(result); out != nil {
+			msg, _ = out["error"].(string)
+		}
+	}
+
+	switch {
+	case msg == "":
+		return audit.OutcomeOK, ""
+	case strings.HasPrefix(msg, policy.ErrDenied.Error()):
+		return audit.OutcomeDenied, msg
+	default:
+		return audit.OutcomeError, msg
+	}
+}
+
+// structuredOutput returns the structured output of a tool result as a map
+func structuredOutput(result mcp.Result) map[string]any {
+	// Failed calls, e.g. to unknown tools, have a nil result
+	res, ok := result.(*mcp.CallToolResult)
+	if !ok || res == nil || res.StructuredContent == nil {
+		return nil
+	}
+	data, err := json.Marshal(res.StructuredContent)
+	if err != nil {
+		return nil
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return out
+}
@@ -2,36 +2,44 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/tuannvm/pagent/internal/agent"
 	"github.com/tuannvm/pagent/internal/api"
 	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/daemon"
+	"github.com/tuannvm/pagent/internal/policy"
+	"github.com/tuannvm/pagent/internal/queue"
+	"github.com/tuannvm/pagent/internal/workspace"
 )
 
-// AgentDescriptions maps agent names to their descriptions.
-var AgentDescriptions = map[string]string{
-	"architect":   "Analyzes PRD and creates technical architecture document",
-	"qa":          "Creates comprehensive test plan based on architecture",
-	"security":    "Performs security assessment and threat modeling",
-	"implementer": "Implements the code based on architecture and security specs",
-	"verifier":    "Verifies implementation against test plan and requirements",
-}
+// Bounds for wait_run and cancel_run; the HTTP transport's write timeout is 60s
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 50 * time.Second
+	cancelWaitTimeout  = 10 * time.Second
+)
 
 // Handlers provides the business logic for MCP tool handlers.
 // It can be used standalone or injected into the MCP server.
 type Handlers struct {
 	configPath string // Optional config file path
 	verbose    bool
+	jobs       jobQueue       // Runs are queued rather than executed inline
+	runs       *runRegistry   // Live progress of runs executed by this server
+	artifacts  artifactHook   // Resource updates for artifacts of those runs
+	policy     *policy.Policy // Access control for OAuth callers; nil allows all
+	resolve    string         // Stack conflict strategy for runs that don't set one
 }
 
 // NewHandlers creates a new Handlers instance.
 func NewHandlers() *Handlers {
-	return &Handlers{}
+	return &Handlers{runs: newRunRegistry()}
 }
 
 // WithConfigPath sets the config file path.
@@ -46,9 +54,38 @@ func (h *Handlers) WithVerbose(verbose bool) *Handlers {
 	return h
 }
 
+// WithResolve sets the strategy for PRD-vs-config stack conflicts in runs
+// that don't choose one; config.ResolveFail makes them fail rather than run
+// with the conflicts unresolved.
+func (h *Handlers) WithResolve(strategy string) *Handlers {
+	h.resolve = strategy
+	return h
+}
+
+// resolvePaths resolves the non-empty paths within sandbox in place; keys
+// name the paths in errors
+func resolvePaths(sandbox *workspace.Sandbox, paths map[string]*string) error {
+	for name, p := range paths {
+		if *p == "" {
+			continue
+		}
+		resolved, err := sandbox.Resolve(*p)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		*p = resolved
+	}
+	return nil
+}
+
 // loadConfig loads the config file or returns defaults.
 func (h *Handlers) loadConfig() *config.Config {
-	cfg, err := config.Load(h.configPath)
+	return loadConfigOrDefault(h.configPath)
+}
+
+// loadConfigOrDefault loads the config file at path or returns defaults.
+func loadConfigOrDefault(path string) *config.Config {
+	cfg, err := config.Load(path)
 	if err != nil {
 		return config.Default()
 	}
@@ -58,165 +95,261 @@ func (h *Handlers) loadConfig() *config.Config {
 // RunAgent executes a single agent.
 func (h *Handlers) RunAgent(ctx context.Context, input RunAgentInput) RunAgentOutput {
 	// Validate input
-	if input.PRDPath == "" {
-		return RunAgentOutput{Success: false, Error: "prd_path is required"}
-	}
 	if input.AgentName == "" {
 		return RunAgentOutput{Success: false, Error: "agent_name is required"}
 	}
+	opts, err := h.runOptions(ctx, input.PRDPath, []string{input.AgentName}, input.RunSettings)
+	if err != nil {
+		return RunAgentOutput{Agent: input.AgentName, Success: false, Error: err.Error()}
+	}
+
+	// Async: return the run ID for get_run/wait_run
+	if input.Async {
+		job, err := h.enqueue(ctx, opts, input.Priority)
+		if err != nil {
+			return RunAgentOutput{Agent: input.AgentName, Success: false, Error: err.Error()}
+		}
+		return RunAgentOutput{RunID: job.ID, State: job.State, Agent: input.AgentName, Success: true}
+	}
 
-	// Check PRD file exists
-	absPath, err := filepath.Abs(input.PRDPath)
+	// Queue the run and wait for the agent to finish
+	job, err := h.runQueued(ctx, opts, input.Priority)
 	if err != nil {
-		return RunAgentOutput{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
+		return RunAgentOutput{RunID: job.ID, Agent: input.AgentName, Success: false, Error: err.Error()}
 	}
-	if _, err := os.Stat(absPath); os.IsNotExist(err) {
-		return RunAgentOutput{Success: false, Error: fmt.Sprintf("PRD file not found: %s", absPath)}
+
+	results, _, _ := agentOutputs(job)
+	if len(results) == 0 {
+		return RunAgentOutput{RunID: job.ID, Agent: input.AgentName, Success: false, Error: fmt.Sprintf("run %s %s: %s", job.ID, job.State, job.Error)}
 	}
+	return results[0]
+}
 
-	// Load config
-	cfg := h.loadConfig()
+// RunPipeline executes the full agent pipeline.
+func (h *Handlers) RunPipeline(ctx context.Context, input RunPipelineInput) (RunPipelineOutput, error) {
+	opts, err := h.runOptions(ctx, input.PRDPath, input.Agents, input.RunSettings)
+	if err != nil {
+		return RunPipelineOutput{}, err
+	}
+	opts.Sequential = input.Sequential
 
-	// Apply overrides
-	if input.OutputDir != "" {
-		cfg.OutputDir = input.OutputDir
+	// Count the agents the run will start with
+	totalAgents := len(opts.Agents)
+	if totalAgents == 0 {
+		totalAgents = len(loadConfigOrDefault(opts.ConfigPath).Agents)
 	}
-	if input.Persona != "" {
-		if !config.IsValidPersona(input.Persona) {
-			return RunAgentOutput{Success: false, Error: fmt.Sprintf("invalid persona: %s", input.Persona)}
+
+	// Async: return the run ID for get_run/wait_run
+	if input.Async {
+		job, err := h.enqueue(ctx, opts, input.Priority)
+		if err != nil {
+			return RunPipelineOutput{}, err
 		}
-		cfg.Persona = input.Persona
+		return RunPipelineOutput{
+			RunID:       job.ID,
+			State:       job.State,
+			Results:     []RunAgentOutput{},
+			TotalAgents: totalAgents,
+		}, nil
 	}
 
-	// Validate agent exists
-	if _, ok := cfg.Agents[input.AgentName]; !ok {
-		return RunAgentOutput{Success: false, Error: fmt.Sprintf("unknown agent: %s", input.AgentName)}
+	// Queue the run and wait for it to finish
+	job, err := h.runQueued(ctx, opts, input.Priority)
+	if err != nil {
+		return RunPipelineOutput{RunID: job.ID, State: job.State, Results: []RunAgentOutput{}}, err
 	}
 
-	// Create manager and run agent
-	verbose := input.Verbose || h.verbose
-	manager := agent.NewManager(cfg, absPath, verbose)
-	result := manager.RunAgent(ctx, input.AgentName)
+	results, successful, failed := agentOutputs(job)
+	if len(results) == 0 && job.State != queue.StateSucceeded {
+		return RunPipelineOutput{RunID: job.ID, State: job.State, Results: results}, fmt.Errorf("run %s %s: %s", job.ID, job.State, job.Error)
+	}
 
-	output := RunAgentOutput{
-		Agent:      result.Agent,
-		OutputPath: result.OutputPath,
-		Duration:   result.Duration.String(),
-		Success:    result.Error == nil,
+	output := RunPipelineOutput{
+		RunID:       job.ID,
+		State:       job.State,
+		Results:     results,
+		TotalAgents: totalAgents,
+		Successful:  successful,
+		Failed:      failed,
 	}
-	if result.Error != nil {
-		output.Error = result.Error.Error()
+	if job.StartedAt != nil && job.FinishedAt != nil {
+		output.TotalDuration = job.FinishedAt.Sub(*job.StartedAt).String()
 	}
-
-	return output
+	return output, nil
 }
 
-// RunPipeline executes the full agent pipeline.
-func (h *Handlers) RunPipeline(ctx context.Context, input RunPipelineInput) (RunPipelineOutput, error) {
-	// Validate input
-	if input.PRDPath == "" {
-		return RunPipelineOutput{}, fmt.Errorf("prd_path is required")
+// runOptions builds the options of a queued run, as 'pagent run' would from
+// its flags. Everything the runner would reject is checked here, so bad
+// input fails the tool call instead of the queued job. Paths are resolved
+// within the caller's workspace.
+func (h *Handlers) runOptions(ctx context.Context, inputPath string, agents []string, s RunSettings) (config.RunOptions, error) {
+	if inputPath == "" {
+		return config.RunOptions{}, fmt.Errorf("prd_path is required")
+	}
+	sandbox, err := h.sandbox(ctx)
+	if err != nil {
+		return config.RunOptions{}, err
 	}
 
-	// Check PRD file exists
-	absPath, err := filepath.Abs(input.PRDPath)
+	// Check the input file or directory exists
+	absPath, err := sandbox.Resolve(inputPath)
 	if err != nil {
-		return RunPipelineOutput{}, fmt.Errorf("invalid path: %v", err)
+		return config.RunOptions{}, fmt.Errorf("invalid prd_path: %w", err)
 	}
 	if _, err := os.Stat(absPath); os.IsNotExist(err) {
-		return RunPipelineOutput{}, fmt.Errorf("PRD file not found: %s", absPath)
+		return config.RunOptions{}, fmt.Errorf("PRD file not found: %s", absPath)
 	}
 
-	// Load config
-	cfg := h.loadConfig()
+	// A client config could point prompt files anywhere and run commands
+	if s.ConfigPath != "" {
+		if err := h.checkConfigPath(ctx, sandbox, s.ConfigPath); err != nil {
+			return config.RunOptions{}, err
+		}
+	}
 
-	// Apply overrides
-	if input.OutputDir != "" {
-		cfg.OutputDir = input.OutputDir
+	// An explicit config must load; the server's config falls back to defaults
+	configPath := h.configPath
+	if s.ConfigPath != "" {
+		configPath = s.ConfigPath
+		if _, err := config.Load(configPath); err != nil {
+			return config.RunOptions{}, fmt.Errorf("failed to load config %s: %w", configPath, err)
+		}
 	}
-	if input.Persona != "" {
-		if !config.IsValidPersona(input.Persona) {
-			return RunPipelineOutput{}, fmt.Errorf("invalid persona: %s", input.Persona)
+	cfg := loadConfigOrDefault(configPath)
+	if s.Preset != "" {
+		if cfg, err = config.LoadWithPreset(configPath, s.Preset); err != nil {
+			return config.RunOptions{}, fmt.Errorf("invalid preset: %w", err)
 		}
-		cfg.Persona = input.Persona
 	}
 
-	// Determine which agents to run
-	agentsToRun := input.Agents
-	if len(agentsToRun) == 0 {
-		agentsToRun = cfg.GetAgentNames()
+	opts := config.DefaultRunOptions(cfg)
+	opts.InputPath = absPath
+	opts.Agents = agents
+	opts.ConfigPath = configPath
+	opts.Preset = s.Preset
+	if s.OutputDir != "" {
+		opts.OutputDir = s.OutputDir
+	}
+	if s.Persona != "" {
+		opts.Persona = s.Persona
+	}
+	if s.ResumeMode != "" {
+		opts.ResumeMode = s.ResumeMode
+	}
+	if s.Architecture != "" {
+		opts.Architecture = s.Architecture
+	}
+	opts.Resolve = s.Resolve
+	if s.Timeout != nil {
+		opts.Timeout = *s.Timeout
+	}
+	opts.Mode = s.Mode
+	opts.TargetCodebase = s.TargetCodebase
+	opts.SpecsOutputDir = s.SpecsOutputDir
+	opts.Stack = s.Stack
+	opts.Preferences = s.Preferences
+	if s.Verbose || h.verbose {
+		opts.Verbosity = config.VerbosityVerbose
 	}
 
-	// Validate all agents exist
-	for _, name := range agentsToRun {
+	if err := opts.ValidateFor(cfg); err != nil {
+		return config.RunOptions{}, err
+	}
+	if err := resolvePaths(sandbox, map[string]*string{
+		"output_dir":       &opts.OutputDir,
+		"target_codebase":  &opts.TargetCodebase,
+		"specs_output_dir": &opts.SpecsOutputDir,
+	}); err != nil {
+		return config.RunOptions{}, err
+	}
+	for _, name := range agents {
 		if _, ok := cfg.Agents[name]; !ok {
-			return RunPipelineOutput{}, fmt.Errorf("unknown agent: %s", name)
+			return config.RunOptions{}, fmt.Errorf("unknown agent: %s", name)
 		}
 	}
 
-	// Create manager
-	verbose := input.Verbose || h.verbose
-	manager := agent.NewManager(cfg, absPath, verbose)
-
-	// Run agents based on execution mode
-	var results []RunAgentOutput
-	var successful, failed int
-
-	if input.Sequential {
-		// Sequential execution
-		order := manager.TopologicalSort(agentsToRun)
-		for _, name := range order {
-			result := manager.RunAgent(ctx, name)
-			output := RunAgentOutput{
-				Agent:      result.Agent,
-				OutputPath: result.OutputPath,
-				Duration:   result.Duration.String(),
-				Success:    result.Error == nil,
-			}
-			if result.Error != nil {
-				output.Error = result.Error.Error()
-				failed++
-			} else {
-				successful++
-			}
-			results = append(results, output)
-		}
-	} else {
-		// Parallel by dependency level
-		levels := manager.GetDependencyLevels(agentsToRun)
-		for _, level := range levels {
-			levelResults := make(chan agent.Result, len(level))
-			for _, name := range level {
-				go func(agentName string) {
-					levelResults <- manager.RunAgent(ctx, agentName)
-				}(name)
-			}
+	// The caller's policy must allow every agent of the run and its persona
+	runAgents := agents
+	if len(runAgents) == 0 {
+		cfg.Persona = opts.Persona
+		runAgents = cfg.PersonaAgents()
+	}
+	if err := h.checkAgents(ctx, runAgents); err != nil {
+		return config.RunOptions{}, err
+	}
+	if err := h.grant(ctx).CheckPersona(opts.Persona); err != nil {
+		return config.RunOptions{}, err
+	}
 
-			for range level {
-				result := <-levelResults
-				output := RunAgentOutput{
-					Agent:      result.Agent,
-					OutputPath: result.OutputPath,
-					Duration:   result.Duration.String(),
-					Success:    result.Error == nil,
-				}
-				if result.Error != nil {
-					output.Error = result.Error.Error()
-					failed++
-				} else {
-					successful++
-				}
-				results = append(results, output)
-			}
-		}
+	// Check the overrides against the config the run will use
+	if opts.Mode != "" {
+		cfg.Mode = opts.Mode
+	}
+	if opts.TargetCodebase != "" {
+		cfg.TargetCodebase = opts.TargetCodebase
 	}
+	if err := cfg.ValidateMode(); err != nil {
+		return config.RunOptions{}, err
+	}
+	if err := cfg.ApplyStackOverrides(opts.Stack, opts.Preferences); err != nil {
+		return config.RunOptions{}, err
+	}
+	return opts, nil
+}
 
-	return RunPipelineOutput{
-		Results:     results,
-		TotalAgents: len(agentsToRun),
-		Successful:  successful,
-		Failed:      failed,
-	}, nil
+// GetRun returns the state and per-agent progress of a run.
+func (h *Handlers) GetRun(ctx context.Context, input GetRunInput) (RunStatusOutput, error) {
+	job, err := h.getJob(ctx, input.RunID)
+	if err != nil {
+		return RunStatusOutput{}, err
+	}
+	return h.runStatus(job), nil
+}
+
+// WaitRun waits for a run to finish, returning its current state when the
+// (bounded) timeout expires first.
+func (h *Handlers) WaitRun(ctx context.Context, input WaitRunInput) (RunStatusOutput, error) {
+	if _, err := h.getJob(ctx, input.RunID); err != nil {
+		return RunStatusOutput{}, err
+	}
+	q := h.jobs.queue
+
+	// Stay below the HTTP transport's write timeout
+	timeout := time.Duration(input.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+	if timeout > maxWaitTimeout {
+		timeout = maxWaitTimeout
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	job, err := q.Wait(waitCtx, input.RunID)
+	if err != nil && (ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded)) {
+		return RunStatusOutput{}, err
+	}
+	return h.runStatus(job), nil
+}
+
+// CancelRun cancels a queued or running run.
+func (h *Handlers) CancelRun(ctx context.Context, input CancelRunInput) (RunStatusOutput, error) {
+	if _, err := h.getJob(ctx, input.RunID); err != nil {
+		return RunStatusOutput{}, err
+	}
+	q := h.jobs.queue
+	job, err := q.Cancel(input.RunID)
+	if err != nil {
+		return RunStatusOutput{}, err
+	}
+
+	// Give the worker a moment to stop the agents so the result is final
+	waitCtx, cancel := context.WithTimeout(ctx, cancelWaitTimeout)
+	defer cancel()
+	if final, err := q.Wait(waitCtx, job.ID); err == nil {
+		job = final
+	}
+	return h.runStatus(job), nil
 }
 
 // ListAgents returns all available agents.
@@ -229,27 +362,31 @@ func (h *Handlers) ListAgents(_ context.Context, _ ListAgentsInput) ListAgentsOu
 			Name:        name,
 			Output:      agentCfg.Output,
 			DependsOn:   agentCfg.DependsOn,
-			Description: AgentDescriptions[name],
+			Kind:        cfg.AgentKind(name),
+			Description: agentCfg.Description,
 		})
 	}
 
 	return ListAgentsOutput{Agents: agents}
 }
 
-// GetStatus returns the status of running agents.
-func (h *Handlers) GetStatus(_ context.Context, input GetStatusInput) GetStatusOutput {
-	state, err := agent.LoadState()
-	if err != nil {
-		return GetStatusOutput{Agents: []AgentStatus{}}
+// GetStatus returns the status of running agents of runs the caller owns.
+// Listing all agents leaves out those the caller's policy denies.
+func (h *Handlers) GetStatus(ctx context.Context, input GetStatusInput) (GetStatusOutput, error) {
+	matches := h.runningAgents(ctx, input.AgentName)
+	if input.AgentName != "" && len(matches) == 0 {
+		return GetStatusOutput{}, fmt.Errorf("agent %q not found in running agents", input.AgentName)
 	}
 
 	agents := make([]AgentStatus, 0) // Initialize as empty slice, not nil
-	for name, port := range state {
-		if input.AgentName != "" && name != input.AgentName {
+	for _, a := range matches {
+		if err := h.checkRunningAgent(ctx, a); err != nil {
+			if input.AgentName != "" {
+				return GetStatusOutput{}, err
+			}
 			continue
 		}
-
-		client := api.NewClient(port)
+		client := api.NewClient(a.Port)
 		status, err := client.GetStatus()
 		statusStr := "unknown"
 		if err == nil {
@@ -257,17 +394,18 @@ func (h *Handlers) GetStatus(_ context.Context, input GetStatusInput) GetStatusO
 		}
 
 		agents = append(agents, AgentStatus{
-			Name:   name,
-			Port:   port,
+			Name:   a.Name,
+			RunID:  a.RunID,
+			Port:   a.Port,
 			Status: statusStr,
 		})
 	}
 
-	return GetStatusOutput{Agents: agents}
+	return GetStatusOutput{Agents: agents}, nil
 }
 
-// SendMessage sends a message to a running agent.
-func (h *Handlers) SendMessage(_ context.Context, input SendMessageInput) SendMessageOutput {
+// SendMessage sends a message to a running agent of a run the caller owns.
+func (h *Handlers) SendMessage(ctx context.Context, input SendMessageInput) SendMessageOutput {
 	if input.AgentName == "" {
 		return SendMessageOutput{Success: false, Error: "agent_name is required"}
 	}
@@ -275,16 +413,12 @@ func (h *Handlers) SendMessage(_ context.Context, input SendMessageInput) SendMe
 		return SendMessageOutput{Success: false, Error: "message is required"}
 	}
 
-	state, err := agent.LoadState()
-	if err != nil {
-		return SendMessageOutput{Success: false, Error: "no running agents found"}
-	}
-
-	port, ok := state[input.AgentName]
-	if !ok {
-		available := make([]string, 0, len(state))
-		for name := range state {
-			available = append(available, name)
+	// Agents with the same name in several runs: the most recent run wins
+	matches := h.runningAgents(ctx, input.AgentName)
+	if len(matches) == 0 {
+		available := make([]string, 0)
+		for _, a := range h.runningAgents(ctx, "") {
+			available = append(available, a.Name)
 		}
 		return SendMessageOutput{
 			Success: false,
@@ -292,58 +426,48 @@ func (h *Handlers) SendMessage(_ context.Context, input SendMessageInput) SendMe
 		}
 	}
 
-	client := api.NewClient(port)
-	if err := client.SendMessage(input.Message, "user"); err != nil {
+	if err := h.checkRunningAgent(ctx, matches[0]); err != nil {
+		return SendMessageOutput{Success: false, Error: err.Error()}
+	}
+
+	client := api.NewClient(matches[0].Port)
+	if err := client.SendMessage(ctx, input.Message, "user"); err != nil {
 		return SendMessageOutput{Success: false, Error: err.Error()}
 	}
 
 	return SendMessageOutput{Success: true}
 }
 
-// StopAgents stops running agents.
-func (h *Handlers) StopAgents(_ context.Context, input StopAgentsInput) StopAgentsOutput {
-	state, err := agent.LoadState()
-	if err != nil {
-		return StopAgentsOutput{Stopped: []string{}, Success: true}
+// StopAgents stops running agents of runs the caller owns.
+func (h *Handlers) StopAgents(ctx context.Context, input StopAgentsInput) StopAgentsOutput {
+	targets := h.runningAgents(ctx, input.AgentName)
+	if input.AgentName != "" && len(targets) == 0 {
+		return StopAgentsOutput{
+			Stopped: []string{},
+			Success: false,
+			Error:   fmt.Sprintf("agent %q not found in running agents", input.AgentName),
+		}
 	}
 
-	stopped := make([]string, 0) // Initialize as empty slice, not nil
-	var errors []string
-
-	if input.AgentName != "" {
-		// Stop specific agent
-		port, ok := state[input.AgentName]
-		if !ok {
-			return StopAgentsOutput{
-				Stopped: []string{},
-				Success: false,
-				Error:   fmt.Sprintf("agent %q not found in running agents", input.AgentName),
-			}
+	// Stopping all agents requires access to every running agent
+	for _, a := range targets {
+		if err := h.checkRunningAgent(ctx, a); err != nil {
+			return StopAgentsOutput{Stopped: []string{}, Success: false, Error: err.Error()}
 		}
+	}
 
-		if err := stopAgentByPort(input.AgentName, port); err != nil {
+	stopped := make([]string, 0) // Initialize as empty slice, not nil
+	var errors []string
+	for _, a := range targets {
+		if err := h.stopAgent(ctx, a); err != nil {
 			errors = append(errors, err.Error())
-			// Don't remove from state if kill failed - process may still be running
-		} else {
-			stopped = append(stopped, input.AgentName)
-			// Only remove from state after successful termination
-			if err := agent.RemoveAgentFromState(input.AgentName); err != nil {
-				errors = append(errors, fmt.Sprintf("failed to update state: %v", err))
-			}
+			// Don't remove from state if stopping failed - the agent may still be running
+			continue
 		}
-	} else {
-		// Stop all agents
-		for name, port := range state {
-			if err := stopAgentByPort(name, port); err != nil {
-				errors = append(errors, err.Error())
-				// Don't remove failed agents from state
-			} else {
-				stopped = append(stopped, name)
-				// Remove successfully stopped agents from state individually
-				if err := agent.RemoveAgentFromState(name); err != nil {
-					errors = append(errors, fmt.Sprintf("failed to update state for %s: %v", name, err))
-				}
-			}
+		stopped = append(stopped, a.Name)
+		// Only remove from state after successful termination
+		if err := agent.RemoveAgentFromState(a.RunID, a.Name); err != nil {
+			errors = append(errors, fmt.Sprintf("failed to update state for %s: %v", a.Name, err))
 		}
 	}
 
@@ -358,50 +482,63 @@ func (h *Handlers) StopAgents(_ context.Context, input StopAgentsInput) StopAgen
 	return output
 }
 
-// stopAgentByPort terminates processes listening on the specified port.
-func stopAgentByPort(name string, port int) error {
-	// Check if lsof is available
-	lsofPath, err := exec.LookPath("lsof")
+// runningAgents returns the agents in the state file that belong to runs
+// the caller of ctx owns, or those named name if it is non-empty, with the
+// most recent run first
+func (h *Handlers) runningAgents(ctx context.Context, name string) []agent.StateAgent {
+	state, err := agent.LoadState()
 	if err != nil {
-		return fmt.Errorf("lsof not found: %w (required to stop agents)", err)
+		return nil
 	}
+	return slices.DeleteFunc(state.Agents(), func(a agent.StateAgent) bool {
+		return (name != "" && a.Name != name) || !h.ownsRun(ctx, a.RunID)
+	})
+}
 
-	out, err := exec.Command(lsofPath, "-ti", fmt.Sprintf(":%d", port)).Output()
-	if err != nil {
-		// lsof returns exit code 1 when no process is found on the port
-		// This is expected if the agent already stopped
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return nil // No process on port - agent already stopped
-		}
-		return fmt.Errorf("failed to find process on port %d: %w", port, err)
+// ownsRun reports whether the caller of ctx may see and control a run.
+// Local (stdio) callers own every run; remote callers only own queued runs
+// that checkOwner lets them see.
+func (h *Handlers) ownsRun(ctx context.Context, runID string) bool {
+	if !callerFrom(ctx).remote {
+		return true
 	}
+	_, err := h.getJob(ctx, runID)
+	return err == nil
+}
 
-	pidStr := strings.TrimSpace(string(out))
-	if pidStr == "" {
-		return nil // No process found
+// checkRunningAgent returns an error unless the caller's policy allows the
+// agent and, for a queued run, the run's persona, as starting the agent
+// with run_agent would require
+func (h *Handlers) checkRunningAgent(ctx context.Context, a agent.StateAgent) error {
+	grant := h.grant(ctx)
+	if err := grant.CheckAgent(a.Name); err != nil {
+		return err
 	}
-
-	// Check if kill is available
-	killPath, err := exec.LookPath("kill")
+	if grant == nil {
+		return nil
+	}
+	job, err := h.getJob(ctx, a.RunID)
 	if err != nil {
-		return fmt.Errorf("kill not found: %w (required to stop agents)", err)
+		// Only queued runs are visible to callers a policy applies to
+		return fmt.Errorf("agent %q not found in running agents", a.Name)
 	}
+	return grant.CheckPersona(job.Options.Persona)
+}
 
-	var killErrors []string
-	pids := strings.Split(pidStr, "\n")
-	for _, pid := range pids {
-		pid = strings.TrimSpace(pid)
-		if pid == "" {
-			continue
-		}
-		killCmd := exec.Command(killPath, "-TERM", pid)
-		if err := killCmd.Run(); err != nil {
-			killErrors = append(killErrors, fmt.Sprintf("failed to kill PID %s: %v", pid, err))
-		}
+// stopAgent stops an agent through its run's manager when this server
+// executes the run, and through the daemon's control API otherwise. Agents
+// are served from inside the process running them, so terminating the
+// process listening on an agent's port would stop every run it executes.
+func (h *Handlers) stopAgent(ctx context.Context, a agent.StateAgent) error {
+	if m := h.runs.manager(a.RunID); m != nil {
+		return m.CancelAgent(a.Name)
 	}
-
-	if len(killErrors) > 0 {
-		return fmt.Errorf("errors stopping agent %s: %s", name, strings.Join(killErrors, "; "))
+	client := daemon.NewClient(daemon.DefaultSocketPath())
+	if err := client.Ping(ctx); err != nil {
+		return fmt.Errorf("agent %s belongs to run %s, which neither this server nor a daemon executes", a.Name, a.RunID)
+	}
+	if _, err := client.ControlAgent(ctx, a.RunID, a.Name, daemon.ActionStop); err != nil {
+		return fmt.Errorf("failed to stop %s: %w", a.Name, err)
 	}
 	return nil
 }
@@ -0,0 +1,335 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tuannvm/pagent/internal/agent"
+	"github.com/tuannvm/pagent/internal/audit"
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/daemon"
+	"github.com/tuannvm/pagent/internal/policy"
+	"github.com/tuannvm/pagent/internal/queue"
+)
+
+// newTestHandlers returns handlers using serverConfig as the server's
+// config, and a directory holding it and a PRD
+func newTestHandlers(t *testing.T, serverConfig string) (*Handlers, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	// No daemon listens here
+	t.Setenv(daemon.SocketEnv, filepath.Join(dir, "daemon", "pagent.sock"))
+	writeTestFile(t, filepath.Join(dir, "prd.md"), "# PRD\n")
+	configPath := filepath.Join(dir, "server.yaml")
+	writeTestFile(t, configPath, serverConfig)
+	return NewHandlers().WithConfigPath(configPath), dir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunOptionsConfigPath(t *testing.T) {
+	h, dir := newTestHandlers(t, "mcp:\n  config_paths: [allowed.yaml]\n")
+	t.Chdir(dir)
+	writeTestFile(t, "allowed.yaml", "persona: minimal\n")
+	writeTestFile(t, "other.yaml", "post_processing:\n  validation_commands: [\"touch pwned\"]\n")
+	prd := filepath.Join(dir, "prd.md")
+
+	remote := withCaller(context.Background(), caller{remote: true})
+	local := context.Background()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		path    string
+		wantErr string
+	}{
+		{"remote, not listed", remote, "other.yaml", "mcp.config_paths does not list it"},
+		{"remote, missing file", remote, "missing.yaml", "mcp.config_paths does not list it"},
+		{"remote, listed", remote, filepath.Join(dir, "allowed.yaml"), ""},
+		{"local, unrestricted", local, "other.yaml", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := h.runOptions(tt.ctx, prd, nil, RunSettings{ConfigPath: tt.path})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("runOptions() error = %v", err)
+				}
+				if opts.ConfigPath != tt.path {
+					t.Errorf("ConfigPath = %q, want %q", opts.ConfigPath, tt.path)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runOptions() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunOptionsConfigPathWithRoots(t *testing.T) {
+	h, dir := newTestHandlers(t, "")
+	writeTestFile(t, h.configPath, "mcp:\n  roots: ["+dir+"]\n")
+	writeTestFile(t, filepath.Join(dir, "other.yaml"), "persona: minimal\n")
+
+	_, err := h.runOptions(context.Background(), filepath.Join(dir, "prd.md"), nil, RunSettings{ConfigPath: filepath.Join(dir, "other.yaml")})
+	if err == nil || !strings.Contains(err.Error(), "workspace roots apply") {
+		t.Errorf("runOptions() error = %v, want config_path rejected while roots apply", err)
+	}
+}
+
+func TestTokenCallerRemote(t *testing.T) {
+	if tokenCaller(nil).remote || tokenCaller(&mcp.RequestExtra{}).remote {
+		t.Error("requests without headers or a token should be local")
+	}
+	if !tokenCaller(&mcp.RequestExtra{Header: http.Header{}}).remote {
+		t.Error("requests with HTTP headers should be remote")
+	}
+}
+
+func TestEmptySubjectTokenIsRestricted(t *testing.T) {
+	h, dir := newTestHandlers(t, "")
+	writeTestFile(t, h.configPath, "mcp:\n  subject_workspaces: "+filepath.Join(dir, "subjects")+"\n")
+	policyPath := filepath.Join(dir, "policy.yaml")
+	writeTestFile(t, policyPath, "rules:\n  - name: platform\n    match:\n      groups: [platform]\n    tools: [\"*\"]\n    agents: [\"*\"]\n    personas: [\"*\"]\n")
+	p, err := policy.Load(policyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.WithPolicy(p)
+	q := useJobs(t, h)
+
+	noSubject := withCaller(context.Background(), tokenCaller(&mcp.RequestExtra{
+		Header:    http.Header{},
+		TokenInfo: &auth.TokenInfo{Extra: map[string]any{}},
+	}))
+	if err := h.grant(noSubject).CheckTool("get_status"); !errors.Is(err, policy.ErrDenied) {
+		t.Errorf("CheckTool() error = %v, want access denied without a matching rule", err)
+	}
+	if _, err := h.sandbox(noSubject); err == nil {
+		t.Error("sandbox() error = nil, want no workspace for a token without a subject")
+	}
+	job, err := q.Enqueue(context.Background(), config.RunOptions{InputPath: "prd.md"}, 0, audit.SourceMCP, "anonymous")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.getJob(noSubject, job.ID); !errors.Is(err, queue.ErrNotFound) {
+		t.Errorf("getJob() error = %v, want other callers' runs hidden", err)
+	}
+}
+
+// useJobs gives h a job queue in a temporary directory without a worker,
+// so queued jobs stay queued
+func useJobs(t *testing.T, h *Handlers) *queue.Queue {
+	t.Helper()
+	q, err := queue.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.jobs.once.Do(func() { h.jobs.queue = q })
+	return q
+}
+
+// useState points the agent state file at a temporary file holding state
+func useState(t *testing.T, state agent.State) {
+	t.Helper()
+	stateFile := agent.StateFile
+	agent.StateFile = filepath.Join(t.TempDir(), "pagent-state.json")
+	t.Cleanup(func() { agent.StateFile = stateFile })
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, agent.StateFile, string(data))
+}
+
+func TestAgentToolsOnlySeeOwnRuns(t *testing.T) {
+	h, dir := newTestHandlers(t, "")
+	writeTestFile(t, h.configPath, "mcp:\n  subject_workspaces: "+filepath.Join(dir, "subjects")+"\n")
+	useJobs(t, h)
+
+	alice := withCaller(context.Background(), caller{subject: "alice", remote: true, authenticated: true})
+	bob := withCaller(context.Background(), caller{subject: "bob", remote: true, authenticated: true})
+	aliceJob, err := h.enqueue(alice, config.RunOptions{InputPath: "prd.md"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobJob, err := h.enqueue(bob, config.RunOptions{InputPath: "prd.md"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Runs are named as their submitter's tool calls are in the audit log
+	if aliceJob.Source != audit.SourceMCP || aliceJob.Submitter != "alice" {
+		t.Errorf("job source, submitter = %q, %q; want mcp, alice", aliceJob.Source, aliceJob.Submitter)
+	}
+	// Ports nothing listens on, so nothing gets killed
+	useState(t, agent.State{
+		aliceJob.ID: {"architect": 1},
+		bobJob.ID:   {"qa": 2},
+		"cli-run":   {"implementer": 3},
+	})
+
+	status, err := h.GetStatus(alice, GetStatusInput{})
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if len(status.Agents) != 1 || status.Agents[0].Name != "architect" || status.Agents[0].RunID != aliceJob.ID {
+		t.Errorf("GetStatus() = %+v, want only alice's architect", status.Agents)
+	}
+	if _, err := h.GetStatus(alice, GetStatusInput{AgentName: "qa"}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GetStatus(qa) error = %v, want not found", err)
+	}
+
+	if out := h.SendMessage(alice, SendMessageInput{AgentName: "qa", Message: "hi"}); out.Success || !strings.Contains(out.Error, "not running") {
+		t.Errorf("SendMessage(qa) = %+v, want bob's agent hidden", out)
+	}
+
+	if out := h.StopAgents(alice, StopAgentsInput{AgentName: "qa"}); out.Success || !strings.Contains(out.Error, "not found") {
+		t.Errorf("StopAgents(qa) = %+v, want bob's agent hidden", out)
+	}
+	// No daemon executes alice's run, so her architect can't be stopped,
+	// but the error must only name her agent
+	if out := h.StopAgents(alice, StopAgentsInput{}); len(out.Stopped) != 0 || !strings.Contains(out.Error, "architect") || strings.Contains(out.Error, "qa") {
+		t.Errorf("StopAgents() = %+v, want only alice's architect attempted", out)
+	}
+
+	// Local (stdio) callers own every run, including CLI runs
+	status, err = h.GetStatus(context.Background(), GetStatusInput{})
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if len(status.Agents) != 3 {
+		t.Errorf("local GetStatus() = %+v, want every run's agents", status.Agents)
+	}
+}
+
+// TestStopAgentsWithoutManager checks that agents of runs another process
+// executes are not stopped by signalling the process listening on their
+// port, which serves every agent of that process
+func TestStopAgentsWithoutManager(t *testing.T) {
+	listener := exec.Command(os.Args[0], "-test.run=TestHelperListener")
+	listener.Env = append(os.Environ(), "PAGENT_TEST_LISTENER=1")
+	stdout, err := listener.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := listener.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- listener.Wait() }()
+	t.Cleanup(func() {
+		_ = listener.Process.Kill()
+		<-exited
+	})
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading the listener's port: %v", err)
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatalf("listener port %q: %v", line, err)
+	}
+
+	h, _ := newTestHandlers(t, "")
+	useState(t, agent.State{"daemon-run": {"architect": port}})
+
+	out := h.StopAgents(context.Background(), StopAgentsInput{AgentName: "architect"})
+	if out.Success || len(out.Stopped) != 0 || !strings.Contains(out.Error, "daemon") {
+		t.Errorf("StopAgents() = %+v, want an error that no daemon executes the run", out)
+	}
+	select {
+	case err := <-exited:
+		t.Fatalf("process listening on the agent's port was stopped: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	state, err := agent.LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Agents()) != 1 {
+		t.Errorf("state agents = %+v, want the agent kept", state.Agents())
+	}
+}
+
+// TestHelperListener is run by TestStopAgentsWithoutManager as the process
+// serving an agent: it listens on a port, prints it and blocks
+func TestHelperListener(t *testing.T) {
+	if os.Getenv("PAGENT_TEST_LISTENER") != "1" {
+		t.Skip("helper process for TestStopAgentsWithoutManager")
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(l.Addr().(*net.TCPAddr).Port)
+	select {}
+}
+
+func TestAgentToolsCheckPolicy(t *testing.T) {
+	h, dir := newTestHandlers(t, "")
+	policyPath := filepath.Join(dir, "policy.yaml")
+	writeTestFile(t, policyPath, "rules:\n  - name: operators\n    tools: [get_status, send_message, stop_agents]\n    agents: [architect]\n    personas: [minimal]\n")
+	p, err := policy.Load(policyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.WithPolicy(p)
+	useJobs(t, h)
+
+	alice := withCaller(context.Background(), caller{subject: "alice", remote: true, authenticated: true})
+	enqueue := func(persona string) string {
+		job, err := h.enqueue(alice, config.RunOptions{InputPath: "prd.md", Persona: persona}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job.ID
+	}
+	allowed, otherPersona := enqueue(config.PersonaMinimal), enqueue(config.PersonaProduction)
+	useState(t, agent.State{
+		allowed:      {"architect": 1, "qa": 2},
+		otherPersona: {"architect": 3},
+	})
+
+	status, err := h.GetStatus(alice, GetStatusInput{})
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if len(status.Agents) != 1 || status.Agents[0].Name != "architect" || status.Agents[0].RunID != allowed {
+		t.Errorf("GetStatus() = %+v, want only the architect of the minimal run", status.Agents)
+	}
+	if _, err := h.GetStatus(alice, GetStatusInput{AgentName: "qa"}); !errors.Is(err, policy.ErrDenied) {
+		t.Errorf("GetStatus(qa) error = %v, want access denied", err)
+	}
+
+	if out := h.SendMessage(alice, SendMessageInput{AgentName: "qa", Message: "hi"}); out.Success || !strings.Contains(out.Error, "access denied") {
+		t.Errorf("SendMessage(qa) = %+v, want access denied", out)
+	}
+	// The agent is allowed, but the persona of one of its runs is not
+	if out := h.StopAgents(alice, StopAgentsInput{AgentName: "architect"}); out.Success || !strings.Contains(out.Error, "persona") {
+		t.Errorf("StopAgents(architect) = %+v, want the run's persona denied", out)
+	}
+	if out := h.StopAgents(alice, StopAgentsInput{}); out.Success || len(out.Stopped) != 0 {
+		t.Errorf("StopAgents() = %+v, want denied for agents outside the grant", out)
+	}
+}
@@ -0,0 +1,214 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/agent"
+	"github.com/tuannvm/pagent/internal/audit"
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/events"
+	"github.com/tuannvm/pagent/internal/queue"
+	"github.com/tuannvm/pagent/internal/runner"
+)
+
+// jobQueue holds the handlers' connection to the persistent job queue
+type jobQueue struct {
+	once  sync.Once
+	queue *queue.Queue
+	err   error
+	stop  func()
+}
+
+// StartQueue opens the job queue and starts a worker that runs queued jobs
+// until ctx is cancelled. The returned function cancels the worker and
+// waits for running jobs to stop; unfinished jobs are requeued and resume
+// on the next start. If another process (e.g. 'pagent daemon') already
+// serves the queue, jobs are only enqueued here and run there.
+func (h *Handlers) StartQueue(ctx context.Context) (stop func(), err error) {
+	h.jobs.once.Do(func() {
+		cfg := h.loadConfig()
+		h.jobs.queue, h.jobs.err = queue.Open(cfg.Queue.EffectiveDir())
+		if h.jobs.err != nil {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		h.jobs.stop = func() {
+			cancel()
+			<-done
+		}
+		go func() {
+			defer close(done)
+			q := h.jobs.queue
+			err := q.Serve(ctx, cfg.Queue.EffectiveConcurrency(), h.executeJob)
+			switch {
+			case errors.Is(err, queue.ErrWorkerActive):
+				log.Printf("Job queue %s is served by another process; jobs run there", q.Dir())
+			case err != nil:
+				log.Printf("Job queue worker stopped: %v", err)
+			}
+		}()
+	})
+	if h.jobs.err != nil {
+		return nil, h.jobs.err
+	}
+	return h.jobs.stop, nil
+}
+
+// queue returns the job queue, starting it on first use
+func (h *Handlers) queue() (*queue.Queue, error) {
+	if _, err := h.StartQueue(context.Background()); err != nil {
+		return nil, fmt.Errorf("job queue unavailable: %w", err)
+	}
+	return h.jobs.queue, nil
+}
+
+// executeJob runs a queued job with the shared runner, tracking per-agent
+// progress for get_run and reporting written artifacts. Runner output goes
+// to the server log so it never interleaves with the stdio transport.
+func (h *Handlers) executeJob(ctx context.Context, job queue.Job, s runner.Session) error {
+	s.Bus.Subscribe(h.runs.track(job.ID))
+	s.Bus.Subscribe(h.artifactEvents(job.ID), events.AgentCompleted, events.PostProcessStep)
+	defer h.runs.finish(job.ID)
+	s.OnManager = func(m *agent.Manager) { h.runs.setManager(job.ID, m) }
+	s.Resolve = h.resolve
+	s.Audit = &h.loadConfig().Audit
+	return runner.ExecuteSession(ctx, job.Options, runner.NewRunLogger(job.ID, job.Options), s)
+}
+
+// getJob returns a job the caller of ctx may see
+func (h *Handlers) getJob(ctx context.Context, id string) (queue.Job, error) {
+	q, err := h.queue()
+	if err != nil {
+		return queue.Job{}, err
+	}
+	job, err := q.Get(id)
+	if err != nil {
+		return queue.Job{}, err
+	}
+	if err := h.checkOwner(ctx, job); err != nil {
+		return queue.Job{}, err
+	}
+	return job, nil
+}
+
+// enqueue adds a run to the job queue on behalf of the caller of ctx
+func (h *Handlers) enqueue(ctx context.Context, opts config.RunOptions, priority int) (queue.Job, error) {
+	q, err := h.queue()
+	if err != nil {
+		return queue.Job{}, err
+	}
+
+	// Jobs may run in another process, so pass absolute paths
+	for _, p := range []*string{&opts.OutputDir, &opts.ConfigPath, &opts.TargetCodebase, &opts.SpecsOutputDir} {
+		if *p == "" {
+			continue
+		}
+		if *p, err = filepath.Abs(*p); err != nil {
+			return queue.Job{}, err
+		}
+	}
+
+	job, err := q.Enqueue(ctx, opts, priority, audit.SourceMCP, submitter(ctx))
+	if err != nil {
+		return queue.Job{}, err
+	}
+	log.Printf("Queued job %s (priority %d)", job.ID, priority)
+	return job, nil
+}
+
+// runQueued enqueues a run and waits for it to finish. If ctx is cancelled
+// while waiting (e.g. the client went away), the job is cancelled too.
+func (h *Handlers) runQueued(ctx context.Context, opts config.RunOptions, priority int) (queue.Job, error) {
+	job, err := h.enqueue(ctx, opts, priority)
+	if err != nil {
+		return job, err
+	}
+
+	// Report progress while waiting; only runs executed by this server
+	// produce events here
+	if handler := eventHandlerFrom(ctx); handler != nil {
+		stop := h.runs.watch(job.ID, handler)
+		defer stop()
+	}
+
+	job, err = h.jobs.queue.Wait(ctx, job.ID)
+	if err != nil {
+		if ctx.Err() != nil {
+			_, _ = h.jobs.queue.Cancel(job.ID)
+		}
+		return job, err
+	}
+	return job, nil
+}
+
+// runStatus describes a job, using live agent progress while it runs
+func (h *Handlers) runStatus(job queue.Job) RunStatusOutput {
+	output := RunStatusOutput{
+		RunID:       job.ID,
+		State:       job.State,
+		Done:        job.Done(),
+		Input:       job.Options.InputPath,
+		Priority:    job.Priority,
+		SubmittedAt: job.SubmittedAt.Format(time.RFC3339),
+		Agents:      []RunAgentProgress{},
+		Artifacts:   []string{},
+		Error:       job.Error,
+	}
+	if job.StartedAt != nil {
+		output.StartedAt = job.StartedAt.Format(time.RFC3339)
+	}
+	if job.FinishedAt != nil {
+		output.FinishedAt = job.FinishedAt.Format(time.RFC3339)
+	}
+
+	if live, ok := h.runs.agents(job.ID); ok && !job.Done() {
+		output.Agents = live
+	} else {
+		for _, a := range job.Agents {
+			output.Agents = append(output.Agents, RunAgentProgress{
+				Name:       a.Name,
+				Status:     a.Status,
+				OutputPath: a.OutputPath,
+				Duration:   durationString(a.DurationMS),
+				Error:      a.Error,
+			})
+		}
+	}
+
+	for _, a := range output.Agents {
+		if (a.Status == queue.AgentCompleted || a.Status == queue.AgentSkipped) && a.OutputPath != "" {
+			output.Artifacts = append(output.Artifacts, a.OutputPath)
+		}
+	}
+	return output
+}
+
+// agentOutputs converts a finished job's agent results to tool output
+func agentOutputs(job queue.Job) (results []RunAgentOutput, successful, failed int) {
+	results = make([]RunAgentOutput, 0, len(job.Agents))
+	for _, a := range job.Agents {
+		output := RunAgentOutput{
+			RunID:      job.ID,
+			Agent:      a.Name,
+			OutputPath: a.OutputPath,
+			Duration:   (time.Duration(a.DurationMS) * time.Millisecond).String(),
+			Success:    a.Status != queue.AgentFailed,
+			Error:      a.Error,
+		}
+		if output.Success {
+			successful++
+		} else {
+			failed++
+		}
+		results = append(results, output)
+	}
+	return results, successful, failed
+}
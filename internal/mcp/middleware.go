@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tuannvm/pagent/internal/metrics"
+	"github.com/tuannvm/pagent/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// methodCallTool is the MCP method name for tool invocations.
+const methodCallTool = "tools/call"
+
+// tracingMiddleware wraps tools/call requests in a span. The caller's trace
+// context is taken from the request's _meta (traceparent/tracestate keys),
+// falling back to HTTP headers for the streamable HTTP transport.
+func tracingMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != methodCallTool {
+			return next(ctx, method, req)
+		}
+
+		ctx = extractTraceContext(ctx, req)
+
+		tool := toolName(req)
+		ctx, span := telemetry.StartSpan(ctx, "mcp.tools/call "+tool,
+			attribute.String("mcp.method", method),
+			attribute.String("mcp.tool", tool),
+		)
+
+		result, err := next(ctx, method, req)
+		spanErr := err
+		if spanErr == nil && isToolError(result, err) {
+			spanErr = fmt.Errorf("tool %s returned an error", tool)
+		}
+		telemetry.End(span, spanErr)
+
+		return result, err
+	}
+}
+
+// unknownTool labels metrics of calls to tools the server does not have
+const unknownTool = "unknown"
+
+// metricsMiddleware records the count, status and latency of tools/call
+// requests. Tool names come from the client, so calls to tools not in
+// toolNames share one label instead of each adding a series.
+func metricsMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != methodCallTool {
+			return next(ctx, method, req)
+		}
+
+		tool := toolName(req)
+		if !slices.Contains(toolNames, tool) {
+			tool = unknownTool
+		}
+		start := time.Now()
+		result, err := next(ctx, method, req)
+		metrics.Default.ObserveToolCall(tool, isToolError(result, err), time.Since(start))
+		return result, err
+	}
+}
+
+// toolName returns the tool name of a tools/call request
+func toolName(req mcp.Request) string {
+	if params, ok := req.GetParams().(*mcp.CallToolParamsRaw); ok {
+		return params.Name
+	}
+	return ""
+}
+
+// isToolError reports whether a tools/call failed at the protocol or tool level
+func isToolError(result mcp.Result, err error) bool {
+	if err != nil {
+		return true
+	}
+	res, ok := result.(*mcp.CallToolResult)
+	return ok && res != nil && res.IsError
+}
+
+// extractTraceContext returns ctx with the remote span context carried by req
+func extractTraceContext(ctx context.Context, req mcp.Request) context.Context {
+	propagator := otel.GetTextMapPropagator()
+
+	if params := req.GetParams(); params != nil {
+		carrier := propagation.MapCarrier{}
+		for k, v := range params.GetMeta() {
+			if s, ok := v.(string); ok {
+				carrier[k] = s
+			}
+		}
+		if extracted := propagator.Extract(ctx, carrier); trace.SpanContextFromContext(extracted).IsValid() {
+			return extracted
+		}
+	}
+
+	if extra := req.GetExtra(); extra != nil && extra.Header != nil {
+		return propagator.Extract(ctx, propagation.HeaderCarrier(extra.Header))
+	}
+
+	return ctx
+}
@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tuannvm/pagent/internal/policy"
+)
+
+// WithPolicy enables role-based access control for OAuth callers.
+func (h *Handlers) WithPolicy(p *policy.Policy) *Handlers {
+	h.policy = p
+	return h
+}
+
+// grant returns what the caller of ctx may do. Callers without an OAuth
+// token, e.g. over stdio, are not subject to the policy; callers with one
+// are, even if it has no subject.
+func (h *Handlers) grant(ctx context.Context) *policy.Grant {
+	c := callerFrom(ctx)
+	if !c.authenticated {
+		return nil
+	}
+	return h.policy.Grant(c.claims)
+}
+
+// checkAgents returns an error unless the caller of ctx may use every agent
+func (h *Handlers) checkAgents(ctx context.Context, agents []string) error {
+	grant := h.grant(ctx)
+	for _, name := range agents {
+		if err := grant.CheckAgent(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// policyMiddleware rejects tool calls the caller's policy does not allow
+// and hides the tools and agent prompts it may not use from listings
+func (h *Handlers) policyMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if h.policy == nil {
+			return next(ctx, method, req)
+		}
+		ctx = withCaller(ctx, tokenCaller(req.GetExtra()))
+		grant := h.grant(ctx)
+
+		if method == methodCallTool {
+			if err := grant.CheckTool(toolName(req)); err != nil {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}},
+					IsError: true,
+				}, nil
+			}
+			return next(ctx, method, req)
+		}
+
+		result, err := next(ctx, method, req)
+		if err != nil {
+			return result, err
+		}
+		switch res := result.(type) {
+		case *mcp.ListToolsResult:
+			res.Tools = slices.DeleteFunc(slices.Clone(res.Tools), func(t *mcp.Tool) bool {
+				return !grant.AllowsTool(t.Name)
+			})
+		case *mcp.ListPromptsResult:
+			res.Prompts = slices.DeleteFunc(slices.Clone(res.Prompts), func(p *mcp.Prompt) bool {
+				return !grant.AllowsAgent(p.Name)
+			})
+		}
+		return result, nil
+	}
+}
+
+// tokenClaims returns the payload of a JWT access token. The token must
+// already be validated; opaque tokens have no claims.
+func tokenClaims(token string) map[string]any {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil
+	}
+	return claims
+}
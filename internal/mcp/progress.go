@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tuannvm/pagent/internal/events"
+)
+
+// loggerName is reported as the logger of MCP log messages
+const loggerName = "pagent"
+
+type eventHandlerKey struct{}
+
+// withEventHandler returns a context whose synchronous runs report their
+// events to h while the tool call waits for them
+func withEventHandler(ctx context.Context, h events.Handler) context.Context {
+	return context.WithValue(ctx, eventHandlerKey{}, h)
+}
+
+// eventHandlerFrom returns the handler set by withEventHandler, if any
+func eventHandlerFrom(ctx context.Context) events.Handler {
+	h, _ := ctx.Value(eventHandlerKey{}).(events.Handler)
+	return h
+}
+
+// progressReporter turns run events into MCP log messages and, when the
+// client sent a progress token, notifications/progress for the tool call.
+// Progress counts half an agent when it starts and the rest when it
+// finishes, so it increases with every notification.
+func progressReporter(ctx context.Context, req *mcp.CallToolRequest) events.Handler {
+	session := req.Session
+	token := req.Params.GetProgressToken()
+
+	var (
+		progress float64
+		total    float64
+		started  = make(map[string]bool)
+	)
+	return func(e events.Event) {
+		advanced := false
+		switch e.Type {
+		case events.RunStarted:
+			total = float64(len(e.Agents))
+		case events.AgentStarted:
+			if !started[e.Agent] {
+				started[e.Agent] = true
+				progress += 0.5
+				advanced = true
+			}
+		case events.AgentCompleted, events.AgentFailed, events.AgentSkipped:
+			if started[e.Agent] {
+				progress += 0.5
+			} else {
+				progress++
+			}
+			advanced = true
+		}
+
+		message, level := describeEvent(e)
+		if message == "" {
+			return
+		}
+		if token != nil && advanced {
+			_ = session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+				ProgressToken: token,
+				Progress:      progress,
+				Total:         total,
+				Message:       message,
+			})
+		}
+		_ = session.Log(ctx, &mcp.LoggingMessageParams{
+			Level:  level,
+			Logger: loggerName,
+			Data:   message,
+		})
+	}
+}
+
+// describeEvent returns a human-readable message and log level for e,
+// or "" for events not worth reporting
+func describeEvent(e events.Event) (string, mcp.LoggingLevel) {
+	switch e.Type {
+	case events.RunStarted:
+		return fmt.Sprintf("Run %s started: %s", e.RunID, strings.Join(e.Agents, ", ")), "info"
+	case events.RunCompleted:
+		return fmt.Sprintf("Run finished: %d succeeded, %d failed", e.Succeeded, e.Failed), "info"
+	case events.RunPaused:
+		return "Run paused", "notice"
+	case events.RunResumed:
+		return "Run resumed", "notice"
+	case events.AgentStarted:
+		return fmt.Sprintf("%s started on port %d", e.Agent, e.Port), "info"
+	case events.AgentRestarted:
+		return fmt.Sprintf("%s restarted (attempt %d)", e.Agent, e.Attempt), "notice"
+	case events.AgentStatusChanged:
+		return fmt.Sprintf("%s is %s", e.Agent, e.Status), "debug"
+	case events.AgentCompleted:
+		d := (time.Duration(e.DurationMS) * time.Millisecond).Round(time.Second)
+		return fmt.Sprintf("%s completed in %s: %s", e.Agent, d, e.OutputPath), "info"
+	case events.AgentFailed:
+		return fmt.Sprintf("%s failed: %s", e.Agent, e.Error), "error"
+	case events.AgentSkipped:
+		return fmt.Sprintf("%s skipped: %s", e.Agent, e.Reason), "info"
+	case events.PostProcessStep:
+		if e.HasError() {
+			return fmt.Sprintf("Post-processing %s failed: %s", e.Step, e.Error), "warning"
+		}
+		return fmt.Sprintf("Post-processing %s done", e.Step), "info"
+	case events.Log:
+		return e.Message, "debug"
+	}
+	return "", ""
+}
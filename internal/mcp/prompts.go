@@ -0,0 +1,75 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tuannvm/pagent/internal/agent"
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/input"
+)
+
+// AgentPromptInput defines the arguments of an agent's MCP prompt.
+type AgentPromptInput struct {
+	PRDPath   string
+	Persona   string
+	OutputDir string
+}
+
+// AgentPrompt renders the prompt an agent would be started with for a PRD,
+// without starting it. Paths are resolved within the caller's workspace.
+func (h *Handlers) AgentPrompt(ctx context.Context, name string, input AgentPromptInput) (string, error) {
+	if input.PRDPath == "" {
+		return "", fmt.Errorf("prd_path is required")
+	}
+	sandbox, err := h.sandbox(ctx)
+	if err != nil {
+		return "", err
+	}
+	cfg := h.loadConfig()
+	if input.OutputDir != "" {
+		cfg.OutputDir = input.OutputDir
+	}
+	if err := resolvePaths(sandbox, map[string]*string{
+		"prd_path":   &input.PRDPath,
+		"output_dir": &cfg.OutputDir,
+	}); err != nil {
+		return "", err
+	}
+
+	if input.Persona != "" {
+		if !cfg.HasPersona(input.Persona) {
+			return "", fmt.Errorf("invalid persona: %s", input.Persona)
+		}
+		cfg.Persona = input.Persona
+		cfg.ApplyPersona()
+	}
+	if _, ok := cfg.Agents[name]; !ok {
+		return "", fmt.Errorf("unknown agent: %s", name)
+	}
+	grant := h.grant(ctx)
+	if err := grant.CheckAgent(name); err != nil {
+		return "", err
+	}
+	if err := grant.CheckPersona(cfg.Persona); err != nil {
+		return "", err
+	}
+
+	manager, err := newPromptManager(cfg, input.PRDPath)
+	if err != nil {
+		return "", err
+	}
+	return manager.RenderPrompt(name)
+}
+
+// newPromptManager creates a manager over the discovered input files, as a run would
+func newPromptManager(cfg *config.Config, prdPath string) (*agent.Manager, error) {
+	inp, err := input.Discover(prdPath)
+	if err != nil {
+		return nil, fmt.Errorf("input error: %w", err)
+	}
+	if inp.IsDirectory {
+		return agent.NewManagerWithInputs(cfg, inp.PrimaryFile, inp.Files, inp.Path, false), nil
+	}
+	return agent.NewManager(cfg, inp.PrimaryFile, false), nil
+}
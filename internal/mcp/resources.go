@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tuannvm/pagent/internal/events"
+	"github.com/tuannvm/pagent/internal/queue"
+	"github.com/tuannvm/pagent/internal/workspace"
+)
+
+// artifactURIPrefix starts the resource URI of every run artifact:
+// pagent://runs/<run_id>/<file>
+const artifactURIPrefix = "pagent://runs/"
+
+// ArtifactFiles are the generated files exposed as MCP resources
+var ArtifactFiles = []string{
+	"architecture.md",
+	"test-plan.md",
+	"security-assessment.md",
+	"diff-summary.md",
+}
+
+// errArtifactNotFound is returned when a run or its artifact does not exist
+var errArtifactNotFound = errors.New("artifact not found")
+
+// artifactHook is notified when a run executed by this server writes an artifact
+type artifactHook struct {
+	mu sync.Mutex
+	fn func(runID, file string)
+}
+
+// artifactURI returns the resource URI of a run's artifact
+func artifactURI(runID, file string) string {
+	return artifactURIPrefix + runID + "/" + file
+}
+
+// parseArtifactURI splits an artifact URI into run ID and file name
+func parseArtifactURI(uri string) (runID, file string, ok bool) {
+	rest, ok := strings.CutPrefix(uri, artifactURIPrefix)
+	if !ok {
+		return "", "", false
+	}
+	runID, file, ok = strings.Cut(rest, "/")
+	if !ok || runID == "" || !isArtifactFile(file) {
+		return "", "", false
+	}
+	return runID, file, true
+}
+
+func isArtifactFile(file string) bool {
+	return slices.Contains(ArtifactFiles, file)
+}
+
+// ReadArtifact returns the contents of a generated file of a run.
+// file must be one of ArtifactFiles and lie in the caller's workspace.
+func (h *Handlers) ReadArtifact(ctx context.Context, runID, file string) (string, error) {
+	if !isArtifactFile(file) {
+		return "", fmt.Errorf("%w: %s is not a run artifact", errArtifactNotFound, file)
+	}
+	job, err := h.getJob(ctx, runID)
+	if errors.Is(err, queue.ErrNotFound) {
+		return "", fmt.Errorf("%w: unknown run %s", errArtifactNotFound, runID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sandbox, err := h.sandbox(ctx)
+	if err != nil {
+		return "", err
+	}
+	path, err := sandbox.Resolve(h.artifactPath(job, file))
+	if errors.Is(err, workspace.ErrOutsideRoots) {
+		return "", fmt.Errorf("%w: %v", errArtifactNotFound, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: run %s has not written %s", errArtifactNotFound, runID, file)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// artifactPath locates file for a run: the output path reported by the agent
+// that wrote it, else the run's specs directory (e.g. diff-summary.md)
+func (h *Handlers) artifactPath(job queue.Job, file string) string {
+	for _, a := range h.runStatus(job).Agents {
+		if a.OutputPath != "" && filepath.Base(a.OutputPath) == file {
+			return a.OutputPath
+		}
+	}
+
+	cfg := loadConfigOrDefault(job.Options.ConfigPath)
+	if job.Options.OutputDir != "" {
+		cfg.OutputDir = job.Options.OutputDir
+	}
+	if job.Options.Mode != "" {
+		cfg.Mode = job.Options.Mode
+	}
+	if job.Options.TargetCodebase != "" {
+		cfg.TargetCodebase = job.Options.TargetCodebase
+	}
+	if job.Options.SpecsOutputDir != "" {
+		cfg.SpecsOutputDir = job.Options.SpecsOutputDir
+	}
+	return filepath.Join(cfg.GetEffectiveSpecsOutputDir(), file)
+}
+
+// onArtifact registers fn to be called when a run executed by this server
+// writes one of ArtifactFiles
+func (h *Handlers) onArtifact(fn func(runID, file string)) {
+	h.artifacts.mu.Lock()
+	defer h.artifacts.mu.Unlock()
+	h.artifacts.fn = fn
+}
+
+// artifactEvents returns an event handler reporting the artifacts written
+// by run runID: agent outputs and the diff summary post-processing step
+func (h *Handlers) artifactEvents(runID string) events.Handler {
+	return func(e events.Event) {
+		var path string
+		switch e.Type {
+		case events.AgentCompleted:
+			path = e.OutputPath
+		case events.PostProcessStep:
+			if e.Error == "" {
+				path = e.Message
+			}
+		}
+		file := filepath.Base(path)
+		if path == "" || !isArtifactFile(file) {
+			return
+		}
+
+		h.artifacts.mu.Lock()
+		fn := h.artifacts.fn
+		h.artifacts.mu.Unlock()
+		if fn != nil {
+			fn(runID, file)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package mcp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/agent"
+	"github.com/tuannvm/pagent/internal/events"
+)
+
+// finishedRunRetention is how long progress of a finished run is kept;
+// afterwards get_run reports the results stored in the job queue
+const finishedRunRetention = time.Minute
+
+// watcherBuffer bounds the events queued for a slow watcher; further
+// events are dropped rather than blocking the run
+const watcherBuffer = 256
+
+// runRegistry tracks live per-agent progress of runs executed by this
+// server's queue worker and fans their events out to watchers
+type runRegistry struct {
+	mu       sync.Mutex
+	runs     map[string]*runProgress
+	watchers map[string][]*watcher // By run ID; may precede the run starting
+}
+
+// runProgress is the live state of one run
+type runProgress struct {
+	manager    *agent.Manager // Controls the run's agents; nil until it starts them
+	agents     map[string]*RunAgentProgress
+	order      []string       // Agent names in the order they were first seen
+	history    []events.Event // Replayed to watchers that subscribe late
+	finishedAt time.Time
+}
+
+// watcher delivers a run's events to a handler in order, off the run's goroutine
+type watcher struct {
+	ch   chan events.Event
+	done chan struct{}
+}
+
+func newRunRegistry() *runRegistry {
+	return &runRegistry{
+		runs:     make(map[string]*runProgress),
+		watchers: make(map[string][]*watcher),
+	}
+}
+
+// watch delivers the events of runID, past and future, to h until stop is
+// called. The run may not have started yet.
+func (r *runRegistry) watch(runID string, h events.Handler) (stop func()) {
+	w := &watcher{ch: make(chan events.Event, watcherBuffer), done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		for e := range w.ch {
+			h(e)
+		}
+	}()
+
+	r.mu.Lock()
+	if p, ok := r.runs[runID]; ok {
+		for _, e := range p.history {
+			w.send(e)
+		}
+	}
+	r.watchers[runID] = append(r.watchers[runID], w)
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		ws := r.watchers[runID]
+		for i := range ws {
+			if ws[i] == w {
+				ws = append(ws[:i], ws[i+1:]...)
+				break
+			}
+		}
+		if len(ws) == 0 {
+			delete(r.watchers, runID)
+		} else {
+			r.watchers[runID] = ws
+		}
+		r.mu.Unlock()
+
+		close(w.ch)
+		<-w.done
+	}
+}
+
+// send queues e without blocking. Caller holds the registry lock.
+func (w *watcher) send(e events.Event) {
+	select {
+	case w.ch <- e:
+	default:
+	}
+}
+
+// track starts tracking runID and returns the event handler feeding it
+func (r *runRegistry) track(runID string) events.Handler {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, p := range r.runs {
+		if !p.finishedAt.IsZero() && time.Since(p.finishedAt) > finishedRunRetention {
+			delete(r.runs, id)
+		}
+	}
+	p := &runProgress{agents: make(map[string]*RunAgentProgress)}
+	r.runs[runID] = p
+
+	return func(e events.Event) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		p.history = append(p.history, e)
+		for _, w := range r.watchers[runID] {
+			w.send(e)
+		}
+
+		if e.Type == events.RunStarted {
+			for _, name := range e.Agents {
+				p.agent(name)
+			}
+			return
+		}
+		if e.Agent == "" {
+			return
+		}
+
+		a := p.agent(e.Agent)
+		switch e.Type {
+		case events.AgentStarted, events.AgentRestarted:
+			a.Status = "running"
+			a.Port = e.Port
+			a.Error = ""
+		case events.AgentCompleted:
+			a.Status = "completed"
+			a.OutputPath = e.OutputPath
+			a.Duration = durationString(e.DurationMS)
+		case events.AgentFailed:
+			a.Status = "failed"
+			a.Error = e.Error
+			a.Duration = durationString(e.DurationMS)
+		case events.AgentSkipped:
+			a.Status = "skipped"
+			a.OutputPath = e.OutputPath
+		}
+	}
+}
+
+// finish marks runID as finished so it is dropped after the retention period
+func (r *runRegistry) finish(runID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.runs[runID]; ok {
+		p.finishedAt = time.Now()
+	}
+}
+
+// setManager records the agent manager of a tracked run
+func (r *runRegistry) setManager(runID string, m *agent.Manager) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.runs[runID]; ok {
+		p.manager = m
+	}
+}
+
+// manager returns the agent manager of a run executing here, or nil
+func (r *runRegistry) manager(runID string) *agent.Manager {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.runs[runID]; ok && p.finishedAt.IsZero() {
+		return p.manager
+	}
+	return nil
+}
+
+// agents returns a copy of the run's agent progress, if tracked
+func (r *runRegistry) agents(runID string) ([]RunAgentProgress, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.runs[runID]
+	if !ok {
+		return nil, false
+	}
+	agents := make([]RunAgentProgress, 0, len(p.order))
+	for _, name := range p.order {
+		agents = append(agents, *p.agents[name])
+	}
+	return agents, true
+}
+
+// agent returns the progress entry for name, creating a pending one.
+// Caller holds the registry lock.
+func (p *runProgress) agent(name string) *RunAgentProgress {
+	a, ok := p.agents[name]
+	if !ok {
+		a = &RunAgentProgress{Name: name, Status: "pending"}
+		p.agents[name] = a
+		p.order = append(p.order, name)
+	}
+	return a
+}
+
+// durationString formats a millisecond duration, or "" if unset
+func durationString(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return (time.Duration(ms) * time.Millisecond).String()
+}
@@ -2,18 +2,21 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/modelcontextprotocol/go-sdk/auth"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	oauth "github.com/tuannvm/oauth-mcp-proxy"
-	mcpoauth "github.com/tuannvm/oauth-mcp-proxy/mcp"
+	"github.com/tuannvm/pagent/internal/metrics"
 )
 
 const (
@@ -29,15 +32,22 @@ const ServerInstructions = `Pagent is a PRD-to-code orchestration tool that tran
 Available tools:
 - run_agent: Run a single agent (architect, qa, security, implementer, verifier)
 - run_pipeline: Run the full agent pipeline with dependency resolution
+- get_run: Get the state, per-agent progress and artifacts of a run
+- wait_run: Wait (up to 50s) for a run to finish
+- cancel_run: Cancel a queued or running run
 - list_agents: List all available agents and their dependencies
 - get_status: Check status of running agents
 - send_message: Send guidance to a running agent
 - stop_agents: Stop running agents
 
+Generated specs are readable as resources at pagent://runs/<run_id>/<file>
+(architecture.md, test-plan.md, security-assessment.md, diff-summary.md).
+Each agent's prompt template is available as a prompt taking prd_path and persona.
+
 Typical workflow:
 1. Use list_agents to understand available agents
-2. Use run_pipeline with a PRD file to generate architecture, tests, security assessment, and code
-3. Monitor progress with get_status
+2. Use run_pipeline with a PRD file and async=true to generate architecture, tests, security assessment, and code
+3. Monitor progress with get_run or wait_run (pipelines often take longer than a single call)
 4. Send corrections with send_message if needed`
 
 // ServerConfig holds configuration for creating an MCP server.
@@ -54,6 +64,10 @@ type ServerConfig struct {
 
 	// OAuth settings (optional)
 	OAuth *OAuthConfig
+
+	// DisableMetrics stops the HTTP transports serving Prometheus metrics,
+	// which are not protected by OAuth
+	DisableMetrics bool
 }
 
 // OAuthConfig holds OAuth-specific configuration.
@@ -116,11 +130,21 @@ func NewServer(cfg *ServerConfig) *Server {
 		&mcp.ServerOptions{
 			Instructions: cfg.Instructions,
 			Logger:       cfg.Logger,
+			// Any artifact URI may be subscribed to before the run writes it
+			SubscribeHandler:   func(context.Context, *mcp.SubscribeRequest) error { return nil },
+			UnsubscribeHandler: func(context.Context, *mcp.UnsubscribeRequest) error { return nil },
 		},
 	)
 
-	// Register all tools
+	// Trace tool calls (a no-op unless tracing is configured), count them,
+	// audit them and enforce the access policy
+	mcpServer.AddReceivingMiddleware(tracingMiddleware, metricsMiddleware,
+		cfg.Handlers.auditMiddleware, cfg.Handlers.policyMiddleware)
+
+	// Register all tools, resources and prompts
 	registerTools(mcpServer, cfg.Handlers)
+	registerResources(mcpServer, cfg.Handlers)
+	registerPrompts(mcpServer, cfg.Handlers)
 
 	return &Server{
 		mcpServer: mcpServer,
@@ -128,10 +152,14 @@ func NewServer(cfg *ServerConfig) *Server {
 	}
 }
 
-// ServeStdio starts the MCP server with STDIO transport.
+// ServeStdio starts the MCP server with STDIO transport. The transport
+// keeps the process's stdout and os.Stdout is pointed at stderr, so no
+// stray print from a run can corrupt the JSON-RPC stream.
 func (s *Server) ServeStdio() error {
 	log.Println("Starting pagent MCP server on stdio transport")
-	return s.mcpServer.Run(context.Background(), &mcp.StdioTransport{})
+	transport := &mcp.IOTransport{Reader: os.Stdin, Writer: os.Stdout}
+	os.Stdout = os.Stderr
+	return s.mcpServer.Run(context.Background(), transport)
 }
 
 // ServeHTTP starts the MCP server with streamable HTTP transport.
@@ -152,6 +180,9 @@ func (s *Server) ServeHTTP() error {
 	addr := fmt.Sprintf(":%d", s.config.Port)
 	log.Printf("Starting pagent MCP server on http://localhost%s/mcp", addr)
 	log.Printf("Health check: http://localhost%s/health", addr)
+	if !s.config.DisableMetrics {
+		log.Printf("Metrics: http://localhost%s%s", addr, metrics.Path)
+	}
 
 	return s.runHTTPServer(addr, mux)
 }
@@ -170,18 +201,37 @@ func (s *Server) ServeHTTPWithOAuth() error {
 
 	mux := http.NewServeMux()
 
-	// Create OAuth-protected handler
-	oauthServer, handler, err := mcpoauth.WithOAuth(mux, &oauth.Config{
+	// Create OAuth server with its discovery endpoints
+	oauthServer, err := oauth.NewServer(&oauth.Config{
 		Provider:  s.config.OAuth.Provider,
 		Issuer:    s.config.OAuth.Issuer,
 		Audience:  s.config.OAuth.Audience,
 		ServerURL: serverURL,
-	}, s.mcpServer)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create OAuth server: %w", err)
 	}
+	oauthServer.RegisterHandlers(mux)
 	s.oauthServer = oauthServer
 
+	streamable := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return s.mcpServer
+	}, &mcp.StreamableHTTPOptions{
+		SessionTimeout: s.config.SessionTimeout,
+		Logger:         s.config.Logger,
+	})
+
+	// The OAuth server validates the token; the SDK middleware then hands the
+	// authenticated subject to tool handlers as token info
+	protected := oauthServer.WrapHandler(auth.RequireBearerToken(verifyOAuthUser, nil)(streamable))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			streamable.ServeHTTP(w, r)
+			return
+		}
+		protected.ServeHTTP(w, r)
+	})
+
 	mux.Handle("/mcp", handler)
 	s.addHealthCheck(mux)
 
@@ -194,8 +244,40 @@ func (s *Server) ServeHTTPWithOAuth() error {
 	return s.runHTTPServer(addr, mux)
 }
 
-// addHealthCheck adds a health check endpoint to the mux.
+// verifyOAuthUser converts the user validated by the OAuth server into token
+// info for the request, carrying the token's claims for the access policy.
+// Validation already happened, so the info only needs to outlive the request.
+func verifyOAuthUser(ctx context.Context, token string, _ *http.Request) (*auth.TokenInfo, error) {
+	user, ok := oauth.GetUserFromContext(ctx)
+	if !ok {
+		return nil, auth.ErrInvalidToken
+	}
+	claims := tokenClaims(token)
+	if claims == nil {
+		claims = map[string]any{}
+	}
+	claims["sub"] = user.Subject
+	if _, ok := claims["email"]; !ok && user.Email != "" {
+		claims["email"] = user.Email
+	}
+	if _, ok := claims["preferred_username"]; !ok && user.Username != "" {
+		claims["preferred_username"] = user.Username
+	}
+	return &auth.TokenInfo{
+		UserID:     user.Subject,
+		Expiration: time.Now().Add(time.Minute),
+		Extra:      claims,
+	}, nil
+}
+
+// addHealthCheck adds a health check endpoint and, unless disabled, the
+// metrics endpoint to the mux. Both are served without authentication so
+// probes and scrapers need no token; metrics only carry counts per tool,
+// agent and outcome.
 func (s *Server) addHealthCheck(mux *http.ServeMux) {
+	if !s.config.DisableMetrics {
+		mux.Handle(metrics.Path, metrics.Default.Handler())
+	}
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -238,10 +320,19 @@ func boolPtr(b bool) *bool {
 	return &b
 }
 
+// toolNames lists the tools registerTools registers
+var toolNames = []string{
+	"run_agent", "run_pipeline", "get_run", "wait_run", "cancel_run",
+	"list_agents", "get_status", "send_message", "stop_agents",
+}
+
 // registerTools registers all pagent tools with the MCP server.
 func registerTools(server *mcp.Server, h *Handlers) {
 	registerRunAgentTool(server, h)
 	registerRunPipelineTool(server, h)
+	registerGetRunTool(server, h)
+	registerWaitRunTool(server, h)
+	registerCancelRunTool(server, h)
 	registerListAgentsTool(server, h)
 	registerGetStatusTool(server, h)
 	registerSendMessageTool(server, h)
@@ -252,7 +343,7 @@ func registerRunAgentTool(server *mcp.Server, h *Handlers) {
 	mcp.AddTool(server,
 		&mcp.Tool{
 			Name:        "run_agent",
-			Description: "Run a single pagent agent on a PRD file. Agents: architect (creates architecture), qa (test plan), security (threat model), implementer (code), verifier (validation).",
+			Description: "Run a single pagent agent on a PRD file. Agents: architect (creates architecture), qa (test plan), security (threat model), implementer (code), verifier (validation). Set async=true to get a run_id immediately.",
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Run Agent",
 				ReadOnlyHint:    false,
@@ -262,6 +353,7 @@ func registerRunAgentTool(server *mcp.Server, h *Handlers) {
 			},
 		},
 		func(ctx context.Context, req *mcp.CallToolRequest, input RunAgentInput) (*mcp.CallToolResult, RunAgentOutput, error) {
+			ctx = withEventHandler(callerContext(ctx, req.Session, req.Extra), progressReporter(ctx, req))
 			return nil, h.RunAgent(ctx, input), nil
 		},
 	)
@@ -271,7 +363,7 @@ func registerRunPipelineTool(server *mcp.Server, h *Handlers) {
 	mcp.AddTool(server,
 		&mcp.Tool{
 			Name:        "run_pipeline",
-			Description: "Run the full pagent pipeline on a PRD file. Executes agents in dependency order: architect -> qa/security (parallel) -> implementer -> verifier.",
+			Description: "Run the full pagent pipeline on a PRD file. Executes agents in dependency order: architect -> qa/security (parallel) -> implementer -> verifier. Set async=true to get a run_id immediately and follow it with get_run or wait_run.",
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Run Pipeline",
 				ReadOnlyHint:    false,
@@ -281,12 +373,71 @@ func registerRunPipelineTool(server *mcp.Server, h *Handlers) {
 			},
 		},
 		func(ctx context.Context, req *mcp.CallToolRequest, input RunPipelineInput) (*mcp.CallToolResult, RunPipelineOutput, error) {
+			ctx = withEventHandler(callerContext(ctx, req.Session, req.Extra), progressReporter(ctx, req))
 			output, err := h.RunPipeline(ctx, input)
 			return nil, output, err
 		},
 	)
 }
 
+func registerGetRunTool(server *mcp.Server, h *Handlers) {
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "get_run",
+			Description: "Get the state of a run started by run_agent or run_pipeline: per-agent progress, artifacts and errors.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:          "Get Run",
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  boolPtr(false),
+			},
+		},
+		func(ctx context.Context, req *mcp.CallToolRequest, input GetRunInput) (*mcp.CallToolResult, RunStatusOutput, error) {
+			output, err := h.GetRun(callerContext(ctx, req.Session, req.Extra), input)
+			return nil, output, err
+		},
+	)
+}
+
+func registerWaitRunTool(server *mcp.Server, h *Handlers) {
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "wait_run",
+			Description: "Wait for a run to finish, up to timeout_seconds (default 30, max 50). Returns the run state either way; call again while done is false.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:          "Wait Run",
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  boolPtr(false),
+			},
+		},
+		func(ctx context.Context, req *mcp.CallToolRequest, input WaitRunInput) (*mcp.CallToolResult, RunStatusOutput, error) {
+			output, err := h.WaitRun(callerContext(ctx, req.Session, req.Extra), input)
+			return nil, output, err
+		},
+	)
+}
+
+func registerCancelRunTool(server *mcp.Server, h *Handlers) {
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "cancel_run",
+			Description: "Cancel a queued or running run and stop its agents.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Cancel Run",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		},
+		func(ctx context.Context, req *mcp.CallToolRequest, input CancelRunInput) (*mcp.CallToolResult, RunStatusOutput, error) {
+			output, err := h.CancelRun(callerContext(ctx, req.Session, req.Extra), input)
+			return nil, output, err
+		},
+	)
+}
+
 func registerListAgentsTool(server *mcp.Server, h *Handlers) {
 	mcp.AddTool(server,
 		&mcp.Tool{
@@ -309,7 +460,7 @@ func registerGetStatusTool(server *mcp.Server, h *Handlers) {
 	mcp.AddTool(server,
 		&mcp.Tool{
 			Name:        "get_status",
-			Description: "Get the status of running pagent agents. Returns agent name, port, and current status (running/stable).",
+			Description: "Get the status of running pagent agents of your runs. Returns agent name, run ID, port, and current status (running/stable).",
 			Annotations: &mcp.ToolAnnotations{
 				Title:          "Get Status",
 				ReadOnlyHint:   true,
@@ -318,7 +469,8 @@ func registerGetStatusTool(server *mcp.Server, h *Handlers) {
 			},
 		},
 		func(ctx context.Context, req *mcp.CallToolRequest, input GetStatusInput) (*mcp.CallToolResult, GetStatusOutput, error) {
-			return nil, h.GetStatus(ctx, input), nil
+			out, err := h.GetStatus(callerContext(ctx, req.Session, req.Extra), input)
+			return nil, out, err
 		},
 	)
 }
@@ -337,7 +489,7 @@ func registerSendMessageTool(server *mcp.Server, h *Handlers) {
 			},
 		},
 		func(ctx context.Context, req *mcp.CallToolRequest, input SendMessageInput) (*mcp.CallToolResult, SendMessageOutput, error) {
-			return nil, h.SendMessage(ctx, input), nil
+			return nil, h.SendMessage(callerContext(ctx, req.Session, req.Extra), input), nil
 		},
 	)
 }
@@ -346,7 +498,7 @@ func registerStopAgentsTool(server *mcp.Server, h *Handlers) {
 	mcp.AddTool(server,
 		&mcp.Tool{
 			Name:        "stop_agents",
-			Description: "Stop running pagent agents. Specify agent_name to stop a specific agent, or leave empty to stop all.",
+			Description: "Stop running pagent agents of your runs. Specify agent_name to stop a specific agent, or leave empty to stop all.",
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Stop Agents",
 				ReadOnlyHint:    false,
@@ -356,7 +508,81 @@ func registerStopAgentsTool(server *mcp.Server, h *Handlers) {
 			},
 		},
 		func(ctx context.Context, req *mcp.CallToolRequest, input StopAgentsInput) (*mcp.CallToolResult, StopAgentsOutput, error) {
-			return nil, h.StopAgents(ctx, input), nil
+			return nil, h.StopAgents(callerContext(ctx, req.Session, req.Extra), input), nil
 		},
 	)
 }
+
+// registerResources exposes run artifacts as resources. Every artifact is
+// readable through the URI template; artifacts written by runs this server
+// executes are also listed and announced with resource-updated notifications.
+func registerResources(server *mcp.Server, h *Handlers) {
+	read := func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		uri := req.Params.URI
+		runID, file, ok := parseArtifactURI(uri)
+		if !ok {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		text, err := h.ReadArtifact(callerContext(ctx, req.Session, req.Extra), runID, file)
+		if errors.Is(err, errArtifactNotFound) {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "text/markdown", Text: text}},
+		}, nil
+	}
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "run-artifact",
+		Title:       "Run Artifact",
+		URITemplate: artifactURIPrefix + "{run_id}/{file}",
+		Description: "Generated file of a run: " + strings.Join(ArtifactFiles, ", "),
+		MIMEType:    "text/markdown",
+	}, read)
+
+	h.onArtifact(func(runID, file string) {
+		uri := artifactURI(runID, file)
+		server.AddResource(&mcp.Resource{
+			URI:      uri,
+			Name:     runID + "/" + file,
+			MIMEType: "text/markdown",
+		}, read)
+		_ = server.ResourceUpdated(context.Background(), &mcp.ResourceUpdatedNotificationParams{URI: uri})
+	})
+}
+
+// registerPrompts adds one prompt per configured agent, rendering the
+// agent's template for a PRD
+func registerPrompts(server *mcp.Server, h *Handlers) {
+	for _, info := range h.ListAgents(context.Background(), ListAgentsInput{}).Agents {
+		name := info.Name
+		server.AddPrompt(&mcp.Prompt{
+			Name:        name,
+			Description: fmt.Sprintf("Prompt the %s agent is started with (writes %s). %s", name, info.Output, info.Description),
+			Arguments: []*mcp.PromptArgument{
+				{Name: "prd_path", Description: "Absolute path to the PRD or requirements file", Required: true},
+				{Name: "persona", Description: "Implementation style: minimal/balanced/production or a persona defined in the config (default: from config)"},
+				{Name: "output_dir", Description: "Output directory the agent writes to (default: ./outputs)"},
+			},
+		}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			args := req.Params.Arguments
+			text, err := h.AgentPrompt(callerContext(ctx, req.Session, req.Extra), name, AgentPromptInput{
+				PRDPath:   args["prd_path"],
+				Persona:   args["persona"],
+				OutputDir: args["output_dir"],
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.GetPromptResult{
+				Description: fmt.Sprintf("%s prompt for %s", name, args["prd_path"]),
+				Messages: []*mcp.PromptMessage{
+					{Role: "user", Content: &mcp.TextContent{Text: text}},
+				},
+			}, nil
+		})
+	}
+}
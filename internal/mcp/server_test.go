@@ -0,0 +1,69 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tuannvm/pagent/internal/metrics"
+)
+
+// connect returns a client session of a server with h's tools
+func connect(t *testing.T, h *Handlers) *mcp.ClientSession {
+	t.Helper()
+	cfg := DefaultServerConfig()
+	cfg.Handlers = h
+	server := NewServer(cfg)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	serverSession, err := server.mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = serverSession.Close() })
+	client := mcp.NewClient(&mcp.Implementation{Name: "test"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = session.Close() })
+	return session
+}
+
+func TestToolNamesListsRegisteredTools(t *testing.T) {
+	h, _ := newTestHandlers(t, "")
+	res, err := connect(t, h).ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range res.Tools {
+		names = append(names, tool.Name)
+	}
+	slices.Sort(names)
+	want := slices.Sorted(slices.Values(toolNames))
+	if !slices.Equal(names, want) {
+		t.Errorf("registered tools = %v, toolNames = %v", names, want)
+	}
+}
+
+func TestMetricsLabelUnknownTools(t *testing.T) {
+	h, _ := newTestHandlers(t, "")
+	session := connect(t, h)
+	_, _ = session.CallTool(context.Background(), &mcp.CallToolParams{Name: "made_up_tool_1234"})
+
+	rec := httptest.NewRecorder()
+	metrics.Default.Handler().ServeHTTP(rec, httptest.NewRequest("GET", metrics.Path, nil))
+	body, _ := io.ReadAll(rec.Body)
+	if strings.Contains(string(body), "made_up_tool_1234") {
+		t.Error("metrics are labelled with a tool name the client made up")
+	}
+	if !strings.Contains(string(body), `tool="unknown"`) {
+		t.Errorf("metrics have no unknown tool label:\n%s", body)
+	}
+}
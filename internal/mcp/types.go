@@ -2,17 +2,38 @@
 // It exposes pagent's agent orchestration capabilities as MCP tools.
 package mcp
 
+// RunSettings holds the run options shared by run_agent and run_pipeline.
+// They mirror config.RunOptions, so MCP runs behave like 'pagent run'.
+type RunSettings struct {
+	OutputDir      string         `json:"output_dir,omitempty" jsonschema:"Output directory for generated files (default: ./outputs)"`
+	Persona        string         `json:"persona,omitempty" jsonschema:"Implementation style: minimal/balanced/production or a persona defined in the config (default: balanced)"`
+	Preset         string         `json:"preset,omitempty" jsonschema:"Preset profile the config is layered on, e.g. go-api, python-ml, typescript-web, prototype (default: from config)"`
+	ResumeMode     string         `json:"resume_mode,omitempty" jsonschema:"normal (regenerate all), resume (skip up-to-date outputs) or force (ignore existing outputs) (default: normal)"`
+	Architecture   string         `json:"architecture,omitempty" jsonschema:"config (use config setting), stateless or database (default: config)"`
+	Resolve        string         `json:"resolve,omitempty" jsonschema:"Resolve stack conflicts between the PRD and the config: prefer-prd, prefer-config, or fail to stop the run (default: the server's -resolve, else unresolved; agents are told about the conflicts and follow the PRD)"`
+	Timeout        *int           `json:"timeout,omitempty" jsonschema:"Timeout per agent in seconds, 0 for no limit (default: from config)"`
+	ConfigPath     string         `json:"config_path,omitempty" jsonschema:"Config file path (default: the server's config); over HTTP only files listed in the server's mcp.config_paths"`
+	Mode           string         `json:"mode,omitempty" jsonschema:"create (new project) or modify (existing codebase) (default: from config)"`
+	TargetCodebase string         `json:"target_codebase,omitempty" jsonschema:"Existing codebase to modify; required for mode=modify"`
+	SpecsOutputDir string         `json:"specs_output_dir,omitempty" jsonschema:"Directory for spec outputs (default: output_dir, or <target_codebase>/.pagent/specs in modify mode)"`
+	Stack          map[string]any `json:"stack,omitempty" jsonschema:"Stack overrides keyed like the config file, e.g. {\"database\": \"none\", \"cloud\": \"gcp\"}"`
+	Preferences    map[string]any `json:"preferences,omitempty" jsonschema:"Preference overrides keyed like the config file, e.g. {\"api_style\": \"grpc\", \"language\": \"python\"}"`
+	Verbose        bool           `json:"verbose,omitempty" jsonschema:"Enable verbose debug output"`
+	Priority       int            `json:"priority,omitempty" jsonschema:"Job queue priority; higher runs first (default: 0)"`
+	Async          bool           `json:"async,omitempty" jsonschema:"Return a run_id immediately instead of waiting; follow up with get_run or wait_run"`
+}
+
 // RunAgentInput defines parameters for running a single agent.
 type RunAgentInput struct {
-	PRDPath   string `json:"prd_path" jsonschema:"Absolute path to the PRD or requirements file"`
+	PRDPath   string `json:"prd_path" jsonschema:"Absolute path to the PRD or requirements file, or a directory of input files"`
 	AgentName string `json:"agent_name" jsonschema:"Name of the agent to run (architect/qa/security/implementer/verifier)"`
-	OutputDir string `json:"output_dir,omitempty" jsonschema:"Output directory for generated files (default: ./outputs)"`
-	Persona   string `json:"persona,omitempty" jsonschema:"Implementation style: minimal/balanced/production (default: balanced)"`
-	Verbose   bool   `json:"verbose,omitempty" jsonschema:"Enable verbose debug output"`
+	RunSettings
 }
 
 // RunAgentOutput contains the result of running an agent.
 type RunAgentOutput struct {
+	RunID      string `json:"run_id,omitempty"`
+	State      string `json:"state,omitempty"` // Set for async calls: the run's queue state
 	Agent      string `json:"agent"`
 	OutputPath string `json:"output_path"`
 	Duration   string `json:"duration"`
@@ -22,16 +43,16 @@ type RunAgentOutput struct {
 
 // RunPipelineInput defines parameters for running the full agent pipeline.
 type RunPipelineInput struct {
-	PRDPath    string   `json:"prd_path" jsonschema:"Absolute path to the PRD or requirements file"`
+	PRDPath    string   `json:"prd_path" jsonschema:"Absolute path to the PRD or requirements file, or a directory of input files"`
 	Agents     []string `json:"agents,omitempty" jsonschema:"Specific agents to run (default: all agents in dependency order)"`
-	OutputDir  string   `json:"output_dir,omitempty" jsonschema:"Output directory for generated files (default: ./outputs)"`
-	Persona    string   `json:"persona,omitempty" jsonschema:"Implementation style: minimal/balanced/production (default: balanced)"`
 	Sequential bool     `json:"sequential,omitempty" jsonschema:"Run agents sequentially instead of parallel-by-level"`
-	Verbose    bool     `json:"verbose,omitempty" jsonschema:"Enable verbose debug output"`
+	RunSettings
 }
 
 // RunPipelineOutput contains the results of running the pipeline.
 type RunPipelineOutput struct {
+	RunID         string           `json:"run_id"`
+	State         string           `json:"state"`
 	Results       []RunAgentOutput `json:"results"`
 	TotalAgents   int              `json:"total_agents"`
 	Successful    int              `json:"successful"`
@@ -39,6 +60,47 @@ type RunPipelineOutput struct {
 	TotalDuration string           `json:"total_duration"`
 }
 
+// GetRunInput defines parameters for looking up a run.
+type GetRunInput struct {
+	RunID string `json:"run_id" jsonschema:"Run ID returned by run_agent or run_pipeline"`
+}
+
+// WaitRunInput defines parameters for waiting on a run.
+type WaitRunInput struct {
+	RunID          string `json:"run_id" jsonschema:"Run ID returned by run_agent or run_pipeline"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty" jsonschema:"Maximum time to wait in seconds (default: 30, max: 50)"`
+}
+
+// CancelRunInput defines parameters for cancelling a run.
+type CancelRunInput struct {
+	RunID string `json:"run_id" jsonschema:"Run ID returned by run_agent or run_pipeline"`
+}
+
+// RunAgentProgress describes one agent of a run.
+type RunAgentProgress struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // pending, running, completed, failed, skipped
+	Port       int    `json:"port,omitempty"`
+	OutputPath string `json:"output_path,omitempty"`
+	Duration   string `json:"duration,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RunStatusOutput describes a queued, running or finished run.
+type RunStatusOutput struct {
+	RunID       string             `json:"run_id"`
+	State       string             `json:"state"` // queued, running, succeeded, failed, cancelled
+	Done        bool               `json:"done"`
+	Input       string             `json:"input"`
+	Priority    int                `json:"priority"`
+	SubmittedAt string             `json:"submitted_at"`
+	StartedAt   string             `json:"started_at,omitempty"`
+	FinishedAt  string             `json:"finished_at,omitempty"`
+	Agents      []RunAgentProgress `json:"agents"`
+	Artifacts   []string           `json:"artifacts"` // Output paths of completed agents
+	Error       string             `json:"error,omitempty"`
+}
+
 // ListAgentsInput defines parameters for listing agents.
 type ListAgentsInput struct{}
 
@@ -47,6 +109,7 @@ type AgentInfo struct {
 	Name        string   `json:"name"`
 	Output      string   `json:"output"`
 	DependsOn   []string `json:"depends_on"`
+	Kind        string   `json:"kind"` // spec, code or other
 	Description string   `json:"description"`
 }
 
@@ -63,6 +126,7 @@ type GetStatusInput struct {
 // AgentStatus describes the status of a running agent.
 type AgentStatus struct {
 	Name      string `json:"name"`
+	RunID     string `json:"run_id,omitempty"`
 	Port      int    `json:"port"`
 	Status    string `json:"status"` // "running" or "stable"
 	StartedAt string `json:"started_at,omitempty"`
@@ -0,0 +1,171 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tuannvm/pagent/internal/audit"
+	"github.com/tuannvm/pagent/internal/queue"
+	"github.com/tuannvm/pagent/internal/workspace"
+)
+
+// caller identifies the client behind a request, for confining its paths
+type caller struct {
+	subject       string         // OAuth subject; empty without OAuth
+	claims        map[string]any // OAuth token claims
+	clientRoots   []string       // Local paths of the client's roots; nil if not supported
+	remote        bool           // Connected over HTTP rather than stdio
+	authenticated bool           // Presented a validated OAuth token, even one without a subject
+}
+
+type callerKey struct{}
+
+// withCaller attaches the requesting client to ctx
+func withCaller(ctx context.Context, c caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+// callerFrom returns the requesting client attached to ctx
+func callerFrom(ctx context.Context) caller {
+	c, _ := ctx.Value(callerKey{}).(caller)
+	return c
+}
+
+// callerContext attaches the client behind an MCP request to ctx: the
+// subject and claims of its OAuth token and, if it supports roots, its file
+// roots
+func callerContext(ctx context.Context, session *mcp.ServerSession, extra *mcp.RequestExtra) context.Context {
+	c := tokenCaller(extra)
+	if session != nil {
+		if params := session.InitializeParams(); params != nil && params.Capabilities != nil && params.Capabilities.RootsV2 != nil {
+			c.clientRoots = listClientRoots(ctx, session)
+		}
+	}
+	return withCaller(ctx, c)
+}
+
+// tokenCaller returns the caller identified by the OAuth token of a
+// request. Requests carrying HTTP headers or a token come from a remote client.
+func tokenCaller(extra *mcp.RequestExtra) caller {
+	if extra == nil {
+		return caller{}
+	}
+	c := caller{remote: extra.Header != nil || extra.TokenInfo != nil, authenticated: extra.TokenInfo != nil}
+	if extra.TokenInfo != nil {
+		c.subject, c.claims = extra.TokenInfo.UserID, extra.TokenInfo.Extra
+	}
+	return c
+}
+
+// listClientRoots returns the local paths of the client's file roots, or nil
+// if they cannot be listed; roots only ever narrow the configured workspace
+func listClientRoots(ctx context.Context, session *mcp.ServerSession) []string {
+	res, err := session.ListRoots(ctx, nil)
+	if err != nil {
+		log.Printf("Warning: failed to list client roots: %v", err)
+		return nil
+	}
+	roots := []string{}
+	for _, root := range res.Roots {
+		u, err := url.Parse(root.URI)
+		if err != nil || u.Scheme != "file" {
+			continue
+		}
+		roots = append(roots, u.Path)
+	}
+	return roots
+}
+
+// sandbox returns the workspace the caller of ctx may use: its own
+// directory when per-subject workspaces are enabled, else the configured
+// roots, narrowed to the roots of its policy rules and those the client
+// announced
+func (h *Handlers) sandbox(ctx context.Context) (*workspace.Sandbox, error) {
+	c := callerFrom(ctx)
+	cfg := h.loadConfig().MCP
+
+	var (
+		s   *workspace.Sandbox
+		err error
+	)
+	dir := cfg.EffectiveSubjectWorkspaces()
+	if dir != "" && c.authenticated && c.subject == "" {
+		return nil, fmt.Errorf("the OAuth token has no subject to pick a workspace for")
+	}
+	if dir != "" && c.subject != "" {
+		home, err := workspace.SubjectDir(dir, c.subject)
+		if err != nil {
+			return nil, err
+		}
+		s, err = workspace.New(home)
+		if err != nil {
+			return nil, err
+		}
+	} else if s, err = workspace.New(cfg.EffectiveRoots()...); err != nil {
+		return nil, fmt.Errorf("invalid mcp.roots: %w", err)
+	}
+
+	if roots := h.grant(ctx).Roots(); len(roots) > 0 {
+		granted, err := workspace.New(roots...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy roots: %w", err)
+		}
+		s = s.Intersect(granted)
+	}
+	if c.clientRoots != nil {
+		s = s.Restrict(c.clientRoots...)
+	}
+	return s, nil
+}
+
+// checkConfigPath returns an error unless the caller of ctx may run with
+// the config file at path. A config can run commands, so remote callers may
+// only use the files the server's mcp.config_paths lists; local (stdio)
+// callers may use any file while paths are unrestricted.
+func (h *Handlers) checkConfigPath(ctx context.Context, sandbox *workspace.Sandbox, path string) error {
+	if h.loadConfig().MCP.AllowsConfigPath(path) {
+		return nil
+	}
+	if callerFrom(ctx).remote {
+		return fmt.Errorf("config_path is not allowed: the server's mcp.config_paths does not list it")
+	}
+	if sandbox != nil {
+		return fmt.Errorf("config_path is not allowed when workspace roots apply")
+	}
+	return nil
+}
+
+// submitter names the caller of ctx in the job queue, as its tool calls
+// are named in the audit log
+func submitter(ctx context.Context) string {
+	return callerFrom(ctx).actor()
+}
+
+// actor names the caller: the OAuth subject, "anonymous" for
+// unauthenticated HTTP and the local user for stdio
+func (c caller) actor() string {
+	switch {
+	case c.subject != "":
+		return c.subject
+	case c.remote:
+		return "anonymous"
+	default:
+		return audit.LocalUser()
+	}
+}
+
+// checkOwner hides runs of other subjects when per-subject workspaces are
+// enabled. Tokens without a subject own no runs.
+func (h *Handlers) checkOwner(ctx context.Context, job queue.Job) error {
+	c := callerFrom(ctx)
+	if !c.authenticated || h.loadConfig().MCP.SubjectWorkspaces == "" {
+		return nil
+	}
+	if c.subject == "" || job.Source != audit.SourceMCP || job.Submitter != c.subject {
+		return fmt.Errorf("%w: %s", queue.ErrNotFound, job.ID)
+	}
+	return nil
+}
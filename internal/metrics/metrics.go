@@ -0,0 +1,197 @@
+// Package metrics exposes Prometheus metrics for long-running pagent processes.
+// Collectors are fed from the event bus (see Metrics.Handle) and, for MCP
+// tool calls, from the MCP server middleware.
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tuannvm/pagent/internal/events"
+)
+
+// Path is the HTTP path metrics are served on
+const Path = "/metrics"
+
+// Outcome label values
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailed  = "failed"
+	OutcomeSkipped = "skipped"
+)
+
+// durationBuckets covers agent runs from seconds up to an hour
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+
+// Metrics holds pagent's collectors and the registry they are exported from
+type Metrics struct {
+	registry *prometheus.Registry
+
+	runs          *prometheus.CounterVec
+	runDuration   *prometheus.HistogramVec
+	agentRuns     *prometheus.CounterVec
+	agentDuration *prometheus.HistogramVec
+	agentRetries  *prometheus.CounterVec
+	activeAgents  prometheus.Gauge
+	resumeSkips   *prometheus.CounterVec
+	toolCalls     *prometheus.CounterVec
+	toolDuration  *prometheus.HistogramVec
+
+	mu     sync.Mutex
+	active map[string]bool // run_id/agent pairs counted in activeAgents
+}
+
+// Default is the process-wide metrics instance
+var Default = New()
+
+// New creates a Metrics with its own registry, including Go runtime and
+// process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pagent_runs_total",
+			Help: "Pipeline runs by outcome.",
+		}, []string{"outcome"}),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pagent_run_duration_seconds",
+			Help:    "Pipeline run duration.",
+			Buckets: durationBuckets,
+		}, []string{"outcome"}),
+		agentRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pagent_agent_runs_total",
+			Help: "Agent runs by agent and outcome (success, failed, skipped).",
+		}, []string{"agent", "outcome"}),
+		agentDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pagent_agent_duration_seconds",
+			Help:    "Agent run duration by agent and outcome.",
+			Buckets: durationBuckets,
+		}, []string{"agent", "outcome"}),
+		agentRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pagent_agent_retries_total",
+			Help: "Agent attempts that were retried, e.g. after a restart.",
+		}, []string{"agent"}),
+		activeAgents: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "pagent_active_agents",
+			Help: "Agents currently running.",
+		}),
+		resumeSkips: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pagent_resume_skips_total",
+			Help: "Agents skipped because their outputs were up-to-date.",
+		}, []string{"agent"}),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pagent_mcp_tool_calls_total",
+			Help: "MCP tool calls by tool and status (ok, error).",
+		}, []string{"tool", "status"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pagent_mcp_tool_duration_seconds",
+			Help:    "MCP tool call duration by tool.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"tool"}),
+		active: make(map[string]bool),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.runs, m.runDuration,
+		m.agentRuns, m.agentDuration, m.agentRetries, m.activeAgents, m.resumeSkips,
+		m.toolCalls, m.toolDuration,
+	)
+	return m
+}
+
+// Handle updates collectors from a lifecycle event. It is an events.Handler.
+func (m *Metrics) Handle(e events.Event) {
+	switch e.Type {
+	case events.RunCompleted:
+		outcome := OutcomeSuccess
+		if e.HasError() {
+			outcome = OutcomeFailed
+		}
+		m.runs.WithLabelValues(outcome).Inc()
+		m.runDuration.WithLabelValues(outcome).Observe(seconds(e.DurationMS))
+
+	case events.AgentStarted:
+		m.setActive(e, true)
+
+	case events.AgentCompleted, events.AgentFailed:
+		m.setActive(e, false)
+		outcome := OutcomeSuccess
+		if e.Type == events.AgentFailed {
+			outcome = OutcomeFailed
+		}
+		m.agentRuns.WithLabelValues(e.Agent, outcome).Inc()
+		m.agentDuration.WithLabelValues(e.Agent, outcome).Observe(seconds(e.DurationMS))
+
+	case events.AgentSkipped:
+		m.agentRuns.WithLabelValues(e.Agent, OutcomeSkipped).Inc()
+		m.resumeSkips.WithLabelValues(e.Agent).Inc()
+
+	case events.AgentRetry:
+		m.agentRetries.WithLabelValues(e.Agent).Inc()
+	}
+}
+
+// ObserveToolCall records an MCP tool call
+func (m *Metrics) ObserveToolCall(tool string, failed bool, d time.Duration) {
+	status := "ok"
+	if failed {
+		status = "error"
+	}
+	m.toolCalls.WithLabelValues(tool, status).Inc()
+	m.toolDuration.WithLabelValues(tool).Observe(d.Seconds())
+}
+
+// Handler returns the HTTP handler serving the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Serve starts an HTTP server exposing Handler on addr at Path and returns
+// a function that shuts it down. Listen errors are returned immediately.
+func (m *Metrics) Serve(addr string) (shutdown func(context.Context) error, err error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, m.Handler())
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() { _ = srv.Serve(ln) }()
+
+	return srv.Shutdown, nil
+}
+
+// setActive tracks a running agent so the gauge only moves on matched
+// start/finish pairs (an agent can fail before it was ever started)
+func (m *Metrics) setActive(e events.Event, running bool) {
+	key := e.RunID + "/" + e.Agent
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case running && !m.active[key]:
+		m.active[key] = true
+		m.activeAgents.Inc()
+	case !running && m.active[key]:
+		delete(m.active, key)
+		m.activeAgents.Dec()
+	}
+}
+
+// seconds converts milliseconds to seconds
+func seconds(ms int64) float64 {
+	return float64(ms) / 1000
+}
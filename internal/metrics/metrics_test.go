@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tuannvm/pagent/internal/events"
+)
+
+func event(t events.Type, agent string) events.Event {
+	e := events.New(t)
+	e.RunID = "run-1"
+	e.Agent = agent
+	return e
+}
+
+func TestHandleAgentEvents(t *testing.T) {
+	m := New()
+
+	m.Handle(event(events.AgentStarted, "architect"))
+	m.Handle(event(events.AgentStarted, "qa"))
+	if got := testutil.ToFloat64(m.activeAgents); got != 2 {
+		t.Errorf("active agents = %v, want 2", got)
+	}
+
+	completed := event(events.AgentCompleted, "architect")
+	completed.DurationMS = 1500
+	m.Handle(completed)
+	retry := event(events.AgentRetry, "qa")
+	retry.Attempt = 2
+	m.Handle(retry)
+	m.Handle(event(events.AgentFailed, "qa"))
+	// A failure without a matching start must not drive the gauge negative
+	m.Handle(event(events.AgentFailed, "security"))
+	m.Handle(event(events.AgentSkipped, "implementer"))
+
+	if got := testutil.ToFloat64(m.activeAgents); got != 0 {
+		t.Errorf("active agents = %v, want 0", got)
+	}
+	checks := []struct {
+		agent, outcome string
+		want           float64
+	}{
+		{"architect", OutcomeSuccess, 1},
+		{"qa", OutcomeFailed, 1},
+		{"security", OutcomeFailed, 1},
+		{"implementer", OutcomeSkipped, 1},
+	}
+	for _, c := range checks {
+		if got := testutil.ToFloat64(m.agentRuns.WithLabelValues(c.agent, c.outcome)); got != c.want {
+			t.Errorf("agent runs{%s,%s} = %v, want %v", c.agent, c.outcome, got, c.want)
+		}
+	}
+	if got := testutil.ToFloat64(m.resumeSkips.WithLabelValues("implementer")); got != 1 {
+		t.Errorf("resume skips = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.agentRetries.WithLabelValues("qa")); got != 1 {
+		t.Errorf("retries = %v, want 1", got)
+	}
+}
+
+func TestHandleRunCompleted(t *testing.T) {
+	m := New()
+
+	m.Handle(event(events.RunCompleted, ""))
+	failed := event(events.RunCompleted, "")
+	failed.Error = "some agents failed"
+	m.Handle(failed)
+
+	if got := testutil.ToFloat64(m.runs.WithLabelValues(OutcomeSuccess)); got != 1 {
+		t.Errorf("successful runs = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.runs.WithLabelValues(OutcomeFailed)); got != 1 {
+		t.Errorf("failed runs = %v, want 1", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.ObserveToolCall("run_agent", false, 10*time.Millisecond)
+	m.ObserveToolCall("run_agent", true, 10*time.Millisecond)
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, want := range []string{
+		`pagent_mcp_tool_calls_total{status="ok",tool="run_agent"} 1`,
+		`pagent_mcp_tool_calls_total{status="error",tool="run_agent"} 1`,
+		"pagent_mcp_tool_duration_seconds_bucket",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Package notify delivers run lifecycle events to external systems.
+// Webhook targets are configured under `notifications:` in the config file
+// and subscribe to the run's event bus.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/events"
+)
+
+// Request headers set on every webhook delivery
+const (
+	HeaderEvent     = "X-Pagent-Event"
+	HeaderTimestamp = "X-Pagent-Timestamp"
+	HeaderSignature = "X-Pagent-Signature" // "sha256=<hex hmac of timestamp + "." + body>"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultTimeout    = 10 * time.Second
+	defaultBackoff    = 500 * time.Millisecond
+)
+
+// queueSize bounds the events waiting for a webhook; further events are
+// dropped (and reported to onError) rather than blocking the run
+const queueSize = 256
+
+// Notifier sends matching events to configured webhooks.
+// Each webhook has one background sender delivering its events in the
+// order they were published, so a slow endpoint never blocks agents;
+// call Close to wait for queued deliveries before exiting.
+type Notifier struct {
+	targets []target
+	queues  []chan events.Event // Per target, drained by its sender
+	client  *http.Client
+	backoff time.Duration
+	onError func(error)
+
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// target is a webhook with its defaults applied
+type target struct {
+	name       string
+	url        string
+	format     string
+	secret     string
+	headers    map[string]string
+	events     map[events.Type]bool
+	maxRetries int
+	timeout    time.Duration
+}
+
+// New creates a notifier for the configured webhooks and starts their
+// senders. onError is called (from a background goroutine, or from Handle
+// when an event is dropped) when a delivery ultimately fails; it may be nil.
+func New(cfg config.NotificationsConfig, onError func(error)) *Notifier {
+	n := &Notifier{
+		client:  &http.Client{},
+		backoff: defaultBackoff,
+		onError: onError,
+	}
+
+	for i, w := range cfg.Webhooks {
+		t := target{
+			name:       w.Name,
+			url:        w.ExpandedURL(),
+			format:     w.Format,
+			secret:     w.ExpandedSecret(),
+			headers:    w.Headers,
+			events:     make(map[events.Type]bool),
+			maxRetries: defaultMaxRetries,
+			timeout:    time.Duration(w.Timeout) * time.Second,
+		}
+		if t.name == "" {
+			t.name = fmt.Sprintf("webhook #%d", i+1)
+		}
+		if t.format == "" {
+			t.format = config.WebhookFormatJSON
+		}
+		if w.MaxRetries != nil {
+			t.maxRetries = *w.MaxRetries
+		}
+		if t.timeout == 0 {
+			t.timeout = defaultTimeout
+		}
+		filter := w.Events
+		if len(filter) == 0 {
+			filter = config.NotifiableEvents
+		}
+		for _, e := range filter {
+			t.events[events.Type(e)] = true
+		}
+		n.targets = append(n.targets, t)
+	}
+
+	n.start()
+	return n
+}
+
+// Handle is an events.Handler that queues e for every matching webhook
+func (n *Notifier) Handle(e events.Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	for i, t := range n.targets {
+		if !t.events[e.Type] {
+			continue
+		}
+		select {
+		case n.queues[i] <- e:
+		default:
+			n.report(fmt.Errorf("%s: %s notification dropped: too many pending deliveries", t.name, e.Type))
+		}
+	}
+}
+
+// Close stops accepting events and waits for queued deliveries to finish
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		for _, q := range n.queues {
+			close(q)
+		}
+	}
+	n.mu.Unlock()
+	n.wg.Wait()
+}
+
+// start runs a sender per target that delivers its queued events in order
+func (n *Notifier) start() {
+	n.queues = make([]chan events.Event, len(n.targets))
+	for i, t := range n.targets {
+		q := make(chan events.Event, queueSize)
+		n.queues[i] = q
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			for e := range q {
+				if err := n.deliver(t, e); err != nil {
+					n.report(err)
+				}
+			}
+		}()
+	}
+}
+
+// report passes a delivery error to onError, if set
+func (n *Notifier) report(err error) {
+	if n.onError != nil {
+		n.onError(err)
+	}
+}
+
+// deliver sends e to t, retrying with exponential backoff on network
+// errors, 5xx and 429 responses
+func (n *Notifier) deliver(t target, e events.Event) error {
+	body, err := buildPayload(t.format, e)
+	if err != nil {
+		return fmt.Errorf("%s: %w", t.name, err)
+	}
+
+	backoff := n.backoff
+	var lastErr error
+	for attempt := 0; attempt <= t.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		retry, err := n.post(t, e, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+
+	return fmt.Errorf("%s: %s notification failed: %w", t.name, e.Type, lastErr)
+}
+
+// post performs a single delivery attempt and reports whether it may be retried
+func (n *Notifier) post(t target, e events.Event, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(e.Type))
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	if t.secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderTimestamp, ts)
+		req.Header.Set(HeaderSignature, "sha256="+Sign(t.secret, ts, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of timestamp + "." + body.
+// Receivers recompute it with the shared secret to authenticate deliveries.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// buildPayload encodes e in the target's payload format
+func buildPayload(format string, e events.Event) ([]byte, error) {
+	switch format {
+	case config.WebhookFormatSlack:
+		return json.Marshal(map[string]string{"text": slackText(e)})
+	default:
+		return json.Marshal(e)
+	}
+}
+
+// slackText renders a one-line summary for Slack-compatible webhooks
+func slackText(e events.Event) string {
+	switch e.Type {
+	case events.RunStarted:
+		return fmt.Sprintf(":rocket: pagent run `%s` started (agents: %s)", e.RunID, strings.Join(e.Agents, ", "))
+	case events.RunCompleted:
+		duration := (time.Duration(e.DurationMS) * time.Millisecond).Round(time.Second)
+		if e.HasError() {
+			return fmt.Sprintf(":x: pagent run `%s` failed after %s: %d succeeded, %d failed (%s)",
+				e.RunID, duration, e.Succeeded, e.Failed, e.Error)
+		}
+		return fmt.Sprintf(":white_check_mark: pagent run `%s` completed in %s: %d agents succeeded",
+			e.RunID, duration, e.Succeeded)
+	case events.AgentFailed:
+		return fmt.Sprintf(":warning: pagent agent *%s* failed in run `%s`: %s", e.Agent, e.RunID, e.Error)
+	case events.ApprovalNeeded:
+		return fmt.Sprintf(":raising_hand: pagent run `%s` is waiting for approval: %s", e.RunID, e.Message)
+	default:
+		return fmt.Sprintf("pagent %s (run `%s`)", e.Type, e.RunID)
+	}
+}
@@ -0,0 +1,274 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/events"
+)
+
+// receiver is a local HTTP endpoint that records webhook deliveries
+type receiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) handler(status func(attempt int) int) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		attempt := len(r.requests)
+		r.mu.Unlock()
+		w.WriteHeader(status(attempt))
+	}
+}
+
+func ok(int) int { return http.StatusOK }
+
+func newTestNotifier(cfg config.NotificationsConfig, onError func(error)) *Notifier {
+	n := New(cfg, onError)
+	n.backoff = 0
+	return n
+}
+
+func TestNotifierGenericJSON(t *testing.T) {
+	rec := &receiver{}
+	srv := httptest.NewServer(rec.handler(ok))
+	defer srv.Close()
+
+	n := newTestNotifier(config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{URL: srv.URL}},
+	}, nil)
+
+	e := events.New(events.AgentFailed)
+	e.RunID = "run-1"
+	e.Agent = "qa"
+	e.Error = "timeout"
+	n.Handle(e)
+	n.Close()
+
+	if len(rec.bodies) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(rec.bodies))
+	}
+	var got events.Event
+	if err := json.Unmarshal(rec.bodies[0], &got); err != nil {
+		t.Fatalf("payload is not an event: %v", err)
+	}
+	if got.Type != events.AgentFailed || got.Agent != "qa" || got.Error != "timeout" {
+		t.Errorf("unexpected payload: %+v", got)
+	}
+	if h := rec.requests[0].Header.Get(HeaderEvent); h != string(events.AgentFailed) {
+		t.Errorf("%s header = %q", HeaderEvent, h)
+	}
+	if h := rec.requests[0].Header.Get(HeaderSignature); h != "" {
+		t.Errorf("unsigned webhook should not send %s, got %q", HeaderSignature, h)
+	}
+}
+
+func TestNotifierEventFilter(t *testing.T) {
+	rec := &receiver{}
+	srv := httptest.NewServer(rec.handler(ok))
+	defer srv.Close()
+
+	n := newTestNotifier(config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{URL: srv.URL, Events: []string{"run_completed"}}},
+	}, nil)
+
+	n.Handle(events.New(events.RunStarted))
+	n.Handle(events.New(events.AgentFailed))
+	n.Handle(events.New(events.RunCompleted))
+	n.Close()
+
+	if len(rec.requests) != 1 {
+		t.Fatalf("expected only run_completed to be delivered, got %d deliveries", len(rec.requests))
+	}
+	if h := rec.requests[0].Header.Get(HeaderEvent); h != string(events.RunCompleted) {
+		t.Errorf("delivered %q, want run_completed", h)
+	}
+}
+
+func TestNotifierDefaultFilterSkipsNoise(t *testing.T) {
+	rec := &receiver{}
+	srv := httptest.NewServer(rec.handler(ok))
+	defer srv.Close()
+
+	n := newTestNotifier(config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{URL: srv.URL}},
+	}, nil)
+
+	n.Handle(events.New(events.AgentStarted))
+	n.Handle(events.New(events.AgentStatusChanged))
+	n.Close()
+
+	if len(rec.requests) != 0 {
+		t.Errorf("expected no deliveries for non-notifiable events, got %d", len(rec.requests))
+	}
+}
+
+func TestNotifierSlackPayload(t *testing.T) {
+	rec := &receiver{}
+	srv := httptest.NewServer(rec.handler(ok))
+	defer srv.Close()
+
+	n := newTestNotifier(config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{URL: srv.URL, Format: config.WebhookFormatSlack}},
+	}, nil)
+
+	e := events.New(events.RunCompleted)
+	e.RunID = "run-1"
+	e.Succeeded = 5
+	n.Handle(e)
+	n.Close()
+
+	var payload map[string]string
+	if err := json.Unmarshal(rec.bodies[0], &payload); err != nil {
+		t.Fatalf("invalid slack payload: %v", err)
+	}
+	if !strings.Contains(payload["text"], "run-1") || !strings.Contains(payload["text"], "5 agents succeeded") {
+		t.Errorf("unexpected slack text: %q", payload["text"])
+	}
+}
+
+func TestNotifierHMACSignature(t *testing.T) {
+	rec := &receiver{}
+	srv := httptest.NewServer(rec.handler(ok))
+	defer srv.Close()
+
+	t.Setenv("TEST_WEBHOOK_SECRET", "s3cret")
+	n := newTestNotifier(config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{URL: srv.URL, Secret: "${TEST_WEBHOOK_SECRET}"}},
+	}, nil)
+
+	n.Handle(events.New(events.RunStarted))
+	n.Close()
+
+	req := rec.requests[0]
+	ts := req.Header.Get(HeaderTimestamp)
+	if ts == "" {
+		t.Fatal("expected timestamp header on signed delivery")
+	}
+	want := "sha256=" + Sign("s3cret", ts, rec.bodies[0])
+	if got := req.Header.Get(HeaderSignature); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+}
+
+func TestNotifierRetries(t *testing.T) {
+	rec := &receiver{}
+	srv := httptest.NewServer(rec.handler(func(attempt int) int {
+		if attempt < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}))
+	defer srv.Close()
+
+	var errCount int32
+	n := newTestNotifier(config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{URL: srv.URL, MaxRetries: intPtr(3)}},
+	}, func(error) { atomic.AddInt32(&errCount, 1) })
+
+	n.Handle(events.New(events.RunStarted))
+	n.Close()
+
+	if len(rec.requests) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(rec.requests))
+	}
+	if errCount != 0 {
+		t.Errorf("expected eventual success, got %d errors", errCount)
+	}
+}
+
+func TestNotifierZeroRetries(t *testing.T) {
+	rec := &receiver{}
+	srv := httptest.NewServer(rec.handler(func(int) int { return http.StatusServiceUnavailable }))
+	defer srv.Close()
+
+	n := newTestNotifier(config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{URL: srv.URL, MaxRetries: intPtr(0)}},
+	}, nil)
+
+	n.Handle(events.New(events.RunStarted))
+	n.Close()
+
+	if len(rec.requests) != 1 {
+		t.Errorf("max_retries: 0 should make a single attempt, got %d", len(rec.requests))
+	}
+}
+
+func TestNotifierGivesUpOnClientError(t *testing.T) {
+	rec := &receiver{}
+	srv := httptest.NewServer(rec.handler(func(int) int { return http.StatusBadRequest }))
+	defer srv.Close()
+
+	var gotErr error
+	var mu sync.Mutex
+	n := newTestNotifier(config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{Name: "ci", URL: srv.URL, MaxRetries: intPtr(3)}},
+	}, func(err error) {
+		mu.Lock()
+		gotErr = errors.Join(gotErr, err)
+		mu.Unlock()
+	})
+
+	n.Handle(events.New(events.RunStarted))
+	n.Close()
+
+	if len(rec.requests) != 1 {
+		t.Errorf("4xx responses should not be retried, got %d attempts", len(rec.requests))
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), "ci") {
+		t.Errorf("expected error mentioning webhook name, got %v", gotErr)
+	}
+}
+
+func TestNotifierDeliversInOrder(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		arrived []events.Type
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		first := len(arrived) == 0
+		mu.Unlock()
+		// A slow first delivery must not let later events overtake it
+		if first {
+			time.Sleep(200 * time.Millisecond)
+		}
+		mu.Lock()
+		arrived = append(arrived, events.Type(req.Header.Get(HeaderEvent)))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	n := newTestNotifier(config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{URL: srv.URL, Events: []string{
+			string(events.RunStarted), string(events.AgentCompleted), string(events.RunCompleted),
+		}}},
+	}, nil)
+
+	want := []events.Type{events.RunStarted, events.AgentCompleted, events.RunCompleted}
+	for _, typ := range want {
+		n.Handle(events.New(typ))
+	}
+	n.Close()
+
+	if !slices.Equal(arrived, want) {
+		t.Errorf("deliveries arrived as %v, want %v", arrived, want)
+	}
+}
+
+func intPtr(v int) *int { return &v }
@@ -0,0 +1,208 @@
+// Package policy authorizes MCP callers by the claims of their OAuth token.
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Wildcard allows every tool, agent or persona
+const Wildcard = "*"
+
+// ErrDenied is returned for calls the caller's grant does not cover
+var ErrDenied = errors.New("access denied")
+
+// Policy maps OAuth claims to the MCP tools, agents, personas and workspace
+// roots a caller may use. A caller gets the union of every matching rule;
+// callers matching no rule may do nothing.
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule grants access to callers whose claims match
+type Rule struct {
+	Name string `yaml:"name"`
+
+	// Match maps claim names to accepted values; every listed claim must have
+	// one of them. Array claims such as groups match if any element does.
+	// An empty Match applies to every authenticated caller.
+	Match map[string][]string `yaml:"match"`
+
+	Tools    []string `yaml:"tools"`    // MCP tool names, or "*"
+	Agents   []string `yaml:"agents"`   // Agents that may be run or prompted, or "*"
+	Personas []string `yaml:"personas"` // Personas that may be requested, or "*"
+
+	// Roots narrow the caller's workspace; empty keeps it unchanged
+	Roots []string `yaml:"roots"`
+}
+
+// Load reads a policy file, rejecting unknown fields
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Validate checks that rules are named and their roots are absolute
+func (p *Policy) Validate() error {
+	seen := map[string]bool{}
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("rule %d: name is required", i+1)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("rule %s: duplicate name", r.Name)
+		}
+		seen[r.Name] = true
+		for _, root := range r.Roots {
+			if !filepath.IsAbs(root) {
+				return fmt.Errorf("rule %s: root %s must be absolute", r.Name, root)
+			}
+		}
+	}
+	return nil
+}
+
+// Grant returns what a caller with claims may do. A nil policy returns a
+// nil grant, which allows everything.
+func (p *Policy) Grant(claims map[string]any) *Grant {
+	if p == nil {
+		return nil
+	}
+	g := &Grant{subject: claimString(claims, "sub")}
+	for _, r := range p.Rules {
+		if !r.matches(claims) {
+			continue
+		}
+		g.rules = append(g.rules, r.Name)
+		g.tools = append(g.tools, r.Tools...)
+		g.agents = append(g.agents, r.Agents...)
+		g.personas = append(g.personas, r.Personas...)
+		g.roots = append(g.roots, r.Roots...)
+	}
+	return g
+}
+
+// matches reports whether claims satisfy every condition of the rule
+func (r Rule) matches(claims map[string]any) bool {
+	for name, accepted := range r.Match {
+		if !slices.ContainsFunc(claimValues(claims[name]), func(v string) bool {
+			return slices.Contains(accepted, v)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Grant is the access of one caller. A nil Grant allows everything.
+type Grant struct {
+	subject  string
+	rules    []string
+	tools    []string
+	agents   []string
+	personas []string
+	roots    []string
+}
+
+// Roots returns the roots the caller's workspace is narrowed to, or nil
+func (g *Grant) Roots() []string {
+	if g == nil {
+		return nil
+	}
+	return g.roots
+}
+
+// AllowsTool reports whether the caller may call an MCP tool
+func (g *Grant) AllowsTool(name string) bool {
+	return g == nil || allows(g.tools, name)
+}
+
+// CheckTool returns ErrDenied unless the caller may call an MCP tool
+func (g *Grant) CheckTool(name string) error {
+	if g.AllowsTool(name) {
+		return nil
+	}
+	return g.denied("tool", name)
+}
+
+// AllowsAgent reports whether the caller may run or prompt an agent
+func (g *Grant) AllowsAgent(name string) bool {
+	return g == nil || allows(g.agents, name)
+}
+
+// CheckAgent returns ErrDenied unless the caller may run or prompt an agent
+func (g *Grant) CheckAgent(name string) error {
+	if g.AllowsAgent(name) {
+		return nil
+	}
+	return g.denied("agent", name)
+}
+
+// CheckPersona returns ErrDenied unless the caller may request a persona
+func (g *Grant) CheckPersona(name string) error {
+	if g == nil || allows(g.personas, name) {
+		return nil
+	}
+	return g.denied("persona", name)
+}
+
+// denied explains which caller was refused what, and which rules applied
+func (g *Grant) denied(kind, name string) error {
+	who := g.subject
+	if who == "" {
+		who = "caller"
+	}
+	if len(g.rules) == 0 {
+		return fmt.Errorf("%w: %s %s is not allowed for %s (no policy rule matches)", ErrDenied, kind, name, who)
+	}
+	return fmt.Errorf("%w: %s %s is not allowed for %s (rules: %s)", ErrDenied, kind, name, who, strings.Join(g.rules, ", "))
+}
+
+func allows(allowed []string, name string) bool {
+	return slices.Contains(allowed, Wildcard) || slices.Contains(allowed, name)
+}
+
+// claimValues returns a claim as strings: a string claim as itself, an
+// array claim as its string elements
+func claimValues(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		var values []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func claimString(claims map[string]any, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
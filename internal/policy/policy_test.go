@@ -0,0 +1,129 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPolicy = `
+rules:
+  - name: viewers
+    tools: [list_agents, get_status]
+  - name: developers
+    match:
+      groups: [dev, platform]
+    tools: [run_agent, run_pipeline, get_run, wait_run]
+    agents: [architect, qa, security]
+    personas: [minimal, balanced]
+    roots: [/srv/specs]
+  - name: platform
+    match:
+      groups: [platform]
+      email_verified: ["true"]
+    tools: ["*"]
+    agents: ["*"]
+    personas: ["*"]
+`
+
+func loadTestPolicy(t *testing.T, content string) (*Policy, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestGrant(t *testing.T) {
+	p, err := loadTestPolicy(t, testPolicy)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	viewer := p.Grant(map[string]any{"sub": "viewer"})
+	if !viewer.AllowsTool("list_agents") || viewer.AllowsTool("run_agent") {
+		t.Error("viewer should only get the viewers tools")
+	}
+	if err := viewer.CheckTool("stop_agents"); !errors.Is(err, ErrDenied) || !strings.Contains(err.Error(), "rules: viewers") {
+		t.Errorf("CheckTool(stop_agents) = %v, want ErrDenied naming the viewers rule", err)
+	}
+
+	dev := p.Grant(map[string]any{"sub": "dev", "groups": []any{"dev"}})
+	if !dev.AllowsTool("run_pipeline") || !dev.AllowsTool("get_status") {
+		t.Error("developer should get the union of viewers and developers tools")
+	}
+	if err := dev.CheckAgent("implementer"); !errors.Is(err, ErrDenied) {
+		t.Errorf("CheckAgent(implementer) = %v, want ErrDenied", err)
+	}
+	if err := dev.CheckPersona("production"); !errors.Is(err, ErrDenied) {
+		t.Errorf("CheckPersona(production) = %v, want ErrDenied", err)
+	}
+	if got := dev.Roots(); len(got) != 1 || got[0] != "/srv/specs" {
+		t.Errorf("Roots() = %v, want [/srv/specs]", got)
+	}
+
+	// Every listed claim must match
+	unverified := p.Grant(map[string]any{"sub": "ops", "groups": []any{"platform"}})
+	if unverified.AllowsAgent("implementer") {
+		t.Error("platform rule should require email_verified")
+	}
+	platform := p.Grant(map[string]any{"sub": "ops", "groups": []any{"platform"}, "email_verified": "true"})
+	if !platform.AllowsTool("stop_agents") || !platform.AllowsAgent("implementer") || platform.CheckPersona("production") != nil {
+		t.Error("platform should be allowed everything")
+	}
+}
+
+func TestGrantNoMatch(t *testing.T) {
+	p, err := loadTestPolicy(t, `
+rules:
+  - name: platform
+    match:
+      groups: [platform]
+    tools: ["*"]
+`)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	g := p.Grant(map[string]any{"sub": "alice", "groups": "dev"})
+	err = g.CheckTool("list_agents")
+	if !errors.Is(err, ErrDenied) || !strings.Contains(err.Error(), "no policy rule matches") {
+		t.Errorf("CheckTool() = %v, want ErrDenied without matching rules", err)
+	}
+}
+
+func TestNilPolicyAllowsEverything(t *testing.T) {
+	var p *Policy
+	g := p.Grant(map[string]any{"sub": "alice"})
+	if g != nil {
+		t.Fatalf("Grant() = %v, want nil", g)
+	}
+	if g.CheckTool("stop_agents") != nil || g.CheckAgent("implementer") != nil || g.CheckPersona("production") != nil {
+		t.Error("nil grant should allow everything")
+	}
+	if g.Roots() != nil {
+		t.Error("nil grant should not narrow roots")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown field", "rules:\n  - name: a\n    tool: [list_agents]\n"},
+		{"missing name", "rules:\n  - tools: [list_agents]\n"},
+		{"duplicate name", "rules:\n  - name: a\n  - name: a\n"},
+		{"relative root", "rules:\n  - name: a\n    roots: [specs]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadTestPolicy(t, tt.content); err == nil {
+				t.Error("Load() error = nil, want error")
+			}
+		})
+	}
+}
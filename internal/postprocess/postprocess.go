@@ -2,6 +2,7 @@ package postprocess
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,12 +10,15 @@ import (
 	"strings"
 
 	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Runner handles post-processing tasks after agents complete
 type Runner struct {
 	config  *config.Config
 	verbose bool
+	logf    func(format string, args ...interface{}) // Verbose progress (default: stderr)
 }
 
 // NewRunner creates a new post-processing runner
@@ -25,6 +29,26 @@ func NewRunner(cfg *config.Config, verbose bool) *Runner {
 	}
 }
 
+// WithLogger sends verbose progress messages to logf instead of stderr.
+// Long-lived hosts pass their run logger so nothing reaches stdout, which
+// may carry the --events stream or an MCP transport.
+func (r *Runner) WithLogger(logf func(format string, args ...interface{})) *Runner {
+	r.logf = logf
+	return r
+}
+
+// logVerbose reports progress when verbose
+func (r *Runner) logVerbose(format string, args ...interface{}) {
+	if !r.verbose {
+		return
+	}
+	if r.logf != nil {
+		r.logf(format, args...)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[POST] "+format+"\n", args...)
+}
+
 // Result holds the result of a post-processing step
 type Result struct {
 	Step    string
@@ -35,6 +59,12 @@ type Result struct {
 
 // Run executes all configured post-processing steps
 func (r *Runner) Run() []Result {
+	return r.RunContext(context.Background())
+}
+
+// RunContext executes all configured post-processing steps, tracing each
+// step as a child span of the span in ctx
+func (r *Runner) RunContext(ctx context.Context) []Result {
 	var results []Result
 
 	// Only run post-processing in modify mode
@@ -47,7 +77,7 @@ func (r *Runner) Run() []Result {
 	// Run validation commands first
 	if len(pp.ValidationCommands) > 0 {
 		for _, cmd := range pp.ValidationCommands {
-			result := r.runValidationCommand(cmd)
+			result := traceStep(ctx, func() Result { return r.runValidationCommand(cmd) })
 			results = append(results, result)
 			// Stop on first validation failure
 			if !result.Success {
@@ -58,19 +88,32 @@ func (r *Runner) Run() []Result {
 
 	// Generate diff summary
 	if pp.GenerateDiffSummary {
-		result := r.generateDiffSummary()
+		result := traceStep(ctx, r.generateDiffSummary)
 		results = append(results, result)
 	}
 
 	// Generate PR description
 	if pp.GeneratePRDescription {
-		result := r.generatePRDescription()
+		result := traceStep(ctx, r.generatePRDescription)
 		results = append(results, result)
 	}
 
 	return results
 }
 
+// traceStep runs step inside a "postprocess.step" span
+func traceStep(ctx context.Context, step func() Result) Result {
+	_, span := telemetry.StartSpan(ctx, "postprocess.step")
+	result := step()
+	span.SetAttributes(attribute.String("pagent.step", result.Step))
+	err := result.Error
+	if err == nil && !result.Success {
+		err = fmt.Errorf("%s failed", result.Step)
+	}
+	telemetry.End(span, err)
+	return result
+}
+
 // runValidationCommand executes a validation command in the target codebase
 func (r *Runner) runValidationCommand(cmdStr string) Result {
 	result := Result{
@@ -97,9 +140,7 @@ func (r *Runner) runValidationCommand(cmdStr string) Result {
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if r.verbose {
-		fmt.Printf("[POST] Running: %s (in %s)\n", cmdStr, workDir)
-	}
+	r.logVerbose("Running: %s (in %s)", cmdStr, workDir)
 
 	err := cmd.Run()
 	result.Output = stdout.String()
@@ -1,6 +1,7 @@
 package postprocess
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -136,6 +137,29 @@ func TestRunSkipsInCreateMode(t *testing.T) {
 	}
 }
 
+func TestRunnerWithLogger(t *testing.T) {
+	cfg := &config.Config{
+		Mode:           config.ModeModify,
+		TargetCodebase: t.TempDir(),
+		PostProcessing: config.PostProcessingConfig{
+			ValidationCommands: []string{"echo hi"},
+		},
+	}
+
+	var messages []string
+	runner := NewRunner(cfg, true).WithLogger(func(format string, args ...interface{}) {
+		messages = append(messages, fmt.Sprintf(format, args...))
+	})
+	results := runner.Run()
+
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("Expected one successful step, got %+v", results)
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "echo hi") {
+		t.Errorf("Expected the logger to report the command, got %q", messages)
+	}
+}
+
 func TestResultFields(t *testing.T) {
 	result := Result{
 		Step:    "test step",
@@ -21,6 +21,7 @@ type (
 	ArchitecturePreferences = types.ArchitecturePreferences
 	StackResolution         = types.StackResolution
 	StackConflict           = types.StackConflict
+	PersonaTraits           = types.PersonaTraits
 )
 
 // Variables holds the template variables for prompt rendering
@@ -37,6 +38,7 @@ type Variables struct {
 	Persona       string                  // Implementation style: minimal, balanced, production
 	Stack         TechStack               // Technology stack preferences
 	Preferences   ArchitecturePreferences // Architectural style preferences
+	PersonaTraits PersonaTraits           // Label, description and base of Persona
 
 	// Mode-specific variables for existing codebase modifications
 	Mode           string // "create" or "modify"
@@ -51,19 +53,27 @@ type Variables struct {
 	Custom map[string]string
 }
 
-// IsMinimal returns true if persona is "minimal"
+// IsMinimal returns true if persona is "minimal" or builds on it
 func (v Variables) IsMinimal() bool {
-	return v.Persona == "minimal"
+	return v.basePersona() == "minimal"
 }
 
-// IsBalanced returns true if persona is "balanced"
+// IsBalanced returns true if persona is "balanced" or builds on it
 func (v Variables) IsBalanced() bool {
-	return v.Persona == "balanced"
+	return v.basePersona() == "balanced"
 }
 
-// IsProduction returns true if persona is "production"
+// IsProduction returns true if persona is "production" or builds on it
 func (v Variables) IsProduction() bool {
-	return v.Persona == "production"
+	return v.basePersona() == "production"
+}
+
+// basePersona returns the built-in persona whose guidelines apply
+func (v Variables) basePersona() string {
+	if v.PersonaTraits.Base != "" {
+		return v.PersonaTraits.Base
+	}
+	return v.Persona
 }
 
 // IsModifyMode returns true if mode is "modify"
@@ -168,3 +168,81 @@ func TestLoadPriorityOrder(t *testing.T) {
 		t.Errorf("Test 3 failed: promptsDir should take precedence over embedded, got %q", result)
 	}
 }
+
+func TestRenderStackResolution(t *testing.T) {
+	loader := NewLoader("")
+	conflicts := []StackConflict{
+		{Category: "database", ConfigValue: "postgres", PRDHint: "mongodb", Evidence: "prd.md:4", Resolved: true, Resolution: "mongodb"},
+	}
+
+	wants := map[string]string{
+		"architect":   "| database | postgres | mongodb (prd.md:4) | **mongodb** |",
+		"implementer": "- **database**: mongodb (PRD: mongodb, config: postgres)",
+	}
+	for agent, want := range wants {
+		vars := Variables{
+			PRDPath:    "/path/to/prd.md",
+			OutputDir:  "/path/to/outputs",
+			OutputPath: "/path/to/outputs/out.md",
+			AgentName:  agent,
+			Persona:    "balanced",
+			Resolution: &StackResolution{Resolved: true, Conflicts: conflicts},
+		}
+		result, err := loader.LoadAndRender(agent, "", "", vars)
+		if err != nil {
+			t.Fatalf("%s: %v", agent, err)
+		}
+		if !strings.Contains(result, want) {
+			t.Errorf("%s prompt does not contain %q", agent, want)
+		}
+	}
+
+	// Unresolved conflicts are listed without a choice
+	vars := Variables{
+		PRDPath:    "/path/to/prd.md",
+		OutputDir:  "/path/to/outputs",
+		OutputPath: "/path/to/outputs/architecture.md",
+		AgentName:  "architect",
+		Persona:    "balanced",
+		Resolution: &StackResolution{Conflicts: []StackConflict{{Category: "cache", ConfigValue: "redis", PRDHint: "none", Evidence: "prd.md:9"}}},
+	}
+	result, err := loader.LoadAndRender("architect", "", "", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "| cache | redis | none (prd.md:9) |") {
+		t.Error("architect prompt does not list the unresolved conflict")
+	}
+}
+
+func TestRenderCustomPersona(t *testing.T) {
+	loader := NewLoader("")
+	vars := Variables{
+		PRDPath:    "/path/to/prd.md",
+		OutputDir:  "/path/to/outputs",
+		OutputPath: "/path/to/outputs/architecture.md",
+		AgentName:  "architect",
+		Persona:    "startup",
+		PersonaTraits: PersonaTraits{
+			Name: "startup", Label: "Startup", Description: "Ship in a week", Base: "minimal", Custom: true,
+		},
+	}
+	if !vars.IsMinimal() || vars.IsBalanced() {
+		t.Error("a persona built on minimal should follow the minimal guidelines")
+	}
+
+	result, err := loader.LoadAndRender("architect", "", "", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"## PERSONA: STARTUP",
+		"**Startup**: Ship in a week",
+		"builds on the minimal persona",
+		"### Minimal Implementation Philosophy",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("architect prompt does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Package queue implements a durable, file-backed queue of pipeline runs.
+//
+// Each job is stored as a JSON file under <dir>/jobs, so queued jobs survive
+// restarts of the process executing them. Any process may enqueue jobs or
+// request cancellation; a single worker per directory (see Serve) claims and
+// runs them, highest priority first, up to a global concurrency limit.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/state"
+	"github.com/tuannvm/pagent/internal/telemetry"
+)
+
+// Job states
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// Agent result statuses
+const (
+	AgentCompleted = "completed"
+	AgentFailed    = "failed"
+	AgentSkipped   = "skipped"
+)
+
+// pollInterval is how often the worker and waiters re-read the queue
+// directory to pick up changes made by other processes
+const pollInterval = time.Second
+
+// ErrNotFound is returned when a job ID does not exist in the queue
+var ErrNotFound = errors.New("job not found")
+
+// Job is a queued pipeline run
+type Job struct {
+	ID          string            `json:"id"`
+	Options     config.RunOptions `json:"options"`
+	Priority    int               `json:"priority"`               // Higher runs first
+	Source      string            `json:"source,omitempty"`       // Where the job was enqueued, e.g. "mcp" (see package audit)
+	Submitter   string            `json:"submitter,omitempty"`    // Who enqueued the job, as named in the audit log
+	TraceParent string            `json:"trace_parent,omitempty"` // W3C traceparent of the submitting span, parent of the run's spans
+	State       string            `json:"state"`
+	SubmittedAt time.Time         `json:"submitted_at"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+	Attempts    int               `json:"attempts"` // Times the job was started (>1 after a restart)
+	Error       string            `json:"error,omitempty"`
+	Agents      []AgentResult     `json:"agents,omitempty"`
+}
+
+// Done reports whether the job reached a final state
+func (j Job) Done() bool {
+	return j.State == StateSucceeded || j.State == StateFailed || j.State == StateCancelled
+}
+
+// AgentResult is the outcome of one agent in a finished job
+type AgentResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // AgentCompleted, AgentFailed or AgentSkipped
+	OutputPath string `json:"output_path,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Queue is a handle on a queue directory
+type Queue struct {
+	dir string
+
+	mu      sync.Mutex
+	changed chan struct{} // Closed and replaced whenever this process updates a job
+
+	// Set while Serve is running in this process
+	serving bool
+	running map[string]*activeJob
+	wake    chan struct{}
+}
+
+// Open opens (creating if needed) the queue stored in dir
+func Open(dir string) (*Queue, error) {
+	for _, sub := range []string{jobsDir, cancelDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create queue directory: %w", err)
+		}
+	}
+	return &Queue{
+		dir:     dir,
+		changed: make(chan struct{}),
+		running: make(map[string]*activeJob),
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// Dir returns the queue directory
+func (q *Queue) Dir() string {
+	return q.dir
+}
+
+// Enqueue adds a run to the queue and returns the stored job. The span in
+// ctx, if any, becomes the parent of the run's spans; source and submitter
+// are recorded in the run's audit record.
+func (q *Queue) Enqueue(ctx context.Context, opts config.RunOptions, priority int, source, submitter string) (Job, error) {
+	if opts.InputPath == "" {
+		return Job{}, fmt.Errorf("input_path is required")
+	}
+	// Jobs run in the background of another process, so per-run stdout
+	// streams and metrics listeners are not supported
+	opts.EventFormat = ""
+	opts.MetricsAddr = ""
+
+	job := Job{
+		ID:          state.NewRunID(),
+		Options:     opts,
+		Priority:    priority,
+		Source:      source,
+		Submitter:   submitter,
+		TraceParent: telemetry.TraceParent(ctx),
+		State:       StateQueued,
+		SubmittedAt: time.Now().UTC(),
+	}
+	if err := q.save(job); err != nil {
+		return Job{}, err
+	}
+	q.notify()
+	return job, nil
+}
+
+// Get loads a job by ID
+func (q *Queue) Get(id string) (Job, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return Job{}, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	data, err := os.ReadFile(q.jobPath(id))
+	if os.IsNotExist(err) {
+		return Job{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return Job{}, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return Job{}, fmt.Errorf("corrupt job file %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// List returns all jobs, most recently submitted first
+func (q *Queue) List() ([]Job, error) {
+	entries, err := os.ReadDir(filepath.Join(q.dir, jobsDir))
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]Job, 0, len(entries))
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		job, err := q.Get(id)
+		if err != nil {
+			// Skip files that vanished or are being replaced
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].SubmittedAt.After(jobs[j].SubmittedAt) })
+	return jobs, nil
+}
+
+// Cancel cancels a queued or running job. When the job is owned by a worker
+// in another process, a cancellation request is recorded for that worker
+// and the job's current state is returned.
+func (q *Queue) Cancel(id string) (Job, error) {
+	job, err := q.Get(id)
+	if err != nil {
+		return Job{}, err
+	}
+	if job.Done() {
+		return job, fmt.Errorf("job %s already %s", id, job.State)
+	}
+
+	q.mu.Lock()
+	serving := q.serving
+	active := q.running[id]
+	if active != nil {
+		active.cancelled = true
+		active.cancel()
+	}
+	q.mu.Unlock()
+
+	switch {
+	case active != nil:
+		return job, nil
+	case !serving && job.State == StateQueued && !q.WorkerActive():
+		// Nobody else can be touching the job: finish it directly
+		return q.finish(job, StateCancelled, "cancelled before start")
+	default:
+		// Leave it to the worker, which applies requests between dispatches
+		if err := os.WriteFile(q.cancelPath(id), nil, 0644); err != nil {
+			return job, fmt.Errorf("failed to request cancellation: %w", err)
+		}
+		q.notify()
+		return job, nil
+	}
+}
+
+// Wait blocks until the job finishes or ctx is cancelled
+func (q *Queue) Wait(ctx context.Context, id string) (Job, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		q.mu.Lock()
+		changed := q.changed
+		q.mu.Unlock()
+
+		job, err := q.Get(id)
+		if err != nil {
+			return Job{}, err
+		}
+		if job.Done() {
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// finish moves a job to a final state
+func (q *Queue) finish(job Job, jobState, errMsg string) (Job, error) {
+	now := time.Now().UTC()
+	job.State = jobState
+	job.FinishedAt = &now
+	job.Error = errMsg
+	if err := q.save(job); err != nil {
+		return job, err
+	}
+	_ = os.Remove(q.cancelPath(job.ID))
+	q.notify()
+	return job, nil
+}
+
+// notify wakes waiters and the local worker after a job changed
+func (q *Queue) notify() {
+	q.mu.Lock()
+	close(q.changed)
+	q.changed = make(chan struct{})
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,350 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/events"
+	"github.com/tuannvm/pagent/internal/runner"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func openQueue(t *testing.T) *Queue {
+	t.Helper()
+	q, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return q
+}
+
+// serve runs q.Serve in the background until the test ends
+func serve(t *testing.T, q *Queue, concurrency int, execute ExecuteFunc) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- q.Serve(ctx, concurrency, execute) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	})
+}
+
+func waitJob(t *testing.T, q *Queue, id string) Job {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	job, err := q.Wait(ctx, id)
+	if err != nil {
+		t.Fatalf("Wait(%s) error = %v", id, err)
+	}
+	return job
+}
+
+func TestEnqueueAndList(t *testing.T) {
+	q := openQueue(t)
+
+	if _, err := q.Enqueue(context.Background(), config.RunOptions{}, 0, "", ""); err == nil {
+		t.Error("Enqueue() without input should fail")
+	}
+
+	job, err := q.Enqueue(context.Background(), config.RunOptions{InputPath: "/tmp/prd.md", EventFormat: "ndjson"}, 5, "mcp", "alice")
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if job.State != StateQueued || job.Options.EventFormat != "" {
+		t.Errorf("Enqueue() = %+v, want queued job without event format", job)
+	}
+
+	// A second handle on the same directory sees the job
+	other, err := Open(q.Dir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	jobs, err := other.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != job.ID || jobs[0].Priority != 5 || jobs[0].Source != "mcp" || jobs[0].Submitter != "alice" {
+		t.Errorf("List() = %+v, want the enqueued job", jobs)
+	}
+
+	if _, err := q.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestServeRunsByPriority(t *testing.T) {
+	q := openQueue(t)
+
+	low, _ := q.Enqueue(context.Background(), config.RunOptions{InputPath: "low.md"}, 0, "", "")
+	high, _ := q.Enqueue(context.Background(), config.RunOptions{InputPath: "high.md"}, 10, "", "")
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	serve(t, q, 1, func(_ context.Context, job Job, s runner.Session) error {
+		mu.Lock()
+		order = append(order, job.Options.InputPath)
+		mu.Unlock()
+
+		e := events.New(events.AgentCompleted)
+		e.Agent = "architect"
+		e.OutputPath = "/out/architecture.md"
+		s.Bus.Publish(e)
+		if job.ID == low.ID {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	got := waitJob(t, q, high.ID)
+	if got.State != StateSucceeded || got.Attempts != 1 {
+		t.Errorf("high job = %+v, want succeeded", got)
+	}
+	if len(got.Agents) != 1 || got.Agents[0].Status != AgentCompleted || got.Agents[0].OutputPath != "/out/architecture.md" {
+		t.Errorf("high job agents = %+v", got.Agents)
+	}
+
+	got = waitJob(t, q, low.ID)
+	if got.State != StateFailed || got.Error != "boom" {
+		t.Errorf("low job = %+v, want failed with boom", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 2 || order[0] != "high.md" {
+		t.Errorf("execution order = %v, want high.md first", order)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	q := openQueue(t)
+
+	var (
+		mu      sync.Mutex
+		running int
+		peak    int
+	)
+	release := make(chan struct{})
+	serve(t, q, 2, func(ctx context.Context, _ Job, _ runner.Session) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		job, err := q.Enqueue(context.Background(), config.RunOptions{InputPath: "prd.md"}, 0, "", "")
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		ids = append(ids, job.ID)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	for _, id := range ids {
+		if job := waitJob(t, q, id); job.State != StateSucceeded {
+			t.Errorf("job %s = %s, want succeeded", id, job.State)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", peak)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	q := openQueue(t)
+
+	// Without a worker, a queued job is cancelled directly
+	queued, _ := q.Enqueue(context.Background(), config.RunOptions{InputPath: "prd.md"}, 0, "", "")
+	job, err := q.Cancel(queued.ID)
+	if err != nil || job.State != StateCancelled {
+		t.Fatalf("Cancel(queued) = %+v, %v; want cancelled", job, err)
+	}
+	if _, err := q.Cancel(queued.ID); err == nil {
+		t.Error("Cancel() of a finished job should fail")
+	}
+
+	started := make(chan struct{})
+	serve(t, q, 1, func(ctx context.Context, _ Job, _ runner.Session) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	running, _ := q.Enqueue(context.Background(), config.RunOptions{InputPath: "prd.md"}, 0, "", "")
+	<-started
+	if _, err := q.Cancel(running.ID); err != nil {
+		t.Fatalf("Cancel(running) error = %v", err)
+	}
+	if job := waitJob(t, q, running.ID); job.State != StateCancelled {
+		t.Errorf("running job = %s, want cancelled", job.State)
+	}
+}
+
+func TestRestartRequeuesInterruptedJobs(t *testing.T) {
+	q := openQueue(t)
+	job, _ := q.Enqueue(context.Background(), config.RunOptions{InputPath: "prd.md"}, 0, "", "")
+
+	// First worker is shut down while the job runs
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- q.Serve(ctx, 1, func(ctx context.Context, _ Job, _ runner.Session) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+	<-started
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	if got, _ := q.Get(job.ID); got.State != StateQueued {
+		t.Fatalf("after shutdown state = %s, want queued", got.State)
+	}
+
+	// A new worker (as after a process restart) runs it again
+	restarted, err := Open(q.Dir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	serve(t, restarted, 1, func(context.Context, Job, runner.Session) error { return nil })
+	got := waitJob(t, restarted, job.ID)
+	if got.State != StateSucceeded || got.Attempts != 2 {
+		t.Errorf("restarted job = %+v, want succeeded on attempt 2", got)
+	}
+}
+
+func TestServeRejectsSecondWorker(t *testing.T) {
+	q := openQueue(t)
+	serve(t, q, 1, func(context.Context, Job, runner.Session) error { return nil })
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !q.WorkerActive() {
+		if time.Now().After(deadline) {
+			t.Fatal("worker heartbeat not written")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := q.Serve(context.Background(), 1, nil); !errors.Is(err, ErrWorkerActive) {
+		t.Errorf("second Serve() error = %v, want ErrWorkerActive", err)
+	}
+}
+
+func TestConcurrentWorkersRunJobsOnce(t *testing.T) {
+	q := openQueue(t)
+	job, _ := q.Enqueue(context.Background(), config.RunOptions{InputPath: "prd.md"}, 0, "", "")
+
+	var (
+		mu   sync.Mutex
+		runs int
+	)
+	execute := func(context.Context, Job, runner.Session) error {
+		mu.Lock()
+		runs++
+		mu.Unlock()
+		return nil
+	}
+
+	// Workers of two processes (e.g. the daemon and an MCP server) starting
+	// together, before either wrote a heartbeat
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	for range 2 {
+		other, err := Open(q.Dir())
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		go func() { errs <- other.Serve(ctx, 1, execute) }()
+	}
+	if err := <-errs; !errors.Is(err, ErrWorkerActive) {
+		t.Errorf("first Serve() to return: error = %v, want ErrWorkerActive", err)
+	}
+	if got := waitJob(t, q, job.ID); got.State != StateSucceeded {
+		t.Errorf("job = %s, want succeeded", got.State)
+	}
+	cancel()
+	if err := <-errs; err != nil {
+		t.Errorf("serving Serve() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if runs != 1 {
+		t.Errorf("job ran %d times, want once", runs)
+	}
+
+	// The lock is released with the worker
+	serve(t, q, 1, execute)
+}
+
+func TestRunContinuesSubmitterTrace(t *testing.T) {
+	q := openQueue(t)
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	serve(t, q, 1, func(ctx context.Context, _ Job, _ runner.Session) error {
+		_, span := tracer.Start(ctx, "pagent.run")
+		span.End()
+		return nil
+	})
+
+	// The run is submitted from within a tool call's span
+	ctx, call := tracer.Start(context.Background(), "mcp.tools/call run_pipeline")
+	job, err := q.Enqueue(ctx, config.RunOptions{InputPath: "prd.md"}, 0, "", "")
+	call.End()
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if job.TraceParent == "" {
+		t.Fatal("Enqueue() did not record the submitting span")
+	}
+	waitJob(t, q, job.ID)
+
+	var run sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "pagent.run" {
+			run = s
+		}
+	}
+	if run == nil {
+		t.Fatal("no pagent.run span recorded")
+	}
+	if got, want := run.Parent().SpanID(), call.SpanContext().SpanID(); got != want {
+		t.Errorf("pagent.run parent span = %s, want the tool call's %s", got, want)
+	}
+	if got, want := run.SpanContext().TraceID(), call.SpanContext().TraceID(); got != want {
+		t.Errorf("pagent.run trace = %s, want %s", got, want)
+	}
+}
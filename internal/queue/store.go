@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Layout of a queue directory
+const (
+	jobsDir    = "jobs"        // <id>.json per job
+	cancelDir  = "cancel"      // <id> marker per pending cancellation request
+	workerFile = "worker.json" // Heartbeat of the worker serving the queue
+	workerLock = "worker.lock" // Locked by the worker serving the queue
+)
+
+// workerStaleAfter is how long a worker heartbeat stays valid
+const workerStaleAfter = 5 * pollInterval
+
+// heartbeat identifies the process serving a queue
+type heartbeat struct {
+	PID       int       `json:"pid"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queue) jobPath(id string) string {
+	return filepath.Join(q.dir, jobsDir, id+".json")
+}
+
+func (q *Queue) cancelPath(id string) string {
+	return filepath.Join(q.dir, cancelDir, id)
+}
+
+// save writes a job atomically so readers never see a partial file
+func (q *Queue) save(job Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(q.jobPath(job.ID), data)
+}
+
+// lockWorker claims the queue for this process's worker with an exclusive
+// lock, held until the returned file is closed. Locks are released when a
+// process dies, so a crashed worker never keeps the queue claimed.
+func (q *Queue) lockWorker() (*os.File, error) {
+	lock, err := os.OpenFile(filepath.Join(q.dir, workerLock), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock queue: %w", err)
+	}
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = lock.Close()
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("failed to lock queue: %w", err)
+		}
+		if hb, err := q.readHeartbeat(); err == nil {
+			return nil, fmt.Errorf("%w (pid %d)", ErrWorkerActive, hb.PID)
+		}
+		return nil, ErrWorkerActive
+	}
+	return lock, nil
+}
+
+// WorkerActive reports whether some process is currently serving the queue
+func (q *Queue) WorkerActive() bool {
+	hb, err := q.readHeartbeat()
+	return err == nil && time.Since(hb.UpdatedAt) < workerStaleAfter
+}
+
+func (q *Queue) readHeartbeat() (heartbeat, error) {
+	var hb heartbeat
+	data, err := os.ReadFile(filepath.Join(q.dir, workerFile))
+	if err != nil {
+		return hb, err
+	}
+	err = json.Unmarshal(data, &hb)
+	return hb, err
+}
+
+func (q *Queue) writeHeartbeat() error {
+	data, err := json.Marshal(heartbeat{PID: os.Getpid(), UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(q.dir, workerFile), data)
+}
+
+// pendingCancels returns job IDs with a cancellation request
+func (q *Queue) pendingCancels() []string {
+	entries, err := os.ReadDir(filepath.Join(q.dir, cancelDir))
+	if err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.Name())
+	}
+	return ids
+}
+
+// writeFileAtomic writes data to a temp file and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tuannvm/pagent/internal/events"
+	"github.com/tuannvm/pagent/internal/runner"
+	"github.com/tuannvm/pagent/internal/telemetry"
+)
+
+// ErrWorkerActive is returned by Serve when another process serves the queue
+var ErrWorkerActive = errors.New("another process is already serving the queue")
+
+// ExecuteFunc runs a job's pipeline, publishing its events on s.Bus.
+// It must return promptly once ctx is cancelled.
+type ExecuteFunc func(ctx context.Context, job Job, s runner.Session) error
+
+// activeJob tracks a job running in this process
+type activeJob struct {
+	cancel    context.CancelFunc
+	cancelled bool // Cancelled on request rather than by shutdown
+}
+
+// Serve runs queued jobs with execute, at most concurrency at a time, until
+// ctx is cancelled. Jobs interrupted by shutdown (or left running by a
+// crashed worker) are queued again and restarted by the next Serve.
+func (q *Queue) Serve(ctx context.Context, concurrency int, execute ExecuteFunc) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	q.mu.Lock()
+	if q.serving {
+		q.mu.Unlock()
+		return fmt.Errorf("%w (this process)", ErrWorkerActive)
+	}
+	q.serving = true
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		q.serving = false
+		q.mu.Unlock()
+	}()
+
+	lock, err := q.lockWorker()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Close() }()
+
+	if err := q.writeHeartbeat(); err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(filepath.Join(q.dir, workerFile)) }()
+
+	if err := q.requeueInterrupted(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		_ = q.writeHeartbeat()
+		q.applyCancelRequests()
+		q.dispatch(ctx, concurrency, execute, &wg)
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// requeueInterrupted puts jobs left running by a previous worker back in the queue
+func (q *Queue) requeueInterrupted() error {
+	jobs, err := q.List()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.State != StateRunning {
+			continue
+		}
+		job.State = StateQueued
+		job.StartedAt = nil
+		if err := q.save(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyCancelRequests handles cancellation requests from other processes
+func (q *Queue) applyCancelRequests() {
+	for _, id := range q.pendingCancels() {
+		q.mu.Lock()
+		active := q.running[id]
+		if active != nil {
+			active.cancelled = true
+			active.cancel()
+		}
+		q.mu.Unlock()
+		if active != nil {
+			continue
+		}
+
+		job, err := q.Get(id)
+		if err == nil && job.State == StateQueued {
+			_, _ = q.finish(job, StateCancelled, "cancelled before start")
+			continue
+		}
+		_ = os.Remove(q.cancelPath(id))
+	}
+}
+
+// dispatch starts the highest-priority queued jobs while slots are free
+func (q *Queue) dispatch(ctx context.Context, concurrency int, execute ExecuteFunc, wg *sync.WaitGroup) {
+	if ctx.Err() != nil {
+		return
+	}
+	q.mu.Lock()
+	free := concurrency - len(q.running)
+	q.mu.Unlock()
+	if free <= 0 {
+		return
+	}
+
+	jobs, err := q.List()
+	if err != nil {
+		return
+	}
+	queued := jobs[:0]
+	for _, job := range jobs {
+		if job.State == StateQueued {
+			queued = append(queued, job)
+		}
+	}
+	sort.SliceStable(queued, func(i, j int) bool {
+		if queued[i].Priority != queued[j].Priority {
+			return queued[i].Priority > queued[j].Priority
+		}
+		return queued[i].SubmittedAt.Before(queued[j].SubmittedAt)
+	})
+
+	for i := 0; i < len(queued) && i < free; i++ {
+		q.start(ctx, queued[i], execute, wg)
+	}
+}
+
+// start marks a job running and executes it in the background
+func (q *Queue) start(ctx context.Context, job Job, execute ExecuteFunc, wg *sync.WaitGroup) {
+	now := time.Now().UTC()
+	job.State = StateRunning
+	job.StartedAt = &now
+	job.Attempts++
+	job.Error = ""
+	job.Agents = nil
+	if err := q.save(job); err != nil {
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	active := &activeJob{cancel: cancel}
+	q.mu.Lock()
+	q.running[job.ID] = active
+	q.mu.Unlock()
+	q.notify()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		q.run(ctx, jobCtx, job, active, execute)
+	}()
+}
+
+// run executes a job and records its outcome
+func (q *Queue) run(ctx, jobCtx context.Context, job Job, active *activeJob, execute ExecuteFunc) {
+	var (
+		mu      sync.Mutex
+		results []AgentResult
+	)
+	bus := events.NewBus()
+	bus.Subscribe(func(e events.Event) {
+		res := AgentResult{Name: e.Agent, OutputPath: e.OutputPath, DurationMS: e.DurationMS, Error: e.Error}
+		switch e.Type {
+		case events.AgentCompleted:
+			res.Status = AgentCompleted
+		case events.AgentFailed:
+			res.Status = AgentFailed
+		case events.AgentSkipped:
+			res.Status = AgentSkipped
+		}
+		mu.Lock()
+		results = append(results, res)
+		mu.Unlock()
+	}, events.AgentCompleted, events.AgentFailed, events.AgentSkipped)
+
+	// Continue the submitter's trace, which may come from another process
+	jobCtx = telemetry.WithTraceParent(jobCtx, job.TraceParent)
+	err := execute(jobCtx, job, runner.Session{RunID: job.ID, Bus: bus, NoSignals: true, Source: job.Source, Actor: job.Submitter})
+
+	q.mu.Lock()
+	delete(q.running, job.ID)
+	cancelled := active.cancelled
+	q.mu.Unlock()
+
+	mu.Lock()
+	job.Agents = results
+	mu.Unlock()
+
+	switch {
+	case cancelled:
+		_, _ = q.finish(job, StateCancelled, "cancelled")
+	case err == nil:
+		_, _ = q.finish(job, StateSucceeded, "")
+	case ctx.Err() != nil:
+		// The worker is shutting down: run the job again on next start
+		job.State = StateQueued
+		job.StartedAt = nil
+		job.Agents = nil
+		_ = q.save(job)
+		q.notify()
+	case errors.Is(err, context.Canceled):
+		// Cancelled by the executor's owner (e.g. 'pagent runs cancel')
+		_, _ = q.finish(job, StateCancelled, "cancelled")
+	default:
+		_, _ = q.finish(job, StateFailed, err.Error())
+	}
+}
@@ -3,17 +3,30 @@ package runner
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/tuannvm/pagent/internal/agent"
+	"github.com/tuannvm/pagent/internal/audit"
 	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/conflict"
+	"github.com/tuannvm/pagent/internal/events"
 	"github.com/tuannvm/pagent/internal/input"
+	"github.com/tuannvm/pagent/internal/metrics"
+	"github.com/tuannvm/pagent/internal/notify"
 	"github.com/tuannvm/pagent/internal/postprocess"
+	"github.com/tuannvm/pagent/internal/state"
+	"github.com/tuannvm/pagent/internal/telemetry"
+	"github.com/tuannvm/pagent/internal/types"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Logger provides logging methods for the executor
@@ -23,36 +36,188 @@ type Logger interface {
 	Error(format string, args ...interface{})
 }
 
+// Session carries per-run wiring supplied by long-lived callers such as the
+// daemon. The zero value is what a one-shot CLI or TUI run uses.
+type Session struct {
+	RunID     string               // Run identifier (generated when empty)
+	Bus       *events.Bus          // Event bus (created when nil)
+	OnManager func(*agent.Manager) // Called once the agent manager exists
+	NoSignals bool                 // Don't cancel the run on SIGINT/SIGTERM
+	Actor     string               // Who requested the run, for the audit log (default: local user)
+	Source    string               // Where the run was requested, for the audit log (default: audit.SourceCLI)
+
+	// Audit is the audit log settings of the host running the run, such as
+	// the daemon's or MCP server's config. Nil uses the run's own config,
+	// which is only right when the caller owns that config, as on the CLI.
+	Audit *config.AuditConfig
+
+	// ResolveConflicts asks how to resolve PRD-vs-config stack conflicts
+	// when opts.Resolve is empty; it returns nil to cancel the run. Without
+	// it, conflicts stay unresolved and agents are told about them.
+	ResolveConflicts func([]types.StackConflict) ([]types.StackConflict, error)
+
+	// Resolve is the host's strategy for conflicts when opts.Resolve is
+	// empty and nothing else settles them; config.ResolveFail stops the run
+	Resolve string
+}
+
 // Execute runs agents with the given options.
 // This is the shared execution path for both CLI and TUI.
 func Execute(ctx context.Context, opts config.RunOptions, logger Logger) error {
+	return ExecuteSession(ctx, opts, logger, Session{})
+}
+
+// ExecuteSession runs agents like Execute and publishes lifecycle events to
+// s.Bus. Callers subscribe to the bus before calling to observe progress; the
+// logger and the --events stream are attached as subscribers for the run.
+func ExecuteSession(ctx context.Context, opts config.RunOptions, logger Logger, s Session) (err error) {
+	startedAt := time.Now()
+	if s.RunID == "" {
+		s.RunID = state.NewRunID()
+	}
+	auditCfg := s.Audit
+	defer func() { recordAudit(auditCfg, opts, s, startedAt, err, logger) }()
+
+	bus := s.Bus
+	if bus == nil {
+		bus = events.NewBus()
+	}
+	runID := s.RunID
+	publish := func(e events.Event) {
+		e.RunID = runID
+		bus.Publish(e)
+	}
+
+	// Resolve event sink before doing any work so bad formats fail fast
+	eventSink, err := newEventHandler(opts.EventFormat)
+	if err != nil {
+		return err
+	}
+	if eventSink != nil {
+		defer bus.Subscribe(eventSink)()
+	}
+	defer bus.Subscribe(LogSink(logger))()
+	defer bus.Subscribe(metrics.Default.Handle)()
+
+	// However the run ends from here on, tell subscribers with run_completed,
+	// record it in the history once its config is known and only then stop
+	// webhook notifications, so they deliver the end of the run too
+	var (
+		cfg          *config.Config
+		inp          *input.Input
+		results      []agent.Result
+		cancelled    bool
+		stopNotifier func()
+	)
+	defer func() {
+		runCompleted := events.New(events.RunCompleted)
+		runCompleted.DurationMS = time.Since(startedAt).Milliseconds()
+		runCompleted.Succeeded, runCompleted.Failed = countResults(results)
+		if err != nil {
+			runCompleted.Error = err.Error()
+		}
+		if cancelled {
+			runCompleted.Reason = "cancelled"
+		}
+		publish(runCompleted)
+
+		if cfg != nil && inp != nil {
+			recordHistory(cfg, runID, inp, startedAt, results, err, logger)
+		}
+		if stopNotifier != nil {
+			stopNotifier()
+		}
+	}()
+
+	// Expose metrics for the duration of the run
+	if opts.MetricsAddr != "" {
+		shutdownMetrics, err := metrics.Default.Serve(opts.MetricsAddr)
+		if err != nil {
+			return fmt.Errorf("failed to start metrics server: %w", err)
+		}
+		defer func() { _ = shutdownMetrics(context.Background()) }()
+		logger.Verbose("Serving metrics on %s%s", opts.MetricsAddr, metrics.Path)
+	}
+
 	// Discover input files
-	inp, err := input.Discover(opts.InputPath)
+	inp, err = input.Discover(opts.InputPath)
 	if err != nil {
 		return fmt.Errorf("input error: %w", err)
 	}
 
-	// Load config
-	cfg, err := config.Load(opts.ConfigPath)
+	// Load and validate config, layered on the preset if one was chosen
+	loaded, err := config.LoadWithPreset(opts.ConfigPath, opts.Preset)
 	if err != nil {
-		logger.Verbose("Using default config: %v", err)
-		cfg = config.Default()
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			for _, problem := range verr.Problems {
+				logger.Error("%s", problem)
+			}
+		}
+		return fmt.Errorf("config error: %w", err)
+	}
+	cfg = loaded
+	if auditCfg == nil {
+		auditCfg = &cfg.Audit
 	}
 
 	// Apply options to config
-	if err = applyOptions(cfg, opts); err != nil {
+	if err = cfg.ApplyRunOptions(opts); err != nil {
 		return err
 	}
 
+	// In modify mode, take unset stack settings from the target codebase and
+	// report explicit ones it contradicts before any agent starts
+	disagreements, err := cfg.ApplyTargetStack()
+	if err != nil {
+		return err
+	}
+	logDetectedStack(cfg, disagreements, logger)
+
+	// Install the tracer provider, or share the one other runs in this process
+	// use (no-op unless tracing is configured)
+	shutdownTracing, err := telemetry.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("tracing setup failed: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Verbose("Failed to flush traces: %v", err)
+		}
+	}()
+
+	// Attach webhook notifications for this run
+	if cfg.Notifications.HasWebhooks() {
+		notifier := notify.New(cfg.Notifications, func(err error) {
+			logger.Error("notification failed: %v", err)
+		})
+		unsubscribe := bus.Subscribe(notifier.Handle)
+		stopNotifier = func() {
+			unsubscribe()
+			notifier.Close()
+		}
+	}
+
 	// Ensure output directory exists
 	if err = os.MkdirAll(cfg.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	// Settle what the input documents ask for against the configured stack
+	resolution, err := resolveStack(cfg, inp, opts, s, publish, logger)
+	if errors.Is(err, errResolveCancelled) {
+		logger.Info("Cancelled")
+		cancelled = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
 	// Determine which agents to run
 	selectedAgents := opts.Agents
 	if len(selectedAgents) == 0 {
-		selectedAgents = cfg.GetAgentNames()
+		selectedAgents = cfg.PersonaAgents()
 	}
 
 	// Validate agent names
@@ -69,13 +234,19 @@ func Execute(ctx context.Context, opts config.RunOptions, logger Logger) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigCh
-		logger.Info("\nReceived interrupt, shutting down agents...")
-		cancel()
-	}()
+	if !s.NoSignals {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sigCh)
+		go func() {
+			select {
+			case <-sigCh:
+				logger.Info("\nReceived interrupt, shutting down agents...")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
 
 	// Create agent manager with input files
 	var manager *agent.Manager
@@ -85,8 +256,27 @@ func Execute(ctx context.Context, opts config.RunOptions, logger Logger) error {
 		manager = agent.NewManager(cfg, inp.PrimaryFile, opts.IsVerbose())
 	}
 
+	manager.SetStackResolution(resolution)
+	manager.SetLogger(logger.Verbose)
+
+	manager.SetEventBus(runID, bus)
+	if s.OnManager != nil {
+		s.OnManager(manager)
+	}
+
+	ctx, span := telemetry.StartSpan(ctx, "pagent.run",
+		attribute.String("pagent.run_id", runID),
+		attribute.StringSlice("pagent.agents", selectedAgents),
+		attribute.String("pagent.persona", cfg.Persona),
+		attribute.String("pagent.mode", cfg.Mode),
+		attribute.Bool("pagent.sequential", opts.Sequential),
+	)
+
+	runStarted := events.New(events.RunStarted)
+	runStarted.Agents = selectedAgents
+	publish(runStarted)
+
 	// Run agents
-	var results []agent.Result
 	if opts.Sequential {
 		results, err = runSequential(ctx, manager, selectedAgents, logger)
 	} else {
@@ -96,8 +286,22 @@ func Execute(ctx context.Context, opts config.RunOptions, logger Logger) error {
 	// Print summary
 	printSummary(results, logger)
 
-	if err != nil {
-		return err
+	err = finishRun(ctx, cfg, opts, results, err, publish, logger)
+
+	succeeded, failed := countResults(results)
+	span.SetAttributes(
+		attribute.Int("pagent.succeeded", succeeded),
+		attribute.Int("pagent.failed", failed),
+	)
+	telemetry.End(span, err)
+
+	return err
+}
+
+// finishRun checks agent results and runs post-processing when appropriate
+func finishRun(ctx context.Context, cfg *config.Config, opts config.RunOptions, results []agent.Result, runErr error, publish events.Handler, logger Logger) error {
+	if runErr != nil {
+		return runErr
 	}
 
 	// Check for any failures
@@ -109,7 +313,7 @@ func Execute(ctx context.Context, opts config.RunOptions, logger Logger) error {
 
 	// Run post-processing (only in modify mode)
 	if cfg.IsModifyMode() && hasPostProcessing(cfg) {
-		if err := runPostProcessing(cfg, opts.IsVerbose(), logger); err != nil {
+		if err := runPostProcessing(ctx, cfg, opts.IsVerbose(), publish, logger); err != nil {
 			return err
 		}
 	}
@@ -117,46 +321,220 @@ func Execute(ctx context.Context, opts config.RunOptions, logger Logger) error {
 	return nil
 }
 
-// applyOptions applies RunOptions to the config
-func applyOptions(cfg *config.Config, opts config.RunOptions) error {
-	// Override output directory if specified
-	if opts.OutputDir != "" {
-		cfg.OutputDir = opts.OutputDir
+// newEventHandler returns the --events stream handler for the requested format.
+// An empty format returns a nil handler (no stream).
+func newEventHandler(format string) (events.Handler, error) {
+	switch format {
+	case "":
+		return nil, nil
+	case config.EventFormatNDJSON:
+		return events.NewNDJSONWriter(os.Stdout).Handle, nil
+	default:
+		return nil, fmt.Errorf("unknown event format %q (supported: %s)", format, config.EventFormatNDJSON)
+	}
+}
+
+// recordHistory appends the run to the output directory's history log
+func recordHistory(cfg *config.Config, runID string, inp *input.Input, startedAt time.Time, results []agent.Result, runErr error, logger Logger) {
+	rec := state.RunRecord{
+		ID:         runID,
+		Input:      inp.Path,
+		Persona:    cfg.Persona,
+		Mode:       cfg.Mode,
+		StartedAt:  startedAt.UTC(),
+		FinishedAt: time.Now().UTC(),
+		Success:    runErr == nil,
+		Agents:     make([]state.AgentRecord, 0, len(results)),
+	}
+	if runErr != nil {
+		rec.Error = runErr.Error()
+	}
+	for _, r := range results {
+		ar := state.AgentRecord{
+			Name:       r.Agent,
+			OutputPath: r.OutputPath,
+			DurationMS: r.Duration.Milliseconds(),
+			Skipped:    r.Skipped,
+		}
+		if r.Error != nil {
+			ar.Error = r.Error.Error()
+		}
+		rec.Agents = append(rec.Agents, ar)
+	}
+
+	if err := state.AppendHistory(cfg.OutputDir, rec); err != nil {
+		logger.Verbose("Failed to record run history: %v", err)
+	}
+}
+
+// recordAudit appends the run to the audit log configured by auditCfg. A
+// nil auditCfg means the run's config failed to load, so the default log is
+// used.
+func recordAudit(auditCfg *config.AuditConfig, opts config.RunOptions, s Session, startedAt time.Time, runErr error, logger Logger) {
+	if auditCfg == nil {
+		auditCfg = &config.Default().Audit
 	}
 
-	cfg.Timeout = opts.Timeout
+	actor := s.Actor
+	if actor == "" {
+		actor = audit.LocalUser()
+	}
+	source := s.Source
+	if source == "" {
+		source = audit.SourceCLI
+	}
+	var args map[string]any
+	if data, err := json.Marshal(opts); err == nil {
+		_ = json.Unmarshal(data, &args)
+	}
+	rec := audit.Record{
+		StartedAt:  startedAt.UTC(),
+		FinishedAt: time.Now().UTC(),
+		Source:     source,
+		Actor:      actor,
+		Action:     "run",
+		RunID:      s.RunID,
+		Args:       args,
+		Outcome:    audit.OutcomeOK,
+	}
+	for _, p := range []string{opts.InputPath, opts.OutputDir, opts.TargetCodebase, opts.SpecsOutputDir} {
+		if p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		rec.Paths = append(rec.Paths, p)
+	}
+	if runErr != nil {
+		rec.Outcome = audit.OutcomeError
+		rec.Error = runErr.Error()
+	}
 
-	// Handle resume mode
-	switch opts.ResumeMode {
-	case config.ResumeModeResume:
-		cfg.ResumeMode = true
-		cfg.ForceMode = false
-	case config.ResumeModeForce:
-		cfg.ResumeMode = false
-		cfg.ForceMode = true
-	default:
-		cfg.ResumeMode = false
-		cfg.ForceMode = false
+	if err := audit.New(*auditCfg).Append(rec); err != nil {
+		logger.Verbose("Failed to write audit log: %v", err)
+	}
+}
+
+// errResolveCancelled is returned when the user cancels resolving conflicts
+var errResolveCancelled = errors.New("stack resolution cancelled")
+
+// resolveStack finds the stack settings the input documents contradict and
+// resolves them with opts.Resolve, a saved resolution that answers the same
+// conflicts, s.ResolveConflicts or s.Resolve, in that order. An explicit
+// config.ResolveFail in opts skips the saved resolution and the prompt.
+// Resolved values are applied to cfg and saved for later runs. Returns nil
+// if nothing conflicts, and an error if they stay unresolved under
+// config.ResolveFail.
+// An approval_needed event is published while s.ResolveConflicts asks, and
+// when the run goes ahead or stops with the conflicts unresolved.
+func resolveStack(cfg *config.Config, inp *input.Input, opts config.RunOptions, s Session, publish events.Handler, logger Logger) (*types.StackResolution, error) {
+	conflicts, err := conflict.Analyze(inp.Files, cfg.Stack)
+	if err != nil {
+		return nil, fmt.Errorf("stack conflict analysis failed: %w", err)
+	}
+	if len(conflicts) == 0 {
+		return nil, nil
 	}
 
-	// Override persona if specified
-	if opts.Persona != "" {
-		if !config.IsValidPersona(opts.Persona) {
-			return fmt.Errorf("invalid persona %q: must be one of %v", opts.Persona, config.ValidPersonas)
+	saved, err := conflict.Load(cfg.OutputDir)
+	if err != nil {
+		logger.Verbose("Ignoring saved stack resolution: %v", err)
+	}
+
+	strategy := opts.Resolve
+	if strategy == "" {
+		strategy = s.Resolve
+	}
+
+	var resolved []types.StackConflict
+	switch {
+	case opts.Resolve == config.ResolveFail:
+		// Asked to fail: neither a saved answer nor a prompt overrides it
+	case opts.Resolve != "":
+		if resolved, err = conflict.Resolve(conflicts, opts.Resolve); err != nil {
+			return nil, err
+		}
+	case conflict.Matches(saved, conflicts):
+		logger.Info("Using the stack resolution saved in %s", filepath.Join(cfg.OutputDir, conflict.ResolutionFile))
+		resolved = saved.Conflicts
+	case s.ResolveConflicts != nil:
+		publish(approvalNeeded(conflicts, "choose how to resolve them"))
+		if resolved, err = s.ResolveConflicts(conflicts); err != nil {
+			return nil, err
+		}
+		if resolved == nil {
+			return nil, errResolveCancelled
+		}
+	case strategy != "" && strategy != config.ResolveFail:
+		if resolved, err = conflict.Resolve(conflicts, strategy); err != nil {
+			return nil, err
 		}
-		cfg.Persona = opts.Persona
 	}
 
-	// Override architecture preference
-	switch opts.Architecture {
-	case config.ArchitectureStateless:
-		cfg.Preferences.Stateless = true
-	case config.ArchitectureDatabase:
-		cfg.Preferences.Stateless = false
-	// "config" means use whatever is in config
+	if resolved == nil && strategy == config.ResolveFail {
+		for _, c := range conflicts {
+			logger.Error("the PRD asks for %s %s (%s) but the config has %q", c.Category, c.PRDHint, c.Evidence, c.ConfigValue)
+		}
+		publish(approvalNeeded(conflicts, "run stopped; pass resolve prefer-prd or prefer-config to choose"))
+		return nil, fmt.Errorf("%d unresolved stack conflict(s) between the PRD and the config; pass -resolve prefer-prd or prefer-config", len(conflicts))
+	}
+	if resolved == nil {
+		// Nobody chose: agents get the conflicts and the PRD-first guidance
+		for _, c := range conflicts {
+			logger.Info("Warning: the PRD asks for %s %s (%s) but the config has %q; pass -resolve prefer-prd or prefer-config to choose",
+				c.Category, c.PRDHint, c.Evidence, c.ConfigValue)
+		}
+		publish(approvalNeeded(conflicts, "running with them unresolved; pass resolve to choose"))
+		return &types.StackResolution{Conflicts: conflicts}, nil
 	}
 
-	return nil
+	resolution := &types.StackResolution{Resolved: true, Conflicts: resolved}
+	if err := cfg.ApplyStackResolution(resolution); err != nil {
+		return nil, fmt.Errorf("failed to apply stack resolution: %w", err)
+	}
+	for _, c := range resolved {
+		logger.Info("Stack %s: %s (PRD: %s, config: %q)", c.Category, c.Resolution, c.PRDHint, c.ConfigValue)
+	}
+	if err := conflict.Save(cfg.OutputDir, resolution); err != nil {
+		logger.Verbose("Failed to save stack resolution: %v", err)
+	}
+	return resolution, nil
+}
+
+// approvalNeeded builds the approval_needed event for unresolved conflicts
+func approvalNeeded(conflicts []types.StackConflict, action string) events.Event {
+	e := events.New(events.ApprovalNeeded)
+	e.Reason = "stack_conflicts"
+	categories := make([]string, len(conflicts))
+	for i, c := range conflicts {
+		categories[i] = c.Category
+		e.Conflicts = append(e.Conflicts, events.Conflict{
+			Category:    c.Category,
+			ConfigValue: c.ConfigValue,
+			PRDHint:     c.PRDHint,
+			Evidence:    c.Evidence,
+		})
+	}
+	e.Message = fmt.Sprintf("the PRD and the config disagree on %s: %s", strings.Join(categories, ", "), action)
+	return e
+}
+
+// logDetectedStack logs the settings taken from the target codebase and
+// the configured settings it disagrees with
+func logDetectedStack(cfg *config.Config, disagreements []config.Disagreement, logger Logger) {
+	settings, err := cfg.Settings()
+	if err != nil {
+		return
+	}
+	for _, s := range settings {
+		if strings.HasPrefix(s.Origin, "detected:") {
+			logger.Verbose("Detected %s: %s (%s)", s.Key, s.Value, strings.TrimPrefix(s.Origin, "detected:"))
+		}
+	}
+	for _, d := range disagreements {
+		logger.Info("Warning: %s", d)
+	}
 }
 
 func logStartup(logger Logger, inp *input.Input, cfg *config.Config, agents []string, sequential bool) {
@@ -183,6 +561,9 @@ func logStartup(logger Logger, inp *input.Input, cfg *config.Config, agents []st
 	}
 
 	logger.Info("Agents: %s", strings.Join(agents, ", "))
+	if cfg.Preset != "" {
+		logger.Info("Preset: %s", cfg.Preset)
+	}
 	logger.Info("Persona: %s", cfg.Persona)
 	logger.Info("Architecture: %s", map[bool]string{true: "stateless", false: "database-backed"}[cfg.Preferences.Stateless])
 	logger.Info("Execution: %s", map[bool]string{true: "sequential", false: "parallel"}[sequential])
@@ -220,7 +601,6 @@ func runParallel(ctx context.Context, manager *agent.Manager, agents []string, l
 		levelFailed := false
 		for result := range resultCh {
 			allResults = append(allResults, result)
-			printAgentStatus(result, logger)
 			if result.Error != nil {
 				levelFailed = true
 			}
@@ -254,7 +634,6 @@ func runSequential(ctx context.Context, manager *agent.Manager, agents []string,
 
 		result := manager.RunAgent(ctx, name)
 		results = append(results, result)
-		printAgentStatus(result, logger)
 
 		if result.Error != nil {
 			logger.Error("Agent %s failed, stopping sequential execution", name)
@@ -265,21 +644,21 @@ func runSequential(ctx context.Context, manager *agent.Manager, agents []string,
 	return results, nil
 }
 
-func printAgentStatus(result agent.Result, logger Logger) {
-	if result.Error != nil {
-		logger.Info("✗ %s: failed (%v)", result.Agent, result.Error)
-	} else {
-		logger.Info("✓ %s: completed → %s", result.Agent, result.OutputPath)
-	}
-}
-
 func printSummary(results []agent.Result, logger Logger) {
 	logger.Info("")
 	logger.Info("=== Summary ===")
 
-	succeeded := 0
-	failed := 0
+	succeeded, failed := countResults(results)
+
+	logger.Info("%d/%d agents succeeded", succeeded, len(results))
+
+	if failed > 0 {
+		logger.Info("Partial results saved.")
+	}
+}
 
+// countResults returns the number of succeeded and failed agents
+func countResults(results []agent.Result) (succeeded, failed int) {
 	for _, r := range results {
 		if r.Error != nil {
 			failed++
@@ -287,12 +666,7 @@ func printSummary(results []agent.Result, logger Logger) {
 			succeeded++
 		}
 	}
-
-	logger.Info("%d/%d agents succeeded", succeeded, len(results))
-
-	if failed > 0 {
-		logger.Info("Partial results saved.")
-	}
+	return succeeded, failed
 }
 
 func hasPostProcessing(cfg *config.Config) bool {
@@ -300,19 +674,21 @@ func hasPostProcessing(cfg *config.Config) bool {
 	return pp.GenerateDiffSummary || pp.GeneratePRDescription || len(pp.ValidationCommands) > 0
 }
 
-func runPostProcessing(cfg *config.Config, verbose bool, logger Logger) error {
+func runPostProcessing(ctx context.Context, cfg *config.Config, verbose bool, publish events.Handler, logger Logger) error {
 	logger.Info("")
 	logger.Info("=== Post-Processing ===")
 
-	pp := postprocess.NewRunner(cfg, verbose)
-	ppResults := pp.Run()
+	pp := postprocess.NewRunner(cfg, verbose).WithLogger(logger.Verbose)
+	ppResults := pp.RunContext(ctx)
 
 	for _, r := range ppResults {
-		if r.Success {
-			logger.Info("✓ %s: %s", r.Step, r.Output)
-		} else {
-			logger.Error("✗ %s: %v", r.Step, r.Error)
+		e := events.New(events.PostProcessStep)
+		e.Step = r.Step
+		e.Message = r.Output
+		if !r.Success {
+			e.Error = fmt.Sprintf("%v", r.Error)
 		}
+		publish(e)
 	}
 
 	// Check for post-processing failures
@@ -0,0 +1,260 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tuannvm/pagent/internal/audit"
+	"github.com/tuannvm/pagent/internal/config"
+	"github.com/tuannvm/pagent/internal/conflict"
+	"github.com/tuannvm/pagent/internal/events"
+	"github.com/tuannvm/pagent/internal/input"
+	"github.com/tuannvm/pagent/internal/state"
+	"github.com/tuannvm/pagent/internal/types"
+)
+
+// captureStdout returns everything written to os.Stdout while fn runs
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	fn()
+	_ = w.Close()
+	return <-out
+}
+
+func TestExecuteNDJSONVerbose(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("PATH", dir) // No agent binary: the agent fails to spawn
+	t.Chdir(dir)
+
+	prd := filepath.Join(dir, "prd.md")
+	if err := os.WriteFile(prd, []byte("# PRD\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := config.RunOptions{
+		InputPath:   prd,
+		OutputDir:   filepath.Join(dir, "outputs"),
+		Agents:      []string{"architect"},
+		EventFormat: config.EventFormatNDJSON,
+		Verbosity:   config.VerbosityVerbose,
+	}
+	logger := NewStdLogger(true, false).WithOutput(io.Discard)
+
+	var runErr error
+	stdout := captureStdout(t, func() {
+		runErr = ExecuteSession(context.Background(), opts, logger, Session{NoSignals: true})
+	})
+	if runErr == nil {
+		t.Fatal("expected the run to fail without an agent binary")
+	}
+
+	seen := map[events.Type]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		var e events.Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("stdout line is not an event: %q", line)
+		}
+		seen[e.Type] = true
+	}
+	for _, want := range []events.Type{events.RunStarted, events.Log, events.AgentFailed, events.RunCompleted} {
+		if !seen[want] {
+			t.Errorf("no %s event on stdout:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestRunPostProcessingKeepsStdoutClean(t *testing.T) {
+	cfg := config.Default()
+	cfg.Mode = config.ModeModify
+	cfg.TargetCodebase = t.TempDir()
+	cfg.PostProcessing = config.PostProcessingConfig{ValidationCommands: []string{"echo hi"}}
+
+	var steps []events.Event
+	var runErr error
+	stdout := captureStdout(t, func() {
+		logger := NewStdLogger(true, false).WithOutput(io.Discard)
+		runErr = runPostProcessing(context.Background(), cfg, true, func(e events.Event) { steps = append(steps, e) }, logger)
+	})
+	if runErr != nil {
+		t.Fatalf("runPostProcessing() error = %v", runErr)
+	}
+	if stdout != "" {
+		t.Errorf("post-processing wrote to stdout: %q", stdout)
+	}
+	if len(steps) != 1 || !strings.Contains(steps[0].Message, "hi") {
+		t.Errorf("post_process_step events = %+v", steps)
+	}
+}
+
+func TestExecuteAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Chdir(dir)
+
+	prd := filepath.Join(dir, "prd.md")
+	callerLog := filepath.Join(dir, "caller.jsonl")
+	hostLog := filepath.Join(dir, "host.jsonl")
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(prd, []byte("# PRD\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, []byte("audit:\n  path: "+callerLog+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unknown agent fails the run after the config is loaded
+	opts := config.RunOptions{InputPath: prd, ConfigPath: configPath, Agents: []string{"nobody"}}
+	logger := NewStdLogger(false, true)
+
+	// Hosts record runs in their own log, whatever config_path says
+	host := Session{NoSignals: true, Audit: &config.AuditConfig{Path: hostLog}, Source: audit.SourceMCP, Actor: "alice"}
+	if err := ExecuteSession(context.Background(), opts, logger, host); err == nil {
+		t.Fatal("expected the run to fail")
+	}
+	if _, err := os.Stat(callerLog); !os.IsNotExist(err) {
+		t.Errorf("host run was recorded in the caller's audit log")
+	}
+	data, err := os.ReadFile(hostLog)
+	if err != nil || !strings.Contains(string(data), "unknown agent") {
+		t.Errorf("host audit log = %q, %v; want the failed run", data, err)
+	}
+	// Recorded like the tool call that submitted the run
+	if !strings.Contains(string(data), `"source":"mcp"`) || !strings.Contains(string(data), `"actor":"alice"`) {
+		t.Errorf("host audit log = %q, want the session's source and actor", data)
+	}
+
+	// CLI runs use the run's config
+	if err := ExecuteSession(context.Background(), opts, logger, Session{NoSignals: true}); err == nil {
+		t.Fatal("expected the run to fail")
+	}
+	if data, err := os.ReadFile(callerLog); err != nil || !strings.Contains(string(data), "unknown agent") {
+		t.Errorf("CLI audit log = %q, %v; want the failed run", data, err)
+	}
+}
+
+func TestExecuteFailedRunCompletes(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Chdir(dir)
+
+	var (
+		mu       sync.Mutex
+		webhooks []events.Type
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e events.Event
+		_ = json.NewDecoder(r.Body).Decode(&e)
+		mu.Lock()
+		webhooks = append(webhooks, e.Type)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	prd := filepath.Join(dir, "prd.md")
+	outputDir := filepath.Join(dir, "outputs")
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(prd, []byte("# PRD\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := "output_dir: " + outputDir + "\nnotifications:\n  webhooks:\n    - url: " + srv.URL + "\n"
+	if err := os.WriteFile(configPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := events.NewBus()
+	var completed []events.Event
+	bus.Subscribe(func(e events.Event) { completed = append(completed, e) }, events.RunCompleted)
+
+	// An unknown agent fails the run before any agent starts
+	opts := config.RunOptions{InputPath: prd, ConfigPath: configPath, Agents: []string{"nobody"}}
+	err := ExecuteSession(context.Background(), opts, NewStdLogger(false, true), Session{NoSignals: true, Bus: bus})
+	if err == nil {
+		t.Fatal("expected the run to fail")
+	}
+
+	if len(completed) != 1 || !strings.Contains(completed[0].Error, "unknown agent") {
+		t.Errorf("run_completed events = %+v, want one with the run's error", completed)
+	}
+	mu.Lock()
+	if !slices.Contains(webhooks, events.RunCompleted) {
+		t.Errorf("webhook received %v, want run_completed", webhooks)
+	}
+	mu.Unlock()
+	history, err := state.LoadHistory(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Success || !strings.Contains(history[0].Error, "unknown agent") {
+		t.Errorf("history = %+v, want the failed run", history)
+	}
+}
+
+func TestResolveStackExplicitFail(t *testing.T) {
+	dir := t.TempDir()
+	prd := filepath.Join(dir, "prd.md")
+	if err := os.WriteFile(prd, []byte("# PRD\n\nStore documents in MongoDB.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inp, err := input.Discover(prd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.OutputDir = filepath.Join(dir, "outputs")
+
+	// A resolution saved by an earlier run answers the same conflicts
+	conflicts, err := conflict.Analyze(inp.Files, cfg.Stack)
+	if err != nil || len(conflicts) == 0 {
+		t.Fatalf("Analyze() = %v, %v; want conflicts", conflicts, err)
+	}
+	resolved, err := conflict.Resolve(conflicts, config.ResolvePreferPRD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conflict.Save(cfg.OutputDir, &types.StackResolution{Resolved: true, Conflicts: resolved}); err != nil {
+		t.Fatal(err)
+	}
+
+	asked := false
+	s := Session{ResolveConflicts: func(c []types.StackConflict) ([]types.StackConflict, error) {
+		asked = true
+		return conflict.Resolve(c, config.ResolvePreferPRD)
+	}}
+	opts := config.RunOptions{Resolve: config.ResolveFail}
+	logger := NewStdLogger(false, true).WithOutput(io.Discard)
+
+	var published []events.Event
+	publish := func(e events.Event) { published = append(published, e) }
+	if _, err := resolveStack(cfg, inp, opts, s, publish, logger); err == nil {
+		t.Fatal("resolveStack() with -resolve fail succeeded; want unresolved conflicts error")
+	}
+	if asked {
+		t.Error("-resolve fail still prompted for a resolution")
+	}
+	if len(published) != 1 || published[0].Type != events.ApprovalNeeded {
+		t.Errorf("published %+v; want one approval_needed event", published)
+	}
+}
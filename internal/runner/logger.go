@@ -2,31 +2,43 @@ package runner
 
 import (
 	"fmt"
+	"io"
+	"log"
 	"os"
+
+	"github.com/tuannvm/pagent/internal/config"
 )
 
 // StdLogger implements Logger using stdout/stderr
 type StdLogger struct {
 	verbose bool
 	quiet   bool
+	out     io.Writer
 }
 
 // NewStdLogger creates a new standard logger
 func NewStdLogger(verbose, quiet bool) *StdLogger {
-	return &StdLogger{verbose: verbose, quiet: quiet}
+	return &StdLogger{verbose: verbose, quiet: quiet, out: os.Stdout}
+}
+
+// WithOutput redirects info and verbose messages to w.
+// Used to keep stdout clean when it carries machine-readable events.
+func (l *StdLogger) WithOutput(w io.Writer) *StdLogger {
+	l.out = w
+	return l
 }
 
 // Info logs info messages (unless quiet)
 func (l *StdLogger) Info(format string, args ...interface{}) {
 	if !l.quiet {
-		_, _ = fmt.Fprintf(os.Stdout, format+"\n", args...)
+		_, _ = fmt.Fprintf(l.out, format+"\n", args...)
 	}
 }
 
 // Verbose logs verbose/debug messages (only if verbose and not quiet)
 func (l *StdLogger) Verbose(format string, args ...interface{}) {
 	if l.verbose && !l.quiet {
-		_, _ = fmt.Fprintf(os.Stdout, "[DEBUG] "+format+"\n", args...)
+		_, _ = fmt.Fprintf(l.out, "[DEBUG] "+format+"\n", args...)
 	}
 }
 
@@ -34,3 +46,35 @@ func (l *StdLogger) Verbose(format string, args ...interface{}) {
 func (l *StdLogger) Error(format string, args ...interface{}) {
 	_, _ = fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
 }
+
+// RunLogger writes through the standard log package, prefixing each line
+// with the run ID. Used by long-lived processes that run several pipelines.
+type RunLogger struct {
+	id      string
+	verbose bool
+	quiet   bool
+}
+
+// NewRunLogger creates a logger for run id with the verbosity from opts
+func NewRunLogger(id string, opts config.RunOptions) RunLogger {
+	return RunLogger{id: id, verbose: opts.IsVerbose(), quiet: opts.IsQuiet()}
+}
+
+// Info logs info messages (unless quiet)
+func (l RunLogger) Info(format string, args ...interface{}) {
+	if !l.quiet {
+		log.Printf("[%s] %s", l.id, fmt.Sprintf(format, args...))
+	}
+}
+
+// Verbose logs verbose/debug messages (only if verbose and not quiet)
+func (l RunLogger) Verbose(format string, args ...interface{}) {
+	if l.verbose && !l.quiet {
+		log.Printf("[%s] [DEBUG] %s", l.id, fmt.Sprintf(format, args...))
+	}
+}
+
+// Error logs error messages
+func (l RunLogger) Error(format string, args ...interface{}) {
+	log.Printf("[%s] Error: %s", l.id, fmt.Sprintf(format, args...))
+}
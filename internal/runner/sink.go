@@ -0,0 +1,33 @@
+package runner
+
+import "github.com/tuannvm/pagent/internal/events"
+
+// LogSink renders lifecycle events as human-readable log lines.
+// It is the CLI/TUI subscriber on the run's event bus, and renders events
+// streamed from the daemon for `pagent run -daemon`.
+func LogSink(logger Logger) events.Handler {
+	return func(e events.Event) {
+		switch e.Type {
+		case events.AgentCompleted:
+			logger.Info("✓ %s: completed → %s", e.Agent, e.OutputPath)
+		case events.AgentFailed:
+			logger.Info("✗ %s: failed (%s)", e.Agent, e.Error)
+		case events.AgentSkipped:
+			logger.Info("✓ %s: up-to-date → %s", e.Agent, e.OutputPath)
+		case events.AgentRestarted:
+			logger.Info("↻ %s: restarting (attempt %d)", e.Agent, e.Attempt)
+		case events.RunPaused:
+			logger.Info("Run paused; no new agents will start until resumed")
+		case events.RunResumed:
+			logger.Info("Run resumed")
+		case events.PostProcessStep:
+			if e.HasError() {
+				logger.Error("✗ %s: %s", e.Step, e.Error)
+			} else {
+				logger.Info("✓ %s: %s", e.Step, e.Message)
+			}
+		case events.Log:
+			logger.Verbose("%s", e.Message)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package state
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistoryFile is the location of the run history log, relative to the output directory
+const HistoryFile = ".pagent/history.jsonl"
+
+// RunRecord summarizes a completed run for `pagent history`.
+type RunRecord struct {
+	ID         string        `json:"id"`
+	Input      string        `json:"input"`
+	Persona    string        `json:"persona"`
+	Mode       string        `json:"mode"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Success    bool          `json:"success"`
+	Error      string        `json:"error,omitempty"`
+	Agents     []AgentRecord `json:"agents"`
+}
+
+// AgentRecord summarizes a single agent within a run.
+type AgentRecord struct {
+	Name       string `json:"name"`
+	OutputPath string `json:"output_path,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// NewRunID returns a sortable, unique identifier for a run
+// (e.g. "20250101-150405-1a2b3c").
+func NewRunID() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// AppendHistory appends a run record to the history log in outputDir.
+func AppendHistory(outputDir string, rec RunRecord) error {
+	path := filepath.Join(outputDir, HistoryFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run record: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory reads all run records from the history log in outputDir,
+// oldest first. A missing history file yields an empty slice.
+// Malformed lines are skipped.
+func LoadHistory(outputDir string) ([]RunRecord, error) {
+	f, err := os.Open(filepath.Join(outputDir, HistoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []RunRecord{}, nil
+		}
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	records := make([]RunRecord, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,83 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewRunID(t *testing.T) {
+	a := NewRunID()
+	b := NewRunID()
+	if a == b {
+		t.Errorf("expected unique run IDs, got %q twice", a)
+	}
+	if len(a) != len("20060102-150405-000000") {
+		t.Errorf("unexpected run ID format: %q", a)
+	}
+}
+
+func TestLoadHistoryMissingFile(t *testing.T) {
+	records, err := LoadHistory(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadHistory() error = %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("expected no records, got %d", len(records))
+	}
+}
+
+func TestAppendAndLoadHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	first := RunRecord{
+		ID:        "run-1",
+		Input:     "prd.md",
+		StartedAt: time.Now().Add(-time.Minute).UTC(),
+		Success:   true,
+		Agents:    []AgentRecord{{Name: "architect", DurationMS: 1000}},
+	}
+	second := RunRecord{
+		ID:     "run-2",
+		Input:  "prd.md",
+		Error:  "some agents failed",
+		Agents: []AgentRecord{{Name: "qa", Error: "timeout"}},
+	}
+
+	if err := AppendHistory(tmpDir, first); err != nil {
+		t.Fatalf("AppendHistory() error = %v", err)
+	}
+	if err := AppendHistory(tmpDir, second); err != nil {
+		t.Fatalf("AppendHistory() error = %v", err)
+	}
+
+	records, err := LoadHistory(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadHistory() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if records[0].ID != "run-1" || records[1].ID != "run-2" {
+		t.Errorf("records out of order: %q, %q", records[0].ID, records[1].ID)
+	}
+	if records[1].Agents[0].Error != "timeout" {
+		t.Errorf("agent error not preserved: %+v", records[1].Agents[0])
+	}
+}
+
+func TestLoadHistorySkipsMalformedLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, HistoryFile)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte("not json\n{\"id\":\"ok\"}\n"), 0644)
+
+	records, err := LoadHistory(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadHistory() error = %v", err)
+	}
+	if len(records) != 1 || records[0].ID != "ok" {
+		t.Errorf("expected single valid record, got %+v", records)
+	}
+}
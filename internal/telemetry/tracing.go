@@ -0,0 +1,197 @@
+// Package telemetry configures OpenTelemetry tracing for pagent.
+// Until Setup installs an exporter, the global tracer provider is a no-op,
+// so instrumented code paths cost nothing when tracing is disabled.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/tuannvm/pagent/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName identifies pagent's tracer
+const instrumentationName = "github.com/tuannvm/pagent"
+
+// DefaultTraceFile is used by the file exporter when no path is configured
+const DefaultTraceFile = "pagent-traces.json"
+
+// Version is reported as service.version; set at startup from the build version
+var Version = "dev"
+
+// The installed provider is shared by every run in the process and shut
+// down when the last of them releases it
+var (
+	mu            sync.Mutex
+	provider      *sdktrace.TracerProvider
+	closeExporter func()
+	refs          int
+)
+
+// Tracer returns pagent's tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartSpan starts a child span of the span in ctx (if any)
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err (if non-nil) on span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceContext carries span contexts across processes, e.g. from an MCP
+// tool call to the queued run it submitted. It is used directly rather than
+// through the global propagator, which stays a no-op until Setup runs.
+var traceContext = propagation.TraceContext{}
+
+// TraceParent returns the W3C traceparent of the span in ctx, or "" if ctx
+// carries no span
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// WithTraceParent returns ctx with the remote span described by traceparent
+// as its parent, so spans started from it join that trace. An empty or
+// malformed traceparent leaves ctx unchanged.
+func WithTraceParent(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return traceContext.Extract(ctx, propagation.MapCarrier{"traceparent": traceparent})
+}
+
+// Setup installs a tracer provider for the configured exporter and the W3C
+// trace-context propagator, or takes a reference to the provider an earlier
+// call installed. The returned shutdown function releases the reference:
+// it flushes pending spans, and the last release shuts the provider down.
+// Long-lived processes can therefore run several pipelines, each with its
+// own Setup, without one run's shutdown cutting off another's spans.
+// Setup is a no-op when tracing is disabled.
+func Setup(ctx context.Context, cfg config.TracingConfig) (shutdown func(context.Context) error, err error) {
+	if !cfg.IsEnabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if provider == nil {
+		if err := install(ctx, cfg); err != nil {
+			return nil, err
+		}
+	}
+	refs++
+
+	var once sync.Once
+	return func(ctx context.Context) error {
+		var err error
+		once.Do(func() { err = release(ctx) })
+		return err
+	}, nil
+}
+
+// install creates the provider for cfg and makes it global. Caller holds mu.
+func install(ctx context.Context, cfg config.TracingConfig) error {
+	exporter, closeExp, err := newExporter(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "pagent"
+	}
+	res := resource.NewSchemaless(
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(Version),
+	)
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	closeExporter = closeExp
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return nil
+}
+
+// release drops a reference to the provider, flushing its spans, and shuts
+// it down when no run uses it any more
+func release(ctx context.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+	refs--
+	if refs > 0 {
+		return provider.ForceFlush(ctx)
+	}
+
+	err := provider.Shutdown(ctx)
+	closeExporter()
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	provider, closeExporter = nil, nil
+	return err
+}
+
+// newExporter creates the span exporter for cfg.Exporter
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, func(), error) {
+	switch cfg.Exporter {
+	case config.TracingExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exp, func() {}, nil
+
+	case config.TracingExporterFile:
+		path := cfg.File
+		if path == "" {
+			path = DefaultTraceFile
+		}
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		return exp, func() { _ = f.Close() }, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported tracing exporter %q", cfg.Exporter)
+	}
+}
@@ -0,0 +1,100 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tuannvm/pagent/internal/config"
+)
+
+func TestSetupDisabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.TracingConfig{})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
+
+func TestSetupFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := Setup(context.Background(), config.TracingConfig{
+		Exporter: config.TracingExporterFile,
+		File:     path,
+	})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+
+	ctx, parent := StartSpan(context.Background(), "pagent.run")
+	_, child := StartSpan(ctx, "agent.run")
+	End(child, errors.New("boom"))
+	End(parent, nil)
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read trace file: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{`"Name":"pagent.run"`, `"Name":"agent.run"`, "boom"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace file missing %s", want)
+		}
+	}
+}
+
+func TestSetupSharedProvider(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.TracingConfig{Exporter: config.TracingExporterFile, File: filepath.Join(dir, "traces.json")}
+
+	first, err := Setup(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	second, err := Setup(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+
+	// The first run finishing must not cut off the second
+	if err := first(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+	_, span := StartSpan(context.Background(), "second.run")
+	End(span, nil)
+	if err := second(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	// Once released, a later run installs a fresh provider
+	cfg.File = filepath.Join(dir, "later.json")
+	later, err := Setup(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	_, span = StartSpan(context.Background(), "later.run")
+	End(span, nil)
+	if err := later(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	for file, want := range map[string]string{"traces.json": "second.run", "later.json": "later.run"} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("failed to read trace file: %v", err)
+		}
+		if !strings.Contains(string(data), `"Name":"`+want+`"`) {
+			t.Errorf("%s missing span %s", file, want)
+		}
+	}
+}
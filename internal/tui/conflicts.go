@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/huh"
+	"github.com/tuannvm/pagent/internal/types"
+)
+
+// ConflictResolver returns a function that asks which side of each
+// PRD-vs-config stack conflict to use, for runner.Session.ResolveConflicts.
+// The function returns nil, nil if the user cancels.
+func ConflictResolver(accessible bool) func([]types.StackConflict) ([]types.StackConflict, error) {
+	return func(conflicts []types.StackConflict) ([]types.StackConflict, error) {
+		return RunConflictResolver(conflicts, accessible)
+	}
+}
+
+// RunConflictResolver asks which side of each conflict to use.
+// Returns nil, nil if the user cancels.
+func RunConflictResolver(conflicts []types.StackConflict, accessible bool) ([]types.StackConflict, error) {
+	accessible = accessible || !isTerminal()
+
+	resolved := make([]types.StackConflict, len(conflicts))
+	copy(resolved, conflicts)
+	fields := make([]huh.Field, len(resolved))
+	for i := range resolved {
+		c := &resolved[i]
+		c.Resolution = c.PRDHint
+		fields[i] = huh.NewSelect[string]().
+			Title(stackTitle(c.Category)).
+			Description(fmt.Sprintf("The PRD asks for %s (%s); the config has %s", c.PRDHint, c.Evidence, orNone(c.ConfigValue))).
+			Options(
+				huh.NewOption("PRD: "+c.PRDHint, c.PRDHint),
+				huh.NewOption("Config: "+orNone(c.ConfigValue), c.ConfigValue),
+			).
+			Value(&c.Resolution)
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(fields...).
+			Title("Stack conflicts").
+			Description("The input documents and the config disagree. Pick the side agents should follow."),
+	).WithTheme(PagentTheme()).WithAccessible(accessible)
+	if err := form.Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("form error: %w", err)
+	}
+
+	for i := range resolved {
+		resolved[i].Resolved = true
+	}
+	return resolved, nil
+}
+
+// orNone shows an unset stack value as "none"
+func orNone(v string) string {
+	if v == "" {
+		return "none"
+	}
+	return v
+}
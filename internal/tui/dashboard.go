@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 
 	"github.com/charmbracelet/huh"
@@ -35,7 +36,15 @@ func RunDashboard(dashOpts DashboardOptions) (*config.RunOptions, error) {
 
 	// Get all agent names for multi-select
 	agentNames := cfg.GetAgentNames()
-	opts.Agents = agentNames // Default: all agents
+
+	// Default: the agents of the selected persona, until some are picked
+	personaAgents := func(persona string) []string {
+		c := *cfg
+		c.Persona = persona
+		return c.PersonaAgents()
+	}
+	opts.Agents = personaAgents(opts.Persona)
+	agentsPicked := false
 
 	// Build input options
 	discoveredFiles := DiscoverInputFiles()
@@ -74,10 +83,22 @@ func RunDashboard(dashOpts DashboardOptions) (*config.RunOptions, error) {
 
 	// Build persona options from shared definitions
 	var personaOpts []huh.Option[string]
-	for _, o := range config.PersonaOptions {
+	for _, o := range cfg.PersonaChoices() {
 		personaOpts = append(personaOpts, huh.NewOption(o.Label+" - "+o.Description, o.Value))
 	}
 
+	// Build preset options; the empty value keeps the config's own preset
+	fromConfig := "From config"
+	if cfg.Preset != "" {
+		fromConfig += " (" + cfg.Preset + ")"
+	}
+	presetOpts := []huh.Option[string]{huh.NewOption(fromConfig, "")}
+	if presets, err := config.ListPresets(); err == nil {
+		for _, p := range presets {
+			presetOpts = append(presetOpts, huh.NewOption(p.Name+" - "+p.Description, p.Name))
+		}
+	}
+
 	// === Main loop ===
 	for {
 		action = "run" // Reset
@@ -99,6 +120,14 @@ func RunDashboard(dashOpts DashboardOptions) (*config.RunOptions, error) {
 				Value(&opts.InputPath),
 		)
 
+		// Preset profile the config is layered on
+		mainFields = append(mainFields,
+			huh.NewSelect[string]().
+				Title("Preset").
+				Options(presetOpts...).
+				Value(&opts.Preset),
+		)
+
 		// Persona using shared options
 		mainFields = append(mainFields,
 			huh.NewSelect[string]().
@@ -184,6 +213,9 @@ func RunDashboard(dashOpts DashboardOptions) (*config.RunOptions, error) {
 		}
 
 		// action == "advanced"
+		if !agentsPicked {
+			opts.Agents = personaAgents(opts.Persona)
+		}
 		var agentOptions []huh.Option[string]
 		for _, name := range agentNames {
 			selected := false
@@ -193,7 +225,7 @@ func RunDashboard(dashOpts DashboardOptions) (*config.RunOptions, error) {
 					break
 				}
 			}
-			agentOptions = append(agentOptions, huh.NewOption(name, name).Selected(selected))
+			agentOptions = append(agentOptions, huh.NewOption(agentLabel(cfg, name), name).Selected(selected))
 		}
 
 		// Build options from shared definitions
@@ -212,6 +244,11 @@ func RunDashboard(dashOpts DashboardOptions) (*config.RunOptions, error) {
 			archOpts = append(archOpts, huh.NewOption(o.Label, o.Value))
 		}
 
+		var resolveOpts []huh.Option[string]
+		for _, o := range config.ResolveOptions {
+			resolveOpts = append(resolveOpts, huh.NewOption(o.Label, o.Value))
+		}
+
 		var verbOpts []huh.Option[string]
 		for _, o := range config.VerbosityOptions {
 			verbOpts = append(verbOpts, huh.NewOption(o.Label, o.Value))
@@ -240,6 +277,12 @@ func RunDashboard(dashOpts DashboardOptions) (*config.RunOptions, error) {
 					Options(archOpts...).
 					Value(&opts.Architecture),
 
+				huh.NewSelect[string]().
+					Title("Stack conflicts").
+					Description("When the PRD and config disagree").
+					Options(resolveOpts...).
+					Value(&opts.Resolve),
+
 				huh.NewInput().
 					Title("Timeout (sec)").
 					Placeholder("0").
@@ -263,6 +306,10 @@ func RunDashboard(dashOpts DashboardOptions) (*config.RunOptions, error) {
 			}
 			return nil, fmt.Errorf("form error: %w", err)
 		}
+		agentsPicked = !slices.Equal(opts.Agents, personaAgents(opts.Persona))
+	}
+	if !agentsPicked {
+		opts.Agents = personaAgents(opts.Persona)
 	}
 
 	// Parse timeout
@@ -273,9 +320,23 @@ func RunDashboard(dashOpts DashboardOptions) (*config.RunOptions, error) {
 	// Map execution mode to boolean
 	opts.Sequential = (executionMode == config.ExecutionSequential)
 
+	// An untouched persona defers to the chosen preset and the config on top
+	if opts.Preset != "" && opts.Persona == cfg.Persona {
+		opts.Persona = ""
+	}
+
 	return &opts, nil
 }
 
+// agentLabel shows an agent with its kind and description
+func agentLabel(cfg *config.Config, name string) string {
+	label := name + " (" + cfg.AgentKind(name) + ")"
+	if desc := cfg.Agents[name].Description; desc != "" {
+		label += " - " + desc
+	}
+	return label
+}
+
 // isTerminal checks if stdout is a terminal
 func isTerminal() bool {
 	return term.IsTerminal(int(os.Stdout.Fd()))
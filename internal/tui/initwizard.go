@@ -0,0 +1,186 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/tuannvm/pagent/internal/config"
+)
+
+// InitWizardOptions configures the init wizard
+type InitWizardOptions struct {
+	Initial    config.InitOptions // Pre-filled answers
+	Detected   []string           // What was detected in the repo, e.g. "go (go.mod)"
+	ConfigPath string             // Config file to create or update
+	Exists     bool               // ConfigPath already exists
+	Accessible bool
+}
+
+// RunInitWizard asks for the settings 'pagent init' writes.
+// Returns nil, nil if the user cancels.
+func RunInitWizard(wizOpts InitWizardOptions) (*config.InitOptions, error) {
+	accessible := wizOpts.Accessible || !isTerminal()
+	theme := PagentTheme()
+
+	if wizOpts.Exists {
+		update := true
+		confirm := huh.NewForm(huh.NewGroup(
+			huh.NewConfirm().
+				Title("Update " + wizOpts.ConfigPath + "?").
+				Description("Only the settings asked about change; the rest of the file is kept").
+				Affirmative("Update").
+				Negative("Cancel").
+				Value(&update),
+		)).WithTheme(theme).WithAccessible(accessible)
+		if err := confirm.Run(); err != nil {
+			if errors.Is(err, huh.ErrUserAborted) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("form error: %w", err)
+		}
+		if !update {
+			return nil, nil
+		}
+	}
+
+	// Bind answers to local variables; huh fields need typed pointers
+	o := wizOpts.Initial
+	stack := make(map[string]*string, len(config.InitStackKeys))
+	for _, key := range config.InitStackKeys {
+		v := o.Stack[key]
+		stack[key] = &v
+	}
+	prefString := func(key string) *string {
+		v, _ := o.Preferences[key].(string)
+		return &v
+	}
+	prefBool := func(key string) *bool {
+		v, _ := o.Preferences[key].(bool)
+		return &v
+	}
+	language, apiStyle, testingDepth := prefString("language"), prefString("api_style"), prefString("testing_depth")
+	stateless, containerized := prefBool("stateless"), prefBool("containerized")
+	includeCI, includeIaC := prefBool("include_ci"), prefBool("include_iac")
+
+	detected := "Nothing detected in this directory"
+	if len(wizOpts.Detected) > 0 {
+		detected = "Detected: " + strings.Join(wizOpts.Detected, ", ")
+	}
+
+	var modeOpts []huh.Option[string]
+	for _, opt := range config.ModeOptions {
+		modeOpts = append(modeOpts, huh.NewOption(opt.Label+" - "+opt.Description, opt.Value))
+	}
+	var personaOpts []huh.Option[string]
+	for _, opt := range o.PersonaChoices() {
+		personaOpts = append(personaOpts, huh.NewOption(opt.Label+" - "+opt.Description, opt.Value))
+	}
+
+	stackFields := []huh.Field{
+		huh.NewSelect[string]().
+			Title("Language").
+			Options(valueOptions(config.PreferenceOptions("language", *language))...).
+			Value(language),
+	}
+	for _, key := range config.InitStackKeys {
+		stackFields = append(stackFields, huh.NewSelect[string]().
+			Title(stackTitle(key)).
+			Options(valueOptions(config.StackOptions(key, *stack[key]))...).
+			Value(stack[key]))
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Mode").
+				Description(detected).
+				Options(modeOpts...).
+				Value(&o.Mode),
+			huh.NewSelect[string]().
+				Title("Persona").
+				Options(personaOpts...).
+				Value(&o.Persona),
+		).Title("Project"),
+
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Target codebase").
+				Description("Existing codebase to modify").
+				Placeholder(".").
+				Value(&o.TargetCodebase).
+				Validate(func(s string) error {
+					if info, err := os.Stat(s); err != nil || !info.IsDir() {
+						return fmt.Errorf("%q is not a directory", s)
+					}
+					return nil
+				}),
+		).WithHideFunc(func() bool { return o.Mode != config.ModeModify }),
+
+		huh.NewGroup(stackFields...).Title("Stack"),
+
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("API style").
+				Options(valueOptions(config.PreferenceOptions("api_style", *apiStyle))...).
+				Value(apiStyle),
+			huh.NewSelect[string]().
+				Title("Testing depth").
+				Options(valueOptions(config.PreferenceOptions("testing_depth", *testingDepth))...).
+				Value(testingDepth),
+			huh.NewConfirm().Title("Stateless architecture").Value(stateless),
+			huh.NewConfirm().Title("Containerized").Value(containerized),
+			huh.NewConfirm().Title("Generate CI pipelines").Value(includeCI),
+			huh.NewConfirm().Title("Generate infrastructure as code").Value(includeIaC),
+		).Title("Preferences"),
+	).WithTheme(theme).WithAccessible(accessible)
+
+	if err := form.Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("form error: %w", err)
+	}
+
+	o.Stack = make(map[string]string, len(stack))
+	for key, v := range stack {
+		o.Stack[key] = *v
+	}
+	o.Preferences = map[string]any{
+		"language":      *language,
+		"api_style":     *apiStyle,
+		"testing_depth": *testingDepth,
+		"stateless":     *stateless,
+		"containerized": *containerized,
+		"include_ci":    *includeCI,
+		"include_iac":   *includeIaC,
+	}
+	return &o, nil
+}
+
+// valueOptions builds select options from setting values
+func valueOptions(values []string) []huh.Option[string] {
+	opts := make([]huh.Option[string], len(values))
+	for i, v := range values {
+		label := v
+		if v == "" {
+			label = "(not set)"
+		}
+		opts[i] = huh.NewOption(label, v)
+	}
+	return opts
+}
+
+// stackTitle turns a stack key into a field title, e.g. "Message queue"
+func stackTitle(key string) string {
+	switch key {
+	case "ci":
+		return "CI"
+	case "iac":
+		return "Infrastructure as code"
+	}
+	title := strings.ReplaceAll(key, "_", " ")
+	return strings.ToUpper(title[:1]) + title[1:]
+}
@@ -135,13 +135,24 @@ func DefaultStack() TechStack {
 	}
 }
 
+// PersonaTraits describes the persona agents follow. Custom personas are
+// defined in the config and inherit the template behavior of Base.
+type PersonaTraits struct {
+	Name        string // Persona name as selected
+	Label       string // Display name
+	Description string // What the persona is for
+	Base        string // Built-in persona templates follow: minimal, balanced, production
+	Custom      bool   // Defined in the config rather than built in
+}
+
 // StackConflict represents a detected conflict between PRD and config
 type StackConflict struct {
-	Category    string // "database", "compute", "cache", "message_queue"
-	ConfigValue string // What the config specifies
-	PRDHint     string // What the PRD suggests (extracted keyword)
-	Resolved    bool   // Whether this conflict has been resolved
-	Resolution  string // The resolved value (if Resolved is true)
+	Category    string `yaml:"category"`             // "database", "compute", "cache", "message_queue"
+	ConfigValue string `yaml:"config_value"`         // What the config specifies
+	PRDHint     string `yaml:"prd_hint"`             // What the PRD suggests (extracted keyword)
+	Evidence    string `yaml:"evidence,omitempty"`   // Where the PRD suggests it, e.g. "prd.md:12"
+	Resolved    bool   `yaml:"resolved"`             // Whether this conflict has been resolved
+	Resolution  string `yaml:"resolution,omitempty"` // The resolved value (if Resolved is true)
 }
 
 // StackResolution holds user-resolved conflicts from the UI
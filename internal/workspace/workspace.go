@@ -0,0 +1,206 @@
+// Package workspace confines paths supplied by remote clients to a set of
+// allowlisted root directories.
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutsideRoots is returned for paths that resolve outside every root
+var ErrOutsideRoots = errors.New("path is outside the allowed workspace roots")
+
+// Sandbox checks paths against root directories. Roots and paths are
+// compared after symlinks are resolved, so a link inside a root cannot
+// point outside it. A nil Sandbox allows every path.
+type Sandbox struct {
+	roots []string // Absolute and symlink-free; empty allows nothing
+}
+
+// New returns a sandbox allowing paths inside roots. Roots must exist.
+// Without roots, New returns nil, which allows every path.
+func New(roots ...string) (*Sandbox, error) {
+	if len(roots) == 0 {
+		return nil, nil
+	}
+	s := &Sandbox{}
+	for _, root := range roots {
+		resolved, err := resolveRoot(root)
+		if err != nil {
+			return nil, err
+		}
+		s.roots = append(s.roots, resolved)
+	}
+	return s, nil
+}
+
+// Restrict returns a sandbox allowing only paths inside both s and roots,
+// e.g. the roots announced by an MCP client. Roots that do not exist on
+// this host are ignored, as they may describe a remote client's machine; if
+// none exist, s is returned unchanged. If none overlap with s, the result
+// allows nothing.
+func (s *Sandbox) Restrict(roots ...string) *Sandbox {
+	r := &Sandbox{}
+	for _, root := range roots {
+		if resolved, err := resolveRoot(root); err == nil {
+			r.roots = append(r.roots, resolved)
+		}
+	}
+	if len(r.roots) == 0 {
+		return s
+	}
+	return s.Intersect(r)
+}
+
+// Intersect returns a sandbox allowing only paths allowed by both s and o
+func (s *Sandbox) Intersect(o *Sandbox) *Sandbox {
+	if s == nil {
+		return o
+	}
+	if o == nil {
+		return s
+	}
+	r := &Sandbox{}
+	for _, root := range o.roots {
+		for _, allowed := range s.roots {
+			switch {
+			case within(allowed, root):
+				r.roots = append(r.roots, root)
+			case within(root, allowed):
+				r.roots = append(r.roots, allowed)
+			}
+		}
+	}
+	return r
+}
+
+// Roots returns the allowed roots, or nil if every path is allowed
+func (s *Sandbox) Roots() []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s.roots...)
+}
+
+// Resolve returns the absolute, symlink-free form of path and checks that
+// it lies inside a root. Relative paths are relative to the first root (the
+// working directory when unrestricted). Trailing components that do not
+// exist yet, such as an output directory, are allowed.
+func (s *Sandbox) Resolve(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("empty path")
+	}
+	if !filepath.IsAbs(path) {
+		base, err := s.base()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(base, path)
+	}
+
+	resolved, err := resolveExisting(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	if s == nil {
+		return resolved, nil
+	}
+	for _, root := range s.roots {
+		if within(root, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrOutsideRoots, path)
+}
+
+// base returns the directory relative paths are resolved against
+func (s *Sandbox) base() (string, error) {
+	if s == nil {
+		return os.Getwd()
+	}
+	if len(s.roots) == 0 {
+		return "", ErrOutsideRoots
+	}
+	return s.roots[0], nil
+}
+
+// SubjectDir returns the workspace directory of an authenticated subject
+// below dir, creating it if needed. Subjects are reduced to safe file names;
+// a hash suffix keeps altered names unique.
+func SubjectDir(dir, subject string) (string, error) {
+	if subject == "" {
+		return "", fmt.Errorf("empty subject")
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == '@':
+			return r
+		}
+		return '_'
+	}, subject)
+	if name != subject || strings.Trim(name, ".") == "" {
+		sum := sha256.Sum256([]byte(subject))
+		name += "-" + hex.EncodeToString(sum[:4])
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return "", fmt.Errorf("failed to create workspace for %s: %w", subject, err)
+	}
+	return path, nil
+}
+
+// resolveRoot returns the absolute, symlink-free path of an existing directory
+func resolveRoot(root string) (string, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace root %s: %w", root, err)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid workspace root %s: not a directory", root)
+	}
+	return resolved, nil
+}
+
+// resolveExisting evaluates symlinks in the deepest existing ancestor of an
+// absolute, clean path and appends the components that do not exist yet
+func resolveExisting(path string) (string, error) {
+	rest := ""
+	for p := path; ; {
+		if _, err := os.Lstat(p); err == nil {
+			resolved, err := filepath.EvalSymlinks(p)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(resolved, rest), nil
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return path, nil
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
+// within reports whether path is root or inside it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,180 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tempDir returns a symlink-free temporary directory
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestResolve(t *testing.T) {
+	root := tempDir(t)
+	outside := tempDir(t)
+	if err := os.MkdirAll(filepath.Join(root, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(root)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"root", root, root, false},
+		{"existing dir", filepath.Join(root, "docs"), filepath.Join(root, "docs"), false},
+		{"missing output", filepath.Join(root, "outputs", "specs"), filepath.Join(root, "outputs", "specs"), false},
+		{"relative to root", "outputs", filepath.Join(root, "outputs"), false},
+		{"dot dot", filepath.Join(root, "docs", "..", ".."), "", true},
+		{"outside", outside, "", true},
+		{"symlink escape", filepath.Join(root, "escape", "prd.md"), "", true},
+		{"sibling prefix", root + "-other", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Resolve(tt.path)
+			if tt.wantErr {
+				if !errors.Is(err, ErrOutsideRoots) {
+					t.Errorf("Resolve(%q) error = %v, want ErrOutsideRoots", tt.path, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilSandboxAllowsEverything(t *testing.T) {
+	s, err := New()
+	if err != nil || s != nil {
+		t.Fatalf("New() = %v, %v; want nil, nil", s, err)
+	}
+	dir := tempDir(t)
+	if got, err := s.Resolve(dir); err != nil || got != dir {
+		t.Errorf("Resolve(%q) = %q, %v", dir, got, err)
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	root := tempDir(t)
+	project := filepath.Join(root, "project")
+	other := tempDir(t)
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Narrowed to a client root inside the configured root
+	narrowed := s.Restrict(project)
+	if _, err := narrowed.Resolve(filepath.Join(project, "prd.md")); err != nil {
+		t.Errorf("path inside client root rejected: %v", err)
+	}
+	if _, err := narrowed.Resolve(filepath.Join(root, "prd.md")); !errors.Is(err, ErrOutsideRoots) {
+		t.Errorf("path outside client root allowed: %v", err)
+	}
+
+	// Client roots that do not overlap allow nothing
+	if _, err := s.Restrict(other).Resolve(filepath.Join(other, "prd.md")); !errors.Is(err, ErrOutsideRoots) {
+		t.Errorf("non-overlapping client root allowed a path: %v", err)
+	}
+
+	// Client roots that do not exist here are ignored
+	if got := s.Restrict("/nonexistent/client/root"); got != s {
+		t.Errorf("Restrict(nonexistent) = %v, want the original sandbox", got.Roots())
+	}
+
+	// An unrestricted sandbox takes the client roots as is
+	if got := (*Sandbox)(nil).Restrict(other).Roots(); len(got) != 1 || got[0] != other {
+		t.Errorf("nil.Restrict(other).Roots() = %v, want [%s]", got, other)
+	}
+}
+
+func TestSubjectDir(t *testing.T) {
+	dir := tempDir(t)
+
+	got, err := SubjectDir(dir, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "alice@example.com"); got != want {
+		t.Errorf("SubjectDir() = %q, want %q", got, want)
+	}
+	if info, err := os.Stat(got); err != nil || !info.IsDir() {
+		t.Errorf("workspace not created: %v", err)
+	}
+
+	// Unsafe subjects stay inside dir and do not collide
+	a, err := SubjectDir(dir, "auth0|../x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := SubjectDir(dir, "auth0_.._x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(a) != dir || a == b {
+		t.Errorf("SubjectDir() = %q and %q, want distinct children of %q", a, b, dir)
+	}
+	if _, err := SubjectDir(dir, ".."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SubjectDir(dir, ""); err == nil {
+		t.Error("SubjectDir() should reject an empty subject")
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	root := tempDir(t)
+	project := filepath.Join(root, "project")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	narrow, err := New(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, got := range []*Sandbox{s.Intersect(narrow), narrow.Intersect(s)} {
+		if roots := got.Roots(); len(roots) != 1 || roots[0] != project {
+			t.Errorf("Intersect() roots = %v, want [%s]", roots, project)
+		}
+	}
+	if got := (*Sandbox)(nil).Intersect(narrow); got != narrow {
+		t.Errorf("nil.Intersect() = %v, want the other sandbox", got.Roots())
+	}
+	if got := s.Intersect(nil); got != s {
+		t.Errorf("Intersect(nil) = %v, want the original sandbox", got.Roots())
+	}
+}